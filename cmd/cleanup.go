@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// cleanupCmd removes the comments of cdk-notifier e.g. when the pull request is closed
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove all comments of cdk-notifier from Pull Request",
+	Long: `Delete all comments managed by cdk-notifier on the Pull Request, e.g. when it is closed or tag ids were renamed.
+Comments are identified by their marker or start with the header of the default templates. Replies quoting a comment are kept.
+With tag-id-glob only comments of matching tag ids are removed. With hide-outdated comments are hidden instead if supported by the VCS.`,
+	Example: `cdk-notifier cleanup
+cdk-notifier cleanup --tag-id-glob "dev*" --hide-outdated`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		appConfig := initConfig()
+		pattern, err := cmd.Flags().GetString("tag-id-glob")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		cleanup(cmd.Context(), appConfig, pattern)
+	},
+}
+
+// cleanup removes the comments whose tag id matches the pattern
+func cleanup(ctx context.Context, appConfig *config.NotifierConfig, pattern string) {
+	if appConfig.NoPostMode {
+		logrus.Warnf("Skipping... because no-post-mode is set")
+		return
+	}
+	if appConfig.PullRequestID == 0 {
+		err := &config.ValidationError{CliArg: "pull-request-id", EnvVar: []string{"PR_ID", config.EnvCiCircleCiPullRequestID, config.EnvCiBitbucketPrId, config.EnvCiGitlabMrId}}
+		logrus.Warnf("Skipping... because %s", err)
+		return
+	}
+	notifier, cancel := createNotifier(ctx, appConfig)
+	defer cancel()
+	if _, ok := notifier.(provider.CommentMinimizer); appConfig.HideOutdated && !ok {
+		logrus.Warnf("Comments are deleted because hiding comments is not supported by %s", appConfig.Vcs)
+	}
+	operations, err := provider.Cleanup(notifier, *appConfig, pattern)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	logrus.Infof("Removed %d comments", len(operations))
+}
+
+func init() {
+	cleanupCmd.Flags().String("tag-id-glob", "", "Only remove comments whose tag id matches the glob pattern e.g. dev* for dev and its per stack comments. Removes all comments if not set")
+	rootCmd.AddCommand(cleanupCmd)
+}
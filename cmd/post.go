@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// postCmd posts a comment rendered in an earlier step
+var postCmd = &cobra.Command{
+	Use:   "post",
+	Short: "Post a rendered comment body to Pull Request",
+	Long: `Post the body file rendered by render as comment of the tag id. The log is not read again.
+The comment is created, updated or deleted like by cdk-notifier itself. A body without differences deletes the comment depending on delete.
+The body is posted as a single comment, split-stacks and split-comments are not supported.`,
+	Example: `cdk-notifier render -l cdk.log --tag-id dev
+cdk-notifier post --body-file cdk.log.diff --tag-id dev`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		appConfig := initConfig()
+		err := validatePost(appConfig)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		bodyFile, err := cmd.Flags().GetString("body-file")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		body, err := readBody(bodyFile, os.Stdin)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if appConfig.NoPostMode {
+			logrus.Warnf("Skipping... because no-post-mode is set")
+			return
+		}
+		postGroups(cmd.Context(), appConfig, []provider.CommentGroup{bodyGroup(appConfig.TagID, body)}, nil)
+	},
+}
+
+// validatePost rejects options creating several comments because the body file contains a single comment
+func validatePost(appConfig *config.NotifierConfig) error {
+	if appConfig.SplitStacks || appConfig.SplitComments {
+		return errors.New("post does not support split-stacks and split-comments because the body file is posted as a single comment. Use cdk-notifier to post several comments")
+	}
+	return nil
+}
+
+// readBody reads the rendered comment from the file or from stdin for config.LogFileStdin
+func readBody(path string, stdin io.Reader) (string, error) {
+	if path == config.LogFileStdin {
+		body, err := io.ReadAll(stdin)
+		return string(body), err
+	}
+	body, err := os.ReadFile(path)
+	return string(body), err
+}
+
+// bodyGroup returns the comment of the tag id. The comment is deleted if render detected only hash changes.
+func bodyGroup(tagID string, body string) provider.CommentGroup {
+	body, forceDelete := strings.CutPrefix(body, provider.HashChangesOnlyMarker+"\n")
+	if forceDelete {
+		logrus.Warnf("Skipping... because the body was rendered with suppress-hash-changes and only hash changes detected")
+	}
+	return provider.CommentGroup{TagID: tagID, ForceDelete: forceDelete, Comments: []provider.ManagedComment{{TagID: tagID, Content: body}}}
+}
+
+func init() {
+	postCmd.Flags().String("body-file", "", "Path to the comment body rendered by render e.g. cdk.log.diff. Use - to read from stdin")
+	cobra.CheckErr(postCmd.MarkFlagRequired("body-file"))
+	rootCmd.AddCommand(postCmd)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBody(t *testing.T) {
+	body, err := readBody("-", strings.NewReader("## cdk diff for dev\nResources"))
+	assert.NoError(t, err)
+	assert.Equal(t, "## cdk diff for dev\nResources", body)
+
+	path := filepath.Join(t.TempDir(), "cdk.log.diff")
+	assert.NoError(t, os.WriteFile(path, []byte("## cdk diff for prod"), 0644))
+	body, err = readBody(path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "## cdk diff for prod", body)
+
+	_, err = readBody(filepath.Join(t.TempDir(), "missing.diff"), nil)
+	assert.Error(t, err)
+}
+
+func TestRenderPostHashChangesOnly(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cdk.log")
+	log, err := os.ReadFile("../data/cdk-suppress-regex.log")
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(logFile, log, 0644))
+	appConfig := &config.NotifierConfig{
+		LogFile:                  logFile,
+		TagID:                    "dev",
+		Vcs:                      config.VcsGithub,
+		Template:                 "default",
+		NoPostMode:               true,
+		SuppressHashChanges:      true,
+		SuppressHashChangesRegex: `^[+-].*?[a-fA-F0-9]{40}`,
+	}
+	groups, _ := processLogs(appConfig, nil)
+	assert.True(t, groups[0].ForceDelete)
+
+	body, err := readBody(logFile+".diff", nil)
+	assert.NoError(t, err)
+	group := bodyGroup("dev", body)
+	assert.True(t, group.ForceDelete, "expect post to delete the comment like render")
+	assert.Equal(t, strings.TrimPrefix(body, provider.HashChangesOnlyMarker+"\n"), group.Comments[0].Content)
+	assert.Contains(t, group.Comments[0].Content, "## cdk diff for dev")
+
+	// the diff with other changes is posted
+	appConfig.SuppressHashChangesRegex = config.DefaultSuppressHashChangesRegex
+	groups, _ = processLogs(appConfig, nil)
+	assert.False(t, groups[0].ForceDelete)
+	body, err = readBody(logFile+".diff", nil)
+	assert.NoError(t, err)
+	assert.False(t, bodyGroup("dev", body).ForceDelete)
+}
+
+func TestValidatePost(t *testing.T) {
+	assert.NoError(t, validatePost(&config.NotifierConfig{TagID: "dev", AggregateComment: "cdk"}))
+	expected := "post does not support split-stacks and split-comments because the body file is posted as a single comment. Use cdk-notifier to post several comments"
+	assert.EqualError(t, validatePost(&config.NotifierConfig{SplitStacks: true}), expected)
+	assert.EqualError(t, validatePost(&config.NotifierConfig{SplitComments: true}), expected)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// renderCmd transforms the log without posting it so that no credentials are needed
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render cdk diff log as markdown or JSON without posting it",
+	Long: `Transform the cdk diff log into the comment and print it to stdout like no-post-mode. No token or pull request is needed.
+The markdown is also written to <log-file>.diff and with output json the report to <log-file>.json. The rendered file can be posted in another step with post.`,
+	Example: `cdk-notifier render -l cdk.log --tag-id dev
+cdk-notifier post --body-file cdk.log.diff --tag-id dev`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("NO_POST_MODE", true)
+		appConfig := initConfig()
+		result := notify(cmd.Context(), appConfig, os.Stdin)
+		exitWithResult(appConfig, result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+}
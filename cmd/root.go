@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/karlderkaefer/cdk-notifier/config"
 	"github.com/karlderkaefer/cdk-notifier/provider"
@@ -26,46 +30,12 @@ var rootCmd = &cobra.Command{
 	Long:    "Post CDK diff log to Pull Request",
 	Version: Version,
 	Run: func(cmd *cobra.Command, args []string) {
-		appConfig := &config.NotifierConfig{}
-		err := appConfig.Init()
-		if err != nil {
-			logrus.Fatal(err)
-		}
-
-		transformer := transform.NewLogTransformer(appConfig)
-		transformer.Process()
-
-		if appConfig.SuppressHashChanges {
-			logrus.Warnf("Suppressing hash changes detected %d hash changes and %d total changes", transformer.HashChanges, transformer.TotalChanges)
-			if transformer.TotalChanges == transformer.HashChanges {
-				logrus.Warnf("Skipping... because suppress-hash-changes is set and only hash changes detected")
-				// if there are only hash changes we also want to delete the comment
-				appConfig.ForceDeleteComment = true
-			}
-		}
-
-		if appConfig.NoPostMode {
-			return
-		}
-
-		if appConfig.PullRequestID == 0 {
-			err = &config.ValidationError{CliArg: "pull-request-id", EnvVar: []string{"PR_ID", config.EnvCiCircleCiPullRequestID, config.EnvCiBitbucketPrId, config.EnvCiGitlabMrId}}
-			logrus.Warnf("Skipping... because %s", err)
-			return
-		}
-
-		notifier, err := provider.CreateNotifierService(cmd.Context(), *appConfig)
-		if err != nil {
-			logrus.Fatalln(err)
-		}
-		notifier.SetCommentContent(transformer.LogContent)
-		_, err = notifier.PostComment()
-		if err != nil {
-			logrus.Fatalln(err)
-		}
+		appConfig := initConfig()
+		result := notify(cmd.Context(), appConfig, os.Stdin)
+		exitWithResult(appConfig, result)
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		err := setUpLogs(os.Stdout, v)
+		err := setUpLogs(logOutput(cmd), v)
 		if err != nil {
 			return err
 		}
@@ -84,30 +54,51 @@ func init() {
 
 	usageRepo := fmt.Sprintf("Name of repository without organisation. If not set will lookup for env var [%s|%s|%s],'", "REPO_NAME", config.EnvCiCircleCiRepoName, config.EnvCiBitbucketRepoName)
 	usageOwner := fmt.Sprintf("Name of owner. If not set will lookup for env var [%s|%s|%s]", "REPO_OWNER", config.EnvCiCircleCiRepoOwner, config.EnvCiBitbucketRepoOwner)
-	usageToken := fmt.Sprintf("Authentication token used to post comments to PR. If not set will lookup for env var [%s|%s|%s|%s]", "TOKEN_USER", config.EnvGithubToken, config.EnvBitbucketToken, config.EnvGitlabToken)
+	usageToken := fmt.Sprintf("Authentication token used to post comments to PR. If not set will lookup for env var [%s|%s|%s|%s|%s|%s]", "TOKEN_USER", config.EnvGithubToken, config.EnvBitbucketToken, config.EnvGitlabToken, config.EnvGiteaToken, config.EnvAzureDevopsToken)
 	usagePr := fmt.Sprintf("Id or URL of pull request. If not set will lookup for env var [%s|%s|%s|%s]", "PR_ID", config.EnvCiCircleCiPullRequestID, config.EnvCiBitbucketPrId, config.EnvCiGitlabMrId)
 
-	rootCmd.Flags().StringP("repo", "r", "", usageRepo)
-	rootCmd.Flags().StringP("owner", "o", "", usageOwner)
-	rootCmd.Flags().String("token", "", usageToken)
-	rootCmd.Flags().StringP("pull-request-id", "p", "", usagePr)
-	rootCmd.Flags().StringP("log-file", "l", "", "path to cdk log file")
-	rootCmd.Flags().StringP("tag-id", "t", "stack", "unique identifier for stack within pipeline")
-	rootCmd.Flags().BoolP("delete", "d", true, "delete comments when no changes are detected for a specific tag id")
-	rootCmd.Flags().String("vcs", "github", "Version Control System [github|github-enterprise|bitbucket|gitlab]")
-	rootCmd.Flags().String("ci", "circleci", "CI System used [circleci|bitbucket|gitlab]")
-	rootCmd.Flags().StringP("user", "u", "", "Optional set username for token (required for bitbucket)")
-	rootCmd.Flags().String("gitlab-url", "https://gitlab.com/", "Optional set gitlab url")
-	rootCmd.Flags().String("github-host", "", "Optional set host for GitHub Enterprise")
-	rootCmd.Flags().Int("github-max-comment-length", 0, "Optional set max comment length for GitHub Enterprise")
-	rootCmd.Flags().Bool("no-post-mode", false, "Optional do not post comment to VCS, instead write additional file and print diff to stdout")
-	rootCmd.Flags().Bool("disable-collapse", false, "Collapsible comments are enabled by default for GitHub and GitLab. When set to true it will not use collapsed sections.")
-	rootCmd.Flags().Bool("show-overview", false, "[Deprected: use template extended instead] Show Overview are disabled by default. When set to true it will show the number of cdk stacks with diff and  the number of replaced resources in the overview section.")
-	rootCmd.Flags().String("template", "default", "Template to use for comment [default|extended|extendedWithResources]")
-	rootCmd.Flags().String("custom-template", "", "File path or string input to custom template. When set it will override the template flag.")
-	rootCmd.Flags().Bool("suppress-hash-changes", false, "EXPERIMENTAL: when set to true it will ignore changes in hash values")
-	rootCmd.Flags().String("suppress-hash-changes-regex", config.DefaultSuppressHashChangesRegex, "Define Regex to suppress hash changes. Only used when suppress-hash-changes is set to true")
-	rootCmd.Flags().Bool("no-truncate", false, "Disable truncation of diff output. Useful when posting only to GHA job summary where VCS comment size limits do not apply.")
+	rootCmd.PersistentFlags().StringP("repo", "r", "", usageRepo)
+	rootCmd.PersistentFlags().StringP("owner", "o", "", usageOwner)
+	rootCmd.PersistentFlags().String("token", "", usageToken)
+	rootCmd.PersistentFlags().StringP("pull-request-id", "p", "", usagePr)
+	rootCmd.PersistentFlags().StringP("log-file", "l", "", "path to cdk log file. Use - to read from stdin")
+	rootCmd.PersistentFlags().StringSlice("log-files", nil, "Process several cdk log files in one invocation. Each entry is tag=path or a path or glob pattern using the file name as tag id. Overrides log-file and tag-id")
+	rootCmd.PersistentFlags().StringP("tag-id", "t", "stack", "unique identifier for stack within pipeline")
+	rootCmd.PersistentFlags().BoolP("delete", "d", true, "delete comments when no changes are detected for a specific tag id")
+	rootCmd.PersistentFlags().String("vcs", "github", "Version Control System [github|github-enterprise|bitbucket|bitbucket-server|gitlab|gitea|codecommit|azuredevops]")
+	rootCmd.PersistentFlags().String("ci", config.CiAuto, fmt.Sprintf("CI System used [%s|%s]. auto detects the CI System from its environment variables", config.CiAuto, strings.Join(config.CiDetectors(), "|")))
+	rootCmd.PersistentFlags().String("commit-sha", "", "Optional set commit sha. If not set will be detected from the CI System")
+	rootCmd.PersistentFlags().String("branch", "", "Optional set branch name. If not set will be detected from the CI System")
+	rootCmd.PersistentFlags().String("job-link", "", "Optional set link to the CI job shown in the comment. If not set will be detected from the CI System")
+	rootCmd.PersistentFlags().StringP("user", "u", "", "Optional set username for token (required for bitbucket)")
+	rootCmd.PersistentFlags().String("gitlab-url", "https://gitlab.com/", "Optional set gitlab url")
+	rootCmd.PersistentFlags().String("github-host", "", "Optional set host for GitHub Enterprise")
+	rootCmd.PersistentFlags().String("gitea-url", "", fmt.Sprintf("Url of Gitea or Forgejo e.g. https://codeberg.org. If not set will lookup for env var [GITEA_URL|%s|%s]", config.EnvCiWoodpeckerForgeUrl, config.EnvCiGithubServerUrl))
+	rootCmd.PersistentFlags().String("bitbucket-server-url", "", "Url of Bitbucket Server or Data Center e.g. https://bitbucket.example.com. Required for vcs bitbucket-server")
+	rootCmd.PersistentFlags().String("azure-devops-url", "", fmt.Sprintf("Organization url of Azure DevOps e.g. https://dev.azure.com/org. If not set will lookup for env var [AZURE_DEVOPS_URL|%s]", config.EnvCiAzurePipelinesCollectionUri))
+	rootCmd.PersistentFlags().Bool("azure-devops-close-thread", false, "Close the pull request thread instead of deleting it when no changes are detected on Azure DevOps")
+	rootCmd.PersistentFlags().String("aws-region", "", fmt.Sprintf("AWS region of the CodeCommit repository. If not set will lookup for env var [AWS_REGION|%s]", config.EnvAwsDefaultRegion))
+	rootCmd.PersistentFlags().Duration("api-timeout", 5*time.Minute, "Overall timeout for the calls to the VCS API including retries on server errors and rate limits. 0 disables the timeout")
+	rootCmd.PersistentFlags().Int("github-max-comment-length", 0, "Optional set max comment length for GitHub Enterprise")
+	rootCmd.PersistentFlags().Bool("no-post-mode", false, "Optional do not post comment to VCS, instead write additional file and print diff to stdout")
+	rootCmd.PersistentFlags().String("output", config.OutputMarkdown, "Output format [markdown|json]. json writes the parsed diff to <log-file>.json and prints it to stdout in no-post-mode")
+	rootCmd.PersistentFlags().Bool("disable-collapse", false, "Collapsible comments are enabled by default for GitHub, GitLab and Gitea. When set to true it will not use collapsed sections.")
+	rootCmd.PersistentFlags().Bool("show-overview", false, "[Deprected: use template extended instead] Show Overview are disabled by default. When set to true it will show the number of cdk stacks with diff and  the number of replaced resources in the overview section.")
+	rootCmd.PersistentFlags().String("template", "default", "Template to use for comment [default|extended|extendedWithResources]")
+	rootCmd.PersistentFlags().String("custom-template", "", "File path or string input to custom template. When set it will override the template flag.")
+	rootCmd.PersistentFlags().Bool("suppress-hash-changes", false, "EXPERIMENTAL: when set to true it will ignore changes in hash values")
+	rootCmd.PersistentFlags().String("suppress-hash-changes-regex", config.DefaultSuppressHashChangesRegex, "Define Regex to suppress hash changes. Only used when suppress-hash-changes is set to true")
+	rootCmd.PersistentFlags().Bool("no-truncate", false, "Disable truncation of diff output. Useful when posting only to GHA job summary where VCS comment size limits do not apply.")
+	rootCmd.PersistentFlags().Bool("split-comments", false, "Split diffs longer than the max comment length into several comments instead of truncating them.")
+	rootCmd.PersistentFlags().Bool("detailed-exitcode", false, "Exit with 0 if there are no changes, 2 if there are changes and 3 if resources are replaced or destroyed. 1 is used for errors")
+	rootCmd.PersistentFlags().StringSlice("protected-resource-types", nil, "Fail after posting the comment when resources of these types are removed or replaced. Supports glob patterns e.g. AWS::RDS::*")
+	rootCmd.PersistentFlags().StringSlice("protected-logical-ids", nil, "Fail after posting the comment when resources with these logical ids are removed or replaced. Supports glob patterns e.g. OrdersTable*")
+	rootCmd.PersistentFlags().String("rules-file", "", "YAML or JSON file with rules evaluated against every change of the diff. Rules can warn, add a label to the pull request or fail")
+	rootCmd.PersistentFlags().Bool("hide-outdated", false, "Hide outdated comments instead of deleting them and post a new comment for every new diff. Supported by GitHub and GitLab")
+	rootCmd.PersistentFlags().Bool("only-own-comments", false, "Only update or delete comments authored by the user of the token. Comments of other users with the same tag id are ignored")
+	rootCmd.PersistentFlags().String("comment-author", "", "Author of the comments managed with only-own-comments e.g. github-actions[bot]. If not set the user of the token is looked up")
+	rootCmd.PersistentFlags().String("aggregate-comment", "", "Tag id of a single comment shared by parallel jobs. Every job replaces only the section of its tag id")
+	rootCmd.PersistentFlags().Bool("split-stacks", false, "Post a separate comment for each stack with differences. Comments of stacks without differences are deleted.")
 
 	// mapping for viper [mapstruct value, flag name]
 	viperMappings := make(map[string]string)
@@ -117,9 +108,11 @@ func init() {
 	viperMappings["TOKEN_USER"] = "user"
 	viperMappings["PR_ID"] = "pull-request-id"
 	viperMappings["LOG_FILE"] = "log-file"
+	viperMappings["LOG_FILES"] = "log-files"
 	viperMappings["TAG_ID"] = "tag-id"
 	viperMappings["DELETE_COMMENT"] = "delete"
 	viperMappings["NO_POST_MODE"] = "no-post-mode"
+	viperMappings["OUTPUT"] = "output"
 	viperMappings["DISABLE_COLLAPSE"] = "disable-collapse"
 	// TODO show overview deprecated
 	viperMappings["SHOW_OVERVIEW"] = "show-overview"
@@ -127,15 +120,34 @@ func init() {
 	viperMappings["CUSTOM_TEMPLATE"] = "custom-template"
 	viperMappings["VERSION_CONTROL_SYSTEM"] = "vcs"
 	viperMappings["CI_SYSTEM"] = "ci"
+	viperMappings["COMMIT_SHA"] = "commit-sha"
+	viperMappings["BRANCH"] = "branch"
+	viperMappings["JOB_LINK"] = "job-link"
 	viperMappings["URL"] = "gitlab-url"
 	viperMappings["GITHUB_ENTERPRISE_HOST"] = "github-host"
+	viperMappings["AWS_REGION"] = "aws-region"
+	viperMappings["AZURE_DEVOPS_URL"] = "azure-devops-url"
+	viperMappings["BITBUCKET_SERVER_URL"] = "bitbucket-server-url"
+	viperMappings["GITEA_URL"] = "gitea-url"
+	viperMappings["AZURE_DEVOPS_CLOSE_THREAD"] = "azure-devops-close-thread"
+	viperMappings["API_TIMEOUT"] = "api-timeout"
+	viperMappings["HIDE_OUTDATED"] = "hide-outdated"
+	viperMappings["ONLY_OWN_COMMENTS"] = "only-own-comments"
+	viperMappings["COMMENT_AUTHOR"] = "comment-author"
+	viperMappings["AGGREGATE_COMMENT"] = "aggregate-comment"
 	viperMappings["GITHUB_ENTERPRISE_MAX_COMMENT_LENGTH"] = "github-max-comment-length"
 	viperMappings["SUPPRESS_HASH_CHANGES"] = "suppress-hash-changes"
 	viperMappings["SUPPRESS_HASH_CHANGES_REGEX"] = "suppress-hash-changes-regex"
 	viperMappings["NO_TRUNCATE"] = "no-truncate"
+	viperMappings["SPLIT_STACKS"] = "split-stacks"
+	viperMappings["SPLIT_COMMENTS"] = "split-comments"
+	viperMappings["DETAILED_EXITCODE"] = "detailed-exitcode"
+	viperMappings["PROTECTED_RESOURCE_TYPES"] = "protected-resource-types"
+	viperMappings["PROTECTED_LOGICAL_IDS"] = "protected-logical-ids"
+	viperMappings["RULES_FILE"] = "rules-file"
 
 	for k, v := range viperMappings {
-		err := viper.BindPFlag(k, rootCmd.Flags().Lookup(v))
+		err := viper.BindPFlag(k, rootCmd.PersistentFlags().Lookup(v))
 		if err != nil {
 			logrus.Error(err)
 		}
@@ -146,6 +158,254 @@ func init() {
 	}
 }
 
+// notifyResult is the outcome of processing the cdk diff logs
+type notifyResult struct {
+	// ExitCode is the highest detailed exit code of all logs
+	ExitCode                    int
+	ProtectedResourceViolations []transform.ProtectedResourceViolation
+	// RuleFailures are the results of rules with action fail
+	RuleFailures []transform.RuleResult
+	// Labels are added to the pull request by rules with action label
+	Labels []string
+}
+
+// add merges the result of another log
+func (r *notifyResult) add(other notifyResult) {
+	r.ExitCode = max(r.ExitCode, other.ExitCode)
+	r.ProtectedResourceViolations = append(r.ProtectedResourceViolations, other.ProtectedResourceViolations...)
+	r.RuleFailures = append(r.RuleFailures, other.RuleFailures...)
+	for _, label := range other.Labels {
+		if !slices.Contains(r.Labels, label) {
+			r.Labels = append(r.Labels, label)
+		}
+	}
+}
+
+// notify processes all cdk diff logs and posts the comments to the pull request.
+// stdin is read when a log file is config.LogFileStdin.
+func notify(ctx context.Context, appConfig *config.NotifierConfig, stdin io.Reader) notifyResult {
+	groups, result := processLogs(appConfig, stdin)
+	if appConfig.NoPostMode {
+		return result
+	}
+	postGroups(ctx, appConfig, groups, result.Labels)
+	return result
+}
+
+// initConfig reads the configuration. Rules are compiled as well, so invalid rules fail before running cdk diff or posting.
+func initConfig() *config.NotifierConfig {
+	appConfig := &config.NotifierConfig{}
+	err := appConfig.Init()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	err = transform.ValidateRules(appConfig.Rules)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return appConfig
+}
+
+// processLogs transforms all cdk diff logs into their comments
+func processLogs(appConfig *config.NotifierConfig, stdin io.Reader) ([]provider.CommentGroup, notifyResult) {
+	inputs, err := appConfig.LogInputs()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	var groups []provider.CommentGroup
+	result := notifyResult{ExitCode: transform.ExitCodeNoChanges}
+	for _, input := range inputs {
+		group, inputResult := processLog(appConfig, input, stdin)
+		groups = append(groups, group)
+		result.add(inputResult)
+	}
+	return groups, result
+}
+
+// postGroups posts the comments to the pull request and adds the labels
+func postGroups(ctx context.Context, appConfig *config.NotifierConfig, groups []provider.CommentGroup, labels []string) {
+	if appConfig.PullRequestID == 0 {
+		err := &config.ValidationError{CliArg: "pull-request-id", EnvVar: []string{"PR_ID", config.EnvCiCircleCiPullRequestID, config.EnvCiBitbucketPrId, config.EnvCiGitlabMrId}}
+		logrus.Warnf("Skipping... because %s", err)
+		return
+	}
+
+	if appConfig.AggregateComment == "" {
+		markComments(groups, appConfig)
+	}
+	singleComment := len(groups) == 1 && !appConfig.SplitStacks && !appConfig.SplitComments
+	if singleComment {
+		// if there are only hash changes we also want to delete the comment
+		appConfig.ForceDeleteComment = groups[0].ForceDelete
+	}
+	notifier, cancel := createNotifier(ctx, appConfig)
+	defer cancel()
+	if _, ok := notifier.(provider.CommentMinimizer); appConfig.HideOutdated && !ok {
+		logrus.Warnf("Outdated comments are deleted because hiding comments is not supported by %s", appConfig.Vcs)
+	}
+	var err error
+	switch {
+	case appConfig.AggregateComment != "":
+		_, err = provider.PostAggregateComment(notifier, *appConfig, groups, commentMarker(appConfig))
+	case !singleComment:
+		_, err = notifier.PostComments(groups)
+	default:
+		notifier.SetCommentContent(groups[0].Comments[0].Content)
+		_, err = notifier.PostComment()
+	}
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	addLabels(notifier, labels)
+}
+
+// createNotifier creates the client of the VCS. The API timeout starts with the creation.
+func createNotifier(ctx context.Context, appConfig *config.NotifierConfig) (provider.NotifierService, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancel := func() {}
+	if appConfig.ApiTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, appConfig.ApiTimeout)
+	}
+	notifier, err := provider.CreateNotifierService(ctx, *appConfig)
+	if err != nil {
+		cancel()
+		logrus.Fatalln(err)
+	}
+	return notifier, cancel
+}
+
+// commentMarker returns the marker with the version and commit used for all comments
+func commentMarker(appConfig *config.NotifierConfig) provider.CommentMarker {
+	version := Version
+	if version == "" {
+		version = "dev"
+	}
+	return provider.CommentMarker{Version: version, CommitSha: appConfig.CommitSha}
+}
+
+// markComments embeds the hidden marker into every comment and continuation to identify them on the next run
+func markComments(groups []provider.CommentGroup, appConfig *config.NotifierConfig) {
+	for _, group := range groups {
+		for i := range group.Comments {
+			comment := &group.Comments[i]
+			marker := commentMarker(appConfig)
+			marker.TagID = comment.TagID
+			marker.Stacks = comment.Stacks
+			comment.Content = marker.Embed(comment.Content)
+			for j := range comment.Continuations {
+				marker.TagID = provider.PartTagID(comment.TagID, j+2)
+				comment.Continuations[j] = marker.Embed(comment.Continuations[j])
+			}
+		}
+	}
+}
+
+// addLabels adds the labels of matching rules to the pull request if supported by the VCS
+func addLabels(notifier provider.NotifierService, labels []string) {
+	if len(labels) == 0 {
+		return
+	}
+	labeler, ok := notifier.(provider.Labeler)
+	if !ok {
+		logrus.Warnf("Skipping labels %v because labels are not supported by the VCS", labels)
+		return
+	}
+	logrus.Infof("Adding labels %v to pull request", labels)
+	err := labeler.AddLabels(labels)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+}
+
+// exitWithResult fails when protected resources are removed or replaced or rules with action fail match.
+// Otherwise it exits with the detailed exit code when detailed-exitcode is set.
+func exitWithResult(appConfig *config.NotifierConfig, result notifyResult) {
+	if len(result.ProtectedResourceViolations) > 0 {
+		logrus.Errorf("Found %d removed or replaced protected resources:", len(result.ProtectedResourceViolations))
+		for _, violation := range result.ProtectedResourceViolations {
+			logrus.Errorf("  %s", violation)
+		}
+	}
+	if len(result.RuleFailures) > 0 {
+		logrus.Errorf("Found %d failing rules:", len(result.RuleFailures))
+		for _, failure := range result.RuleFailures {
+			logrus.Errorf("  %s", failure)
+		}
+	}
+	if len(result.ProtectedResourceViolations) > 0 || len(result.RuleFailures) > 0 {
+		os.Exit(1)
+	}
+	if !appConfig.DetailedExitCode || result.ExitCode == transform.ExitCodeNoChanges {
+		return
+	}
+	logrus.Infof("Exiting with detailed exit code %d", result.ExitCode)
+	os.Exit(result.ExitCode)
+}
+
+// processLog transforms a single cdk diff log into the comments for its tag id
+func processLog(appConfig *config.NotifierConfig, input config.LogInput, stdin io.Reader) (provider.CommentGroup, notifyResult) {
+	inputConfig := *appConfig
+	inputConfig.LogFile = input.Path
+	inputConfig.TagID = input.TagID
+	transformer := transform.NewLogTransformer(&inputConfig)
+	transformer.Input = stdin
+	transformer.Process()
+
+	group := provider.CommentGroup{TagID: input.TagID}
+	result := notifyResult{
+		ExitCode:                    transformer.DetailedExitCode(),
+		ProtectedResourceViolations: transformer.ProtectedResourceViolations,
+		RuleFailures:                transformer.RuleResultsByAction(config.RuleActionFail),
+		Labels:                      transformer.RuleLabels(),
+	}
+	for _, warning := range transformer.RuleResultsByAction(config.RuleActionWarn) {
+		logrus.Warnf("Rule %s", warning)
+	}
+	if appConfig.SuppressHashChanges {
+		logrus.Warnf("Suppressing hash changes detected %d hash changes and %d total changes for tag id %s", transformer.HashChanges, transformer.TotalChanges, input.TagID)
+		if transformer.OnlyHashChanges() {
+			logrus.Warnf("Skipping... because suppress-hash-changes is set and only hash changes detected")
+			group.ForceDelete = true
+			result.ExitCode = transform.ExitCodeNoChanges
+			// rules matching only hash changes are ignored as well
+			result.RuleFailures = nil
+			result.Labels = nil
+		}
+	}
+	if appConfig.SplitStacks {
+		if !group.ForceDelete {
+			group.Comments = stackComments(transformer, appConfig)
+		}
+		return group, result
+	}
+	group.Comments = []provider.ManagedComment{transformer.ManagedComment()}
+	return group, result
+}
+
+// stackComments creates one comment per stack with differences.
+// Stacks with only hash changes are skipped when suppress-hash-changes is set, so their comment gets deleted.
+func stackComments(transformer *transform.LogTransformer, appConfig *config.NotifierConfig) []provider.ManagedComment {
+	var comments []provider.ManagedComment
+	for _, stackTransformer := range transformer.StackTransformers() {
+		if stackTransformer.OnlyHashChanges() {
+			logrus.Warnf("Skipping stack %s because suppress-hash-changes is set and only hash changes detected", stackTransformer.StackName)
+			continue
+		}
+		comments = append(comments, stackTransformer.ManagedComment())
+	}
+	return comments
+}
+
+// logOutput returns stderr for commands whose stdout is piped into other steps like render and post --body-file -
+func logOutput(cmd *cobra.Command) io.Writer {
+	if cmd == renderCmd || cmd == postCmd {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 func setUpLogs(out io.Writer, level string) error {
 	logrus.SetOutput(out)
 	lvl, err := logrus.ParseLevel(level)
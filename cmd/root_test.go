@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogOutput(t *testing.T) {
+	assert.Equal(t, os.Stderr, logOutput(renderCmd), "expect the rendered comment to be the only output on stdout")
+	assert.Equal(t, os.Stderr, logOutput(postCmd))
+	assert.Equal(t, os.Stdout, logOutput(rootCmd))
+	assert.Equal(t, os.Stdout, logOutput(validateCmd))
+}
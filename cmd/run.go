@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// runCmd executes cdk diff itself and posts the captured output
+var runCmd = &cobra.Command{
+	Use:   "run -- command [args...]",
+	Short: "Run cdk diff and post its output to Pull Request",
+	Long: `Run the given command, e.g. npx cdk diff, stream its output to the console and post the captured output to Pull Request.
+The exit code of the command is preserved and takes precedence over detailed exit codes. If the command fails, existing comments are not deleted
+and the posted comments are labeled as incomplete with the exit code because the diff might be incomplete.`,
+	Example: "cdk-notifier run --tag-id dev -- npx cdk diff --progress=events",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		appConfig := initConfig()
+		if len(appConfig.LogFiles) > 0 {
+			logrus.Fatal("log-files can not be used with run, the output of the command is used as log")
+		}
+
+		output := &lockedWriter{}
+		exitCode, err := runCommand(args, os.Stdin, io.MultiWriter(os.Stdout, output), io.MultiWriter(os.Stderr, output))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if exitCode != 0 {
+			logrus.Warnf("Command exited with code %d. Existing comments will not be deleted and comments are labeled as incomplete", exitCode)
+			appConfig.DeleteComment = false
+		}
+
+		appConfig.LogFile = config.LogFileStdin
+		groups, result := processLogs(appConfig, &output.buffer)
+		if exitCode != 0 {
+			markIncomplete(groups, exitCode)
+		}
+		if !appConfig.NoPostMode {
+			postGroups(cmd.Context(), appConfig, groups, result.Labels)
+		}
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+		exitWithResult(appConfig, result)
+	},
+}
+
+// incompleteBanner is prefixed to comments of a command that failed, so a partial diff is not mistaken for the complete diff
+func incompleteBanner(exitCode int) string {
+	return fmt.Sprintf("> ⚠️ **Incomplete** — command exited with code %d, the diff might be incomplete\n\n", exitCode)
+}
+
+// markIncomplete prefixes all comments and their continuations with the incomplete banner
+func markIncomplete(groups []provider.CommentGroup, exitCode int) {
+	for i := range groups {
+		for j := range groups[i].Comments {
+			comment := &groups[i].Comments[j]
+			comment.Content = incompleteBanner(exitCode) + comment.Content
+			for k := range comment.Continuations {
+				comment.Continuations[k] = incompleteBanner(exitCode) + comment.Continuations[k]
+			}
+		}
+	}
+}
+
+// lockedWriter captures stdout and stderr of the command which are written concurrently
+type lockedWriter struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(p)
+}
+
+// runCommand executes the command and returns its exit code.
+// An error is only returned if the command could not be started.
+func runCommand(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	logrus.Infof("Running %v", args)
+	command := exec.Command(args[0], args[1:]...)
+	command.Stdin = stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func init() {
+	// flags after the command belong to the command, e.g. run npx cdk diff --app ...
+	runCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(runCmd)
+}
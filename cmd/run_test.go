@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommand(t *testing.T) {
+	var console bytes.Buffer
+	output := &lockedWriter{}
+	exitCode, err := runCommand([]string{"sh", "-c", "echo diff; echo warning >&2; exit 2"}, strings.NewReader(""), io.MultiWriter(&console, output), output)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "diff\n", console.String())
+	assert.Contains(t, output.buffer.String(), "diff\n")
+	assert.Contains(t, output.buffer.String(), "warning\n")
+
+	exitCode, err = runCommand([]string{"true"}, nil, io.Discard, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+
+	_, err = runCommand([]string{"cdk-notifier-not-existing-command"}, nil, io.Discard, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestMarkIncomplete(t *testing.T) {
+	groups := []provider.CommentGroup{{TagID: "dev", Comments: []provider.ManagedComment{
+		{TagID: "dev", Content: "## cdk diff for dev (part 1/2)", Continuations: []string{"## cdk diff for dev (part 2/2)"}},
+	}}}
+	markIncomplete(groups, 1)
+	comment := groups[0].Comments[0]
+	assert.Equal(t, "> ⚠️ **Incomplete** — command exited with code 1, the diff might be incomplete\n\n## cdk diff for dev (part 1/2)", comment.Content)
+	assert.Equal(t, []string{"> ⚠️ **Incomplete** — command exited with code 1, the diff might be incomplete\n\n## cdk diff for dev (part 2/2)"}, comment.Continuations)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// markStaleCmd marks the existing comments as outdated at the start of a pipeline
+var markStaleCmd = &cobra.Command{
+	Use:   "mark-stale",
+	Short: "Mark existing comments as outdated while the diff is recomputed",
+	Long: `Prefix the existing comments of the tag id and its per stack and continuation comments with a banner that the diff is being recomputed.
+Run it at the start of the pipeline. The banner is removed when the new diff is posted.`,
+	Example: "cdk-notifier mark-stale --tag-id dev",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		appConfig := initConfig()
+		markStale(cmd.Context(), appConfig)
+	},
+}
+
+// markStale prefixes the comments of the tag id with the outdated banner
+func markStale(ctx context.Context, appConfig *config.NotifierConfig) {
+	if appConfig.NoPostMode {
+		logrus.Warnf("Skipping... because no-post-mode is set")
+		return
+	}
+	if appConfig.PullRequestID == 0 {
+		err := &config.ValidationError{CliArg: "pull-request-id", EnvVar: []string{"PR_ID", config.EnvCiCircleCiPullRequestID, config.EnvCiBitbucketPrId, config.EnvCiGitlabMrId}}
+		logrus.Warnf("Skipping... because %s", err)
+		return
+	}
+	notifier, cancel := createNotifier(ctx, appConfig)
+	defer cancel()
+	operations, err := provider.MarkStale(notifier, *appConfig, commentMarker(appConfig))
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	if len(operations) == 0 {
+		logrus.Infof("No comments found for tag id %s", appConfig.TagID)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(markStaleCmd)
+}
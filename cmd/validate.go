@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/transform"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd checks the configuration before running cdk diff
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate configuration and comment template",
+	Long: `Check the configuration, log files, rules file and comment template without reading a cdk diff log or posting.
+Token, repository and pull request are checked unless no-post-mode is set.`,
+	Example: `cdk-notifier validate --tag-id dev --custom-template template.md
+cdk-notifier validate --no-post-mode --rules-file rules.yaml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		appConfig := initConfig()
+		err := validate(appConfig)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("Configuration is valid")
+	},
+}
+
+// validate checks the parts of the configuration which are only used when processing the logs
+func validate(appConfig *config.NotifierConfig) error {
+	if _, err := appConfig.LogInputs(); err != nil {
+		return err
+	}
+	if err := transform.ValidateRules(appConfig.Rules); err != nil {
+		return err
+	}
+	return transform.NewLogTransformer(appConfig).ValidateTemplate()
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	appConfig := &config.NotifierConfig{
+		LogFile:  "../data/cdk-small.log",
+		TagID:    "dev",
+		Vcs:      config.VcsGithub,
+		Template: "default",
+		Rules:    []config.Rule{{Name: "replacement", When: "replacement", Action: config.RuleActionWarn}},
+	}
+	assert.NoError(t, validate(appConfig))
+
+	appConfig.Rules = append(appConfig.Rules, config.Rule{Name: "broken", When: `type == `, Action: config.RuleActionFail})
+	assert.ErrorContains(t, validate(appConfig), "invalid when 'type == ' for rule 'broken'")
+
+	appConfig.Rules = nil
+	appConfig.CustomTemplate = "## cdk diff for {{ .TagID"
+	assert.Error(t, validate(appConfig))
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -82,8 +85,14 @@ const (
 	EnvBitbucketToken = "BITBUCKET_TOKEN"
 	// EnvGitlabToken Name of environment variable for Gitlab token
 	EnvGitlabToken = "GITLAB_TOKEN"
+	// EnvGiteaToken Name of environment variable for Gitea and Forgejo token
+	EnvGiteaToken = "GITEA_TOKEN"
+	// EnvAzureDevopsToken Name of environment variable for Azure DevOps personal access token
+	EnvAzureDevopsToken = "AZURE_DEVOPS_TOKEN"
 	// EnvBitbucketUser Name of environment variable for bitbucket user
 	EnvBitbucketUser = "BITBUCKET_USER"
+	// EnvAwsDefaultRegion Name of environment variable for the AWS region used when AWS_REGION is not set
+	EnvAwsDefaultRegion = "AWS_DEFAULT_REGION"
 
 	// EnvCiCircleCiPullRequestID Name of environment variable for pull request url
 	EnvCiCircleCiPullRequestID = "CIRCLE_PULL_REQUEST"
@@ -99,6 +108,26 @@ const (
 	// EnvCiBitbucketRepoName Bitbucket CI variable for repo name
 	EnvCiBitbucketRepoName = "BITBUCKET_REPO_SLUG"
 
+	// EnvCiAzurePipelinesPrId Azure Pipelines variable for pull request id - only available on pull request triggered builds
+	EnvCiAzurePipelinesPrId = "SYSTEM_PULLREQUEST_PULLREQUESTID"
+	// EnvCiAzurePipelinesRepoName Azure Pipelines variable for repo name
+	EnvCiAzurePipelinesRepoName = "BUILD_REPOSITORY_NAME"
+	// EnvCiAzurePipelinesProject Azure Pipelines variable for the project containing the repo
+	EnvCiAzurePipelinesProject = "SYSTEM_TEAMPROJECT"
+	// EnvCiAzurePipelinesCollectionUri Azure Pipelines variable for the organization url e.g. https://dev.azure.com/org/
+	EnvCiAzurePipelinesCollectionUri = "SYSTEM_COLLECTIONURI"
+	// EnvCiAzurePipelinesToken Azure Pipelines variable for the job access token. It has to be mapped explicitly in the pipeline
+	EnvCiAzurePipelinesToken = "SYSTEM_ACCESSTOKEN"
+
+	// EnvCiWoodpeckerPrId Woodpecker variable for pull request number - only available on pull request triggered pipelines
+	EnvCiWoodpeckerPrId = "CI_COMMIT_PULL_REQUEST"
+	// EnvCiWoodpeckerRepoOwner Woodpecker variable for repo owner
+	EnvCiWoodpeckerRepoOwner = "CI_REPO_OWNER"
+	// EnvCiWoodpeckerRepoName Woodpecker variable for repo name
+	EnvCiWoodpeckerRepoName = "CI_REPO_NAME"
+	// EnvCiWoodpeckerForgeUrl Woodpecker variable for the url of the forge e.g. https://codeberg.org
+	EnvCiWoodpeckerForgeUrl = "CI_FORGE_URL"
+
 	// EnvCiGitlabMrId Name of environment variable for Gitlab merge request id
 	EnvCiGitlabMrId = "CI_MERGE_REQUEST_IID"
 	// EnvCiGitlabUrl Name of environment variable for Gitlab Base Url
@@ -111,37 +140,69 @@ const (
 	VcsGithub           = "github"
 	VcsGithubEnterprise = "github-enterprise"
 	VcsBitbucket        = "bitbucket"
+	VcsBitbucketServer  = "bitbucket-server"
 	VcsGitlab           = "gitlab"
+	VcsCodeCommit       = "codecommit"
+	VcsAzureDevops      = "azuredevops"
+	VcsGitea            = "gitea"
 
-	CiCircleCi  = "circleci"
-	CiBitbucket = "bitbucket"
-	CiGitlab    = "gitlab"
+	CiCircleCi      = "circleci"
+	CiBitbucket     = "bitbucket"
+	CiGitlab        = "gitlab"
+	CiGithubActions = "githubactions"
+
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
 )
 
 // NotifierConfig holds configuration
 type NotifierConfig struct {
-	LogFile                  string `mapstructure:"LOG_FILE"`
-	TagID                    string `mapstructure:"TAG_ID"`
-	RepoName                 string `mapstructure:"REPO_NAME"`
-	RepoOwner                string `mapstructure:"REPO_OWNER"`
-	Token                    string `mapstructure:"TOKEN"`
-	TokenUser                string `mapstructure:"TOKEN_USER"`
-	PullRequestID            int    `mapstructure:"PR_ID"`
-	DeleteComment            bool   `mapstructure:"DELETE_COMMENT"`
-	Vcs                      string `mapstructure:"VERSION_CONTROL_SYSTEM"`
-	Ci                       string `mapstructure:"CI_SYSTEM"`
-	Url                      string `mapstructure:"URL"`
-	GithubHost               string `mapstructure:"GITHUB_ENTERPRISE_HOST"`
-	GithubMaxCommentLength   int    `mapstructure:"GITHUB_ENTERPRISE_MAX_COMMENT_LENGTH"`
-	NoPostMode               bool   `mapstructure:"NO_POST_MODE"`
-	DisableCollapse          bool   `mapstructure:"DISABLE_COLLAPSE"`
-	Template                 string `mapstructure:"NOTIFIER_TEMPLATE"`
-	CustomTemplate           string `mapstructure:"CUSTOM_TEMPLATE"`
-	SuppressHashChanges      bool   `mapstructure:"SUPPRESS_HASH_CHANGES"`
-	SuppressHashChangesRegex string `mapstructure:"SUPPRESS_HASH_CHANGES_REGEX"`
-	ShowOverview             bool   `mapstructure:"SHOW_OVERVIEW"` // TODO deprecated
-	NoTruncate               bool   `mapstructure:"NO_TRUNCATE"`
-	ForceDeleteComment       bool   // only used for suppress hash changes in order to delete comment if no-op
+	LogFile                  string   `mapstructure:"LOG_FILE"`
+	LogFiles                 []string `mapstructure:"LOG_FILES"`
+	TagID                    string   `mapstructure:"TAG_ID"`
+	RepoName                 string   `mapstructure:"REPO_NAME"`
+	RepoOwner                string   `mapstructure:"REPO_OWNER"`
+	Token                    string   `mapstructure:"TOKEN"`
+	TokenUser                string   `mapstructure:"TOKEN_USER"`
+	PullRequestID            int      `mapstructure:"PR_ID"`
+	DeleteComment            bool     `mapstructure:"DELETE_COMMENT"`
+	Vcs                      string   `mapstructure:"VERSION_CONTROL_SYSTEM"`
+	Ci                       string   `mapstructure:"CI_SYSTEM"`
+	Url                      string   `mapstructure:"URL"`
+	GithubHost               string   `mapstructure:"GITHUB_ENTERPRISE_HOST"`
+	GithubMaxCommentLength   int      `mapstructure:"GITHUB_ENTERPRISE_MAX_COMMENT_LENGTH"`
+	NoPostMode               bool     `mapstructure:"NO_POST_MODE"`
+	Output                   string   `mapstructure:"OUTPUT"`
+	DisableCollapse          bool     `mapstructure:"DISABLE_COLLAPSE"`
+	Template                 string   `mapstructure:"NOTIFIER_TEMPLATE"`
+	CustomTemplate           string   `mapstructure:"CUSTOM_TEMPLATE"`
+	SuppressHashChanges      bool     `mapstructure:"SUPPRESS_HASH_CHANGES"`
+	SuppressHashChangesRegex string   `mapstructure:"SUPPRESS_HASH_CHANGES_REGEX"`
+	ShowOverview             bool     `mapstructure:"SHOW_OVERVIEW"` // TODO deprecated
+	NoTruncate               bool     `mapstructure:"NO_TRUNCATE"`
+	SplitStacks              bool     `mapstructure:"SPLIT_STACKS"`
+	SplitComments            bool     `mapstructure:"SPLIT_COMMENTS"`
+	DetailedExitCode         bool     `mapstructure:"DETAILED_EXITCODE"`
+	ProtectedResourceTypes   []string `mapstructure:"PROTECTED_RESOURCE_TYPES"`
+	ProtectedLogicalIDs      []string `mapstructure:"PROTECTED_LOGICAL_IDS"`
+	RulesFile                string   `mapstructure:"RULES_FILE"`
+	CommitSha                string   `mapstructure:"COMMIT_SHA"`
+	Branch                   string   `mapstructure:"BRANCH"`
+	JobLink                  string   `mapstructure:"JOB_LINK"`
+	AwsRegion                string   `mapstructure:"AWS_REGION"`
+	AzureDevopsUrl           string   `mapstructure:"AZURE_DEVOPS_URL"`
+	BitbucketServerUrl       string   `mapstructure:"BITBUCKET_SERVER_URL"`
+	GiteaUrl                 string   `mapstructure:"GITEA_URL"`
+	AzureDevopsCloseThread   bool     `mapstructure:"AZURE_DEVOPS_CLOSE_THREAD"`
+	HideOutdated             bool     `mapstructure:"HIDE_OUTDATED"`
+	OnlyOwnComments          bool     `mapstructure:"ONLY_OWN_COMMENTS"`
+	CommentAuthor            string   `mapstructure:"COMMENT_AUTHOR"`
+	AggregateComment         string   `mapstructure:"AGGREGATE_COMMENT"`
+	Rules                    []Rule   // loaded from RulesFile
+	ForceDeleteComment       bool     // only used for suppress hash changes in order to delete comment if no-op
+
+	// ApiTimeout bounds all calls to the VCS API including retries
+	ApiTimeout time.Duration `mapstructure:"API_TIMEOUT"`
 }
 
 // Init will create default NotifierConfig with following priority
@@ -154,6 +215,17 @@ func (c *NotifierConfig) Init() error {
 		logrus.Errorln(err)
 		return err
 	}
+	err = c.loadRules()
+	if err != nil {
+		logrus.Errorln(err)
+		return err
+	}
+	err = c.setCiInfo()
+	if err != nil {
+		logrus.Errorln(err)
+		return err
+	}
+	c.setGitInfo()
 	err = c.validate()
 	if err != nil {
 		logrus.Errorln(err)
@@ -175,38 +247,48 @@ func (c *NotifierConfig) loadViperConfig() error {
 	if err != nil {
 		return err
 	}
+	if c.Ci == CiAuto {
+		if detector := DetectCi(); detector != nil {
+			c.Ci = detector.Name
+		}
+	}
 	return nil
 }
 
 // create binding to map individual CI environment variables to Config struct fields
 func createBindings() map[string]string {
-	ci := viper.GetString("ci_system")
 	bindings := make(map[string]string)
-	switch ci {
-	case CiBitbucket:
-		bindings[EnvCiBitbucketPrId] = "PR_ID"
-		bindings[EnvCiBitbucketRepoName] = "REPO_NAME"
-		bindings[EnvCiBitbucketRepoOwner] = "REPO_OWNER"
-	case CiCircleCi:
-		bindings[EnvCiCircleCiRepoName] = "REPO_NAME"
-		bindings[EnvCiCircleCiRepoOwner] = "REPO_OWNER"
-	case CiGitlab:
-		bindings[EnvCiGitlabMrId] = "PR_ID"
-		bindings[EnvCiGitlabRepoName] = "REPO_NAME"
-		bindings[EnvCiGitlabRepoOwner] = "REPO_OWNER"
-		bindings[EnvCiGitlabUrl] = "URL"
-	default:
-		logrus.Warnf("Could not detect CI environment from '%s'. Skipping override from CI Env vars", ci)
+	detector := resolveCiDetector(viper.GetString("ci_system"))
+	if detector != nil {
+		for env, key := range detector.Bindings {
+			bindings[env] = key
+		}
 	}
 	// mapping token environment vars regardless of environment since no conflicts expected
 	bindings[EnvBitbucketUser] = "TOKEN_USER"
 	bindings[EnvBitbucketToken] = "TOKEN"
 	bindings[EnvGithubToken] = "TOKEN"
 	bindings[EnvGitlabToken] = "TOKEN"
+	bindings[EnvAzureDevopsToken] = "TOKEN"
+	bindings[EnvGiteaToken] = "TOKEN"
 	return bindings
 }
 
 func (c *NotifierConfig) validate() error {
+	if c.Output != "" && c.Output != OutputMarkdown && c.Output != OutputJSON {
+		return fmt.Errorf("unsupported output '%s'. Use one of [%s|%s]", c.Output, OutputMarkdown, OutputJSON)
+	}
+	for _, pattern := range slices.Concat(c.ProtectedResourceTypes, c.ProtectedLogicalIDs) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected resource pattern '%s': %w", pattern, err)
+		}
+	}
+	if c.AggregateComment != "" && c.SplitComments {
+		return fmt.Errorf("aggregate-comment can not be combined with split-comments")
+	}
+	if c.ApiTimeout < 0 {
+		return fmt.Errorf("api-timeout must not be negative but is %s", c.ApiTimeout)
+	}
 	if c.NoPostMode {
 		return nil
 	}
@@ -217,12 +299,35 @@ func (c *NotifierConfig) validate() error {
 	if c.RepoName == "" {
 		return &ValidationError{"repo", []string{"REPO_NAME", EnvCiCircleCiRepoName, EnvCiBitbucketRepoName, EnvCiGitlabRepoName}}
 	}
+	if c.Vcs == VcsCodeCommit {
+		return c.validateCodeCommit()
+	}
 	if c.RepoOwner == "" {
 		return &ValidationError{"owner", []string{"REPO_OWNER", EnvCiCircleCiRepoOwner, EnvCiBitbucketRepoOwner, EnvCiGitlabRepoOwner}}
 	}
 	if c.Token == "" {
 		return &ValidationError{"token", []string{"TOKEN", EnvGithubToken, EnvBitbucketToken, EnvGitlabToken}}
 	}
+	if c.Vcs == VcsAzureDevops && c.AzureDevopsUrl == "" {
+		return &ValidationError{"azure-devops-url", []string{"AZURE_DEVOPS_URL", EnvCiAzurePipelinesCollectionUri}}
+	}
+	if c.Vcs == VcsBitbucketServer && c.BitbucketServerUrl == "" {
+		return &ValidationError{"bitbucket-server-url", []string{"BITBUCKET_SERVER_URL"}}
+	}
+	if c.Vcs == VcsGitea && c.GiteaUrl == "" {
+		return &ValidationError{"gitea-url", []string{"GITEA_URL", EnvCiWoodpeckerForgeUrl, EnvCiGithubServerUrl}}
+	}
+	return nil
+}
+
+// validateCodeCommit checks the region. CodeCommit uses AWS credentials instead of owner and token.
+func (c *NotifierConfig) validateCodeCommit() error {
+	if c.AwsRegion == "" {
+		c.AwsRegion = os.Getenv(EnvAwsDefaultRegion)
+	}
+	if c.AwsRegion == "" {
+		return &ValidationError{"aws-region", []string{"AWS_REGION", EnvAwsDefaultRegion}}
+	}
 	return nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -382,6 +383,32 @@ func TestNotifierConfig_Init(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			description: "test azure pipelines bindings",
+			inputConfig: NotifierConfig{
+				LogFile: "./cdk.log",
+			},
+			vcs: VcsAzureDevops,
+			ci:  CiAzurePipelines,
+			envVars: map[string]string{
+				EnvCiAzurePipelinesPrId:          "24",
+				EnvCiAzurePipelinesRepoName:      "my-repo",
+				EnvCiAzurePipelinesProject:       "my-project",
+				EnvCiAzurePipelinesCollectionUri: "https://dev.azure.com/org/",
+				EnvCiAzurePipelinesToken:         "some-token",
+			},
+			expectedConfig: NotifierConfig{
+				LogFile:        "./cdk.log",
+				RepoName:       "my-repo",
+				RepoOwner:      "my-project",
+				Token:          "some-token",
+				PullRequestID:  24,
+				AzureDevopsUrl: "https://dev.azure.com/org/",
+				Ci:             CiAzurePipelines,
+				Vcs:            VcsAzureDevops,
+			},
+			err: nil,
+		},
 	}
 	for _, c := range testCasesInit {
 		t.Run(c.description, func(t *testing.T) {
@@ -403,3 +430,70 @@ func TestNotifierConfig_Init(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifierConfig_ValidateOutput(t *testing.T) {
+	c := NotifierConfig{NoPostMode: true, Output: OutputJSON}
+	assert.NoError(t, c.validate())
+	c.Output = ""
+	assert.NoError(t, c.validate())
+	c.Output = "yaml"
+	assert.EqualError(t, c.validate(), "unsupported output 'yaml'. Use one of [markdown|json]")
+}
+
+func TestNotifierConfig_ValidateProtectedResources(t *testing.T) {
+	c := NotifierConfig{NoPostMode: true, ProtectedResourceTypes: []string{"AWS::RDS::*", "AWS::DynamoDB::Table"}, ProtectedLogicalIDs: []string{"Orders*"}}
+	assert.NoError(t, c.validate())
+	c.ProtectedLogicalIDs = []string{"Orders["}
+	assert.EqualError(t, c.validate(), "invalid protected resource pattern 'Orders[': syntax error in pattern")
+}
+
+func TestNotifierConfig_ValidateCodeCommit(t *testing.T) {
+	t.Setenv(EnvCiCircleCiPullRequestID, "")
+	t.Setenv(EnvAwsDefaultRegion, "")
+	c := NotifierConfig{Vcs: VcsCodeCommit, RepoName: "my-repo", PullRequestID: 1}
+	assert.EqualError(t, c.validate(), "missing argument. Set --aws-region argument or env var [AWS_REGION AWS_DEFAULT_REGION]")
+	t.Setenv(EnvAwsDefaultRegion, "eu-west-1")
+	assert.NoError(t, c.validate())
+	assert.Equal(t, "eu-west-1", c.AwsRegion)
+	c.AwsRegion = "us-east-1"
+	assert.NoError(t, c.validate())
+	assert.Equal(t, "us-east-1", c.AwsRegion)
+}
+
+func TestNotifierConfig_ValidateAzureDevops(t *testing.T) {
+	t.Setenv(EnvCiCircleCiPullRequestID, "")
+	c := NotifierConfig{Vcs: VcsAzureDevops, RepoName: "my-repo", RepoOwner: "my-project", Token: "pat", PullRequestID: 1}
+	assert.EqualError(t, c.validate(), "missing argument. Set --azure-devops-url argument or env var [AZURE_DEVOPS_URL SYSTEM_COLLECTIONURI]")
+	c.AzureDevopsUrl = "https://dev.azure.com/org"
+	assert.NoError(t, c.validate())
+}
+
+func TestNotifierConfig_ValidateBitbucketServer(t *testing.T) {
+	t.Setenv(EnvCiCircleCiPullRequestID, "")
+	c := NotifierConfig{Vcs: VcsBitbucketServer, RepoName: "my-repo", RepoOwner: "PROJ", Token: "some-token", PullRequestID: 1}
+	assert.EqualError(t, c.validate(), "missing argument. Set --bitbucket-server-url argument or env var [BITBUCKET_SERVER_URL]")
+	c.BitbucketServerUrl = "https://bitbucket.example.com"
+	assert.NoError(t, c.validate())
+}
+
+func TestNotifierConfig_ValidateGitea(t *testing.T) {
+	t.Setenv(EnvCiCircleCiPullRequestID, "")
+	c := NotifierConfig{Vcs: VcsGitea, RepoName: "my-repo", RepoOwner: "my-org", Token: "some-token", PullRequestID: 1}
+	assert.EqualError(t, c.validate(), "missing argument. Set --gitea-url argument or env var [GITEA_URL CI_FORGE_URL GITHUB_SERVER_URL]")
+	c.GiteaUrl = "https://codeberg.org"
+	assert.NoError(t, c.validate())
+}
+
+func TestNotifierConfig_ValidateApiTimeout(t *testing.T) {
+	c := NotifierConfig{NoPostMode: true, ApiTimeout: -time.Second}
+	assert.EqualError(t, c.validate(), "api-timeout must not be negative but is -1s")
+	c.ApiTimeout = time.Minute
+	assert.NoError(t, c.validate())
+}
+
+func TestNotifierConfig_ValidateAggregateComment(t *testing.T) {
+	c := NotifierConfig{NoPostMode: true, AggregateComment: "cdk", SplitComments: true}
+	assert.EqualError(t, c.validate(), "aggregate-comment can not be combined with split-comments")
+	c.SplitComments = false
+	assert.NoError(t, c.validate())
+}
@@ -0,0 +1,412 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// CiAuto detects the CI system from its environment variables
+	CiAuto = "auto"
+
+	CiJenkins   = "jenkins"
+	CiBuildkite = "buildkite"
+	CiTeamCity  = "teamcity"
+	CiDrone     = "drone"
+	CiCodeBuild = "codebuild"
+	// CiAzurePipelines is Azure Pipelines of Azure DevOps
+	CiAzurePipelines = "azurepipelines"
+	CiWoodpecker     = "woodpecker"
+	// CiForgejoActions is Forgejo Actions or Gitea Actions
+	CiForgejoActions = "forgejoactions"
+)
+
+// CiInfo is the pull request and build information provided by a CI system
+type CiInfo struct {
+	PullRequestID int
+	RepoOwner     string
+	RepoName      string
+	CommitSha     string
+	Branch        string
+	JobLink       string
+	// GithubHost is set when the CI runs against GitHub Enterprise
+	GithubHost string
+}
+
+// CiDetector describes how to detect a CI system and how to read the pull request and build information from its environment
+type CiDetector struct {
+	// Name is the value of the --ci flag
+	Name string
+	// Detect returns true if cdk-notifier is running on the CI system
+	Detect func() bool
+	// Bindings maps environment variables of the CI system to config keys, e.g. BITBUCKET_PR_ID to PR_ID
+	Bindings map[string]string
+	// Info reads the values which can not be mapped by Bindings. Values set by CLI args or environment variables take precedence.
+	Info func() (CiInfo, error)
+}
+
+// ciDetectors are checked in order when the CI system is detected automatically
+var ciDetectors []CiDetector
+
+// RegisterCiDetector adds a CI system. Detectors registered first take precedence in auto-detection.
+func RegisterCiDetector(detector CiDetector) {
+	ciDetectors = append(ciDetectors, detector)
+}
+
+// CiDetectors returns the names of all registered CI systems
+func CiDetectors() []string {
+	var names []string
+	for _, detector := range ciDetectors {
+		names = append(names, detector.Name)
+	}
+	return names
+}
+
+// FindCiDetector returns the CI system with the given name or nil
+func FindCiDetector(name string) *CiDetector {
+	for i := range ciDetectors {
+		if ciDetectors[i].Name == name {
+			return &ciDetectors[i]
+		}
+	}
+	return nil
+}
+
+// DetectCi returns the first CI system detected from the environment or nil
+func DetectCi() *CiDetector {
+	for i := range ciDetectors {
+		if ciDetectors[i].Detect() {
+			return &ciDetectors[i]
+		}
+	}
+	return nil
+}
+
+// resolveCiDetector returns the detector for the --ci flag. For auto the CI system is detected from the environment.
+func resolveCiDetector(ci string) *CiDetector {
+	if ci == CiAuto {
+		detector := DetectCi()
+		if detector == nil {
+			logrus.Infof("Could not detect CI environment. Skipping override from CI Env vars")
+		}
+		return detector
+	}
+	detector := FindCiDetector(ci)
+	if detector == nil {
+		logrus.Warnf("Could not detect CI environment from '%s'. Skipping override from CI Env vars", ci)
+	}
+	return detector
+}
+
+// setCiInfo fills all values not set by CLI args or environment variables from the CI system
+func (c *NotifierConfig) setCiInfo() error {
+	detector := FindCiDetector(c.Ci)
+	if detector == nil || detector.Info == nil {
+		return nil
+	}
+	info, err := detector.Info()
+	if err != nil {
+		return err
+	}
+	if c.PullRequestID == 0 {
+		c.PullRequestID = info.PullRequestID
+	}
+	if c.RepoOwner == "" {
+		c.RepoOwner = info.RepoOwner
+	}
+	if c.RepoName == "" {
+		c.RepoName = info.RepoName
+	}
+	if c.CommitSha == "" {
+		c.CommitSha = info.CommitSha
+	}
+	if c.Branch == "" {
+		c.Branch = info.Branch
+	}
+	if c.JobLink == "" {
+		c.JobLink = info.JobLink
+	}
+	if info.GithubHost != "" && c.GithubHost == "" {
+		c.GithubHost = info.GithubHost
+		if c.Vcs == VcsGithub {
+			logrus.Infof("Using GitHub Enterprise %s detected from %s", info.GithubHost, c.Ci)
+			c.Vcs = VcsGithubEnterprise
+		}
+	}
+	return nil
+}
+
+// codeBuildInfo reads the pull request from the webhook trigger e.g. pr/123.
+// Builds started by CodeCommit events have no webhook, the pull request id has to be set by PR_ID.
+func codeBuildInfo() (CiInfo, error) {
+	info := CiInfo{
+		CommitSha: os.Getenv("CODEBUILD_RESOLVED_SOURCE_VERSION"),
+		Branch:    strings.TrimPrefix(os.Getenv("CODEBUILD_WEBHOOK_HEAD_REF"), "refs/heads/"),
+		JobLink:   os.Getenv("CODEBUILD_BUILD_URL"),
+	}
+	sourceUrl := os.Getenv("CODEBUILD_SOURCE_REPO_URL")
+	if _, name, found := strings.Cut(sourceUrl, "/v1/repos/"); found && strings.Contains(sourceUrl, "git-codecommit.") {
+		// CodeCommit repositories have no owner
+		info.RepoName = name
+	} else {
+		info.RepoOwner, info.RepoName = repoFromGitUrl(sourceUrl)
+	}
+	for _, env := range []string{"CODEBUILD_WEBHOOK_TRIGGER", "CODEBUILD_SOURCE_VERSION"} {
+		number, found := strings.CutPrefix(os.Getenv(env), "pr/")
+		if !found {
+			continue
+		}
+		var err error
+		info.PullRequestID, err = strconv.Atoi(number)
+		if err != nil {
+			return info, fmt.Errorf("unable to parse pull request number '%s' from %s: %w", number, env, err)
+		}
+		break
+	}
+	return info, nil
+}
+
+// forgejoActionsInfo reads the GitHub Actions compatible environment of Forgejo and Gitea Actions
+func forgejoActionsInfo() (CiInfo, error) {
+	info, err := githubActionsInfo()
+	// the server url is the Gitea url and not a GitHub Enterprise host
+	info.GithubHost = ""
+	if runNumber := os.Getenv("GITHUB_RUN_NUMBER"); runNumber != "" {
+		// runs are addressed by their number within the repo
+		info.JobLink = fmt.Sprintf("%s/%s/actions/runs/%s", strings.TrimSuffix(os.Getenv(EnvCiGithubServerUrl), "/"), os.Getenv(EnvCiGithubRepository), runNumber)
+	}
+	return info, err
+}
+
+// envPullRequestID parses the pull request number from the environment variable. Values like "false" are ignored.
+func envPullRequestID(env string) (int, error) {
+	value := os.Getenv(env)
+	if value == "" || value == "false" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse pull request number '%s' from %s: %w", value, env, err)
+	}
+	return number, nil
+}
+
+// repoFromGitUrl extracts owner and repo from a git remote url like https://github.com/owner/repo.git or git@github.com:owner/repo.git
+func repoFromGitUrl(gitUrl string) (string, string) {
+	path := gitUrl
+	if u, err := url.Parse(gitUrl); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, after, found := strings.Cut(gitUrl, ":"); found {
+		path = after
+	}
+	path = strings.Trim(strings.TrimSuffix(path, ".git"), "/")
+	index := strings.LastIndex(path, "/")
+	if index == -1 {
+		return "", ""
+	}
+	return path[:index], path[index+1:]
+}
+
+// firstEnv returns the value of the first environment variable which is not empty
+func firstEnv(envs ...string) string {
+	for _, env := range envs {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func init() {
+	// Forgejo and Gitea Actions set GITHUB_ACTIONS as well and have to be detected first
+	RegisterCiDetector(CiDetector{
+		Name: CiForgejoActions,
+		Detect: func() bool {
+			return os.Getenv("FORGEJO_ACTIONS") == "true" || os.Getenv("GITEA_ACTIONS") == "true"
+		},
+		Bindings: map[string]string{
+			EnvCiGithubServerUrl: "GITEA_URL",
+		},
+		Info: forgejoActionsInfo,
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiGithubActions,
+		Detect: func() bool { return os.Getenv("GITHUB_ACTIONS") == "true" },
+		Info:   githubActionsInfo,
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiBitbucket,
+		Detect: func() bool { return os.Getenv("BITBUCKET_BUILD_NUMBER") != "" },
+		Bindings: map[string]string{
+			EnvCiBitbucketPrId:      "PR_ID",
+			EnvCiBitbucketRepoName:  "REPO_NAME",
+			EnvCiBitbucketRepoOwner: "REPO_OWNER",
+		},
+		Info: func() (CiInfo, error) {
+			info := CiInfo{
+				CommitSha: os.Getenv("BITBUCKET_COMMIT"),
+				Branch:    os.Getenv("BITBUCKET_BRANCH"),
+			}
+			if buildNumber := os.Getenv("BITBUCKET_BUILD_NUMBER"); buildNumber != "" {
+				info.JobLink = fmt.Sprintf("https://bitbucket.org/%s/%s/pipelines/results/%s", os.Getenv("BITBUCKET_WORKSPACE"), os.Getenv(EnvCiBitbucketRepoName), buildNumber)
+			}
+			return info, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiCircleCi,
+		Detect: func() bool { return os.Getenv("CIRCLECI") == "true" },
+		Bindings: map[string]string{
+			EnvCiCircleCiRepoName:  "REPO_NAME",
+			EnvCiCircleCiRepoOwner: "REPO_OWNER",
+		},
+		Info: func() (CiInfo, error) {
+			return CiInfo{
+				CommitSha: os.Getenv("CIRCLE_SHA1"),
+				Branch:    os.Getenv("CIRCLE_BRANCH"),
+				JobLink:   os.Getenv("CIRCLE_BUILD_URL"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiGitlab,
+		Detect: func() bool { return os.Getenv("GITLAB_CI") == "true" },
+		Bindings: map[string]string{
+			EnvCiGitlabMrId:      "PR_ID",
+			EnvCiGitlabRepoName:  "REPO_NAME",
+			EnvCiGitlabRepoOwner: "REPO_OWNER",
+			EnvCiGitlabUrl:       "URL",
+		},
+		Info: func() (CiInfo, error) {
+			return CiInfo{
+				CommitSha: os.Getenv("CI_COMMIT_SHA"),
+				Branch:    firstEnv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_REF_NAME"),
+				JobLink:   os.Getenv("CI_JOB_URL"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiJenkins,
+		Detect: func() bool { return os.Getenv("JENKINS_URL") != "" },
+		Info: func() (CiInfo, error) {
+			// CHANGE_* variables are set by multibranch pipelines for pull requests
+			number, err := envPullRequestID("CHANGE_ID")
+			if err != nil {
+				return CiInfo{}, err
+			}
+			owner, repo := repoFromGitUrl(os.Getenv("GIT_URL"))
+			return CiInfo{
+				PullRequestID: number,
+				RepoOwner:     owner,
+				RepoName:      repo,
+				CommitSha:     os.Getenv("GIT_COMMIT"),
+				Branch:        firstEnv("CHANGE_BRANCH", "BRANCH_NAME"),
+				JobLink:       os.Getenv("BUILD_URL"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiBuildkite,
+		Detect: func() bool { return os.Getenv("BUILDKITE") == "true" },
+		Info: func() (CiInfo, error) {
+			number, err := envPullRequestID("BUILDKITE_PULL_REQUEST")
+			if err != nil {
+				return CiInfo{}, err
+			}
+			owner, repo := repoFromGitUrl(os.Getenv("BUILDKITE_REPO"))
+			return CiInfo{
+				PullRequestID: number,
+				RepoOwner:     owner,
+				RepoName:      repo,
+				CommitSha:     os.Getenv("BUILDKITE_COMMIT"),
+				Branch:        os.Getenv("BUILDKITE_BRANCH"),
+				JobLink:       os.Getenv("BUILDKITE_BUILD_URL"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiTeamCity,
+		Detect: func() bool { return os.Getenv("TEAMCITY_VERSION") != "" },
+		Info: func() (CiInfo, error) {
+			// TeamCity does not expose pull request information by default. It has to be passed as env parameters e.g.
+			// env.TEAMCITY_PULL_REQUEST_NUMBER=%teamcity.pullRequest.number%
+			number, err := envPullRequestID("TEAMCITY_PULL_REQUEST_NUMBER")
+			if err != nil {
+				return CiInfo{}, err
+			}
+			return CiInfo{
+				PullRequestID: number,
+				CommitSha:     os.Getenv("BUILD_VCS_NUMBER"),
+				Branch:        os.Getenv("TEAMCITY_BRANCH"),
+				JobLink:       os.Getenv("TEAMCITY_BUILD_URL"),
+			}, nil
+		},
+	})
+	// Woodpecker is a fork of Drone and has to be detected first
+	RegisterCiDetector(CiDetector{
+		Name:   CiWoodpecker,
+		Detect: func() bool { return os.Getenv("CI") == "woodpecker" },
+		Bindings: map[string]string{
+			EnvCiWoodpeckerPrId:      "PR_ID",
+			EnvCiWoodpeckerRepoOwner: "REPO_OWNER",
+			EnvCiWoodpeckerRepoName:  "REPO_NAME",
+			EnvCiWoodpeckerForgeUrl:  "GITEA_URL",
+		},
+		Info: func() (CiInfo, error) {
+			return CiInfo{
+				CommitSha: os.Getenv("CI_COMMIT_SHA"),
+				Branch:    firstEnv("CI_COMMIT_SOURCE_BRANCH", "CI_COMMIT_BRANCH"),
+				JobLink:   firstEnv("CI_PIPELINE_URL", "CI_BUILD_LINK"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiDrone,
+		Detect: func() bool { return os.Getenv("DRONE") == "true" },
+		Info: func() (CiInfo, error) {
+			number, err := envPullRequestID("DRONE_PULL_REQUEST")
+			if err != nil {
+				return CiInfo{}, err
+			}
+			return CiInfo{
+				PullRequestID: number,
+				RepoOwner:     os.Getenv("DRONE_REPO_OWNER"),
+				RepoName:      os.Getenv("DRONE_REPO_NAME"),
+				CommitSha:     os.Getenv("DRONE_COMMIT_SHA"),
+				Branch:        firstEnv("DRONE_SOURCE_BRANCH", "DRONE_BRANCH"),
+				JobLink:       os.Getenv("DRONE_BUILD_LINK"),
+			}, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiAzurePipelines,
+		Detect: func() bool { return strings.EqualFold(os.Getenv("TF_BUILD"), "true") },
+		Bindings: map[string]string{
+			EnvCiAzurePipelinesPrId:          "PR_ID",
+			EnvCiAzurePipelinesRepoName:      "REPO_NAME",
+			EnvCiAzurePipelinesProject:       "REPO_OWNER",
+			EnvCiAzurePipelinesCollectionUri: "AZURE_DEVOPS_URL",
+			EnvCiAzurePipelinesToken:         "TOKEN",
+		},
+		Info: func() (CiInfo, error) {
+			info := CiInfo{
+				CommitSha: firstEnv("SYSTEM_PULLREQUEST_SOURCECOMMITID", "BUILD_SOURCEVERSION"),
+				Branch:    strings.TrimPrefix(firstEnv("SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH"), "refs/heads/"),
+			}
+			if buildID := os.Getenv("BUILD_BUILDID"); buildID != "" {
+				info.JobLink = fmt.Sprintf("%s%s/_build/results?buildId=%s", os.Getenv(EnvCiAzurePipelinesCollectionUri), url.PathEscape(os.Getenv(EnvCiAzurePipelinesProject)), buildID)
+			}
+			return info, nil
+		},
+	})
+	RegisterCiDetector(CiDetector{
+		Name:   CiCodeBuild,
+		Detect: func() bool { return os.Getenv("CODEBUILD_BUILD_ID") != "" },
+		Info:   codeBuildInfo,
+	})
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clearCiEnv unsets the environment variables used to detect CI systems
+func clearCiEnv(t *testing.T) {
+	for _, env := range []string{"GITHUB_ACTIONS", "BITBUCKET_BUILD_NUMBER", "CIRCLECI", "GITLAB_CI", "JENKINS_URL", "BUILDKITE", "TEAMCITY_VERSION", "DRONE", "TF_BUILD", "CODEBUILD_BUILD_ID", "FORGEJO_ACTIONS", "GITEA_ACTIONS", "CI"} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestDetectCi(t *testing.T) {
+	clearCiEnv(t)
+	assert.Nil(t, DetectCi())
+	assert.Nil(t, resolveCiDetector(CiAuto))
+
+	t.Setenv("DRONE", "true")
+	assert.Equal(t, CiDrone, DetectCi().Name)
+	// jenkins is registered before drone
+	t.Setenv("JENKINS_URL", "https://jenkins.example.com/")
+	assert.Equal(t, CiJenkins, DetectCi().Name)
+	assert.Equal(t, CiJenkins, resolveCiDetector(CiAuto).Name)
+	// an explicit CI system is not detected
+	assert.Equal(t, CiGitlab, resolveCiDetector(CiGitlab).Name)
+	assert.Nil(t, resolveCiDetector("unknown"))
+
+	// Forgejo Actions sets GITHUB_ACTIONS as well
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.Equal(t, CiGithubActions, DetectCi().Name)
+	t.Setenv("FORGEJO_ACTIONS", "true")
+	assert.Equal(t, CiForgejoActions, DetectCi().Name)
+}
+
+func TestRegisterCiDetector(t *testing.T) {
+	clearCiEnv(t)
+	registered := ciDetectors
+	t.Cleanup(func() { ciDetectors = registered })
+
+	RegisterCiDetector(CiDetector{
+		Name:   "custom",
+		Detect: func() bool { return true },
+		Info:   func() (CiInfo, error) { return CiInfo{PullRequestID: 3}, nil },
+	})
+	assert.Contains(t, CiDetectors(), "custom")
+	assert.Equal(t, "custom", FindCiDetector("custom").Name)
+	assert.Equal(t, "custom", DetectCi().Name)
+	assert.Nil(t, FindCiDetector("unknown"))
+}
+
+func TestCiDetectorInfo(t *testing.T) {
+	tests := []struct {
+		ci       string
+		env      map[string]string
+		expected CiInfo
+	}{
+		{
+			ci: CiJenkins,
+			env: map[string]string{
+				"JENKINS_URL":   "https://jenkins.example.com/",
+				"CHANGE_ID":     "21",
+				"CHANGE_BRANCH": "feature",
+				"BRANCH_NAME":   "PR-21",
+				"GIT_URL":       "https://github.com/karlderkaefer/cdk-notifier.git",
+				"GIT_COMMIT":    "abc123",
+				"BUILD_URL":     "https://jenkins.example.com/job/cdk-notifier/PR-21/3/",
+			},
+			expected: CiInfo{PullRequestID: 21, RepoOwner: "karlderkaefer", RepoName: "cdk-notifier", CommitSha: "abc123", Branch: "feature", JobLink: "https://jenkins.example.com/job/cdk-notifier/PR-21/3/"},
+		},
+		{
+			ci: CiBuildkite,
+			env: map[string]string{
+				"BUILDKITE":              "true",
+				"BUILDKITE_PULL_REQUEST": "false",
+				"BUILDKITE_REPO":         "git@github.com:karlderkaefer/cdk-notifier.git",
+				"BUILDKITE_COMMIT":       "abc123",
+				"BUILDKITE_BRANCH":       "main",
+				"BUILDKITE_BUILD_URL":    "https://buildkite.com/org/pipeline/builds/5",
+			},
+			expected: CiInfo{RepoOwner: "karlderkaefer", RepoName: "cdk-notifier", CommitSha: "abc123", Branch: "main", JobLink: "https://buildkite.com/org/pipeline/builds/5"},
+		},
+		{
+			ci: CiTeamCity,
+			env: map[string]string{
+				"TEAMCITY_VERSION":             "2024.03",
+				"TEAMCITY_PULL_REQUEST_NUMBER": "22",
+				"BUILD_VCS_NUMBER":             "abc123",
+				"TEAMCITY_BRANCH":              "feature",
+				"TEAMCITY_BUILD_URL":           "https://teamcity.example.com/viewLog.html?buildId=7",
+			},
+			expected: CiInfo{PullRequestID: 22, CommitSha: "abc123", Branch: "feature", JobLink: "https://teamcity.example.com/viewLog.html?buildId=7"},
+		},
+		{
+			ci: CiDrone,
+			env: map[string]string{
+				"DRONE":               "true",
+				"DRONE_PULL_REQUEST":  "23",
+				"DRONE_REPO_OWNER":    "karlderkaefer",
+				"DRONE_REPO_NAME":     "cdk-notifier",
+				"DRONE_COMMIT_SHA":    "abc123",
+				"DRONE_SOURCE_BRANCH": "feature",
+				"DRONE_BRANCH":        "main",
+				"DRONE_BUILD_LINK":    "https://drone.example.com/karlderkaefer/cdk-notifier/9",
+			},
+			expected: CiInfo{PullRequestID: 23, RepoOwner: "karlderkaefer", RepoName: "cdk-notifier", CommitSha: "abc123", Branch: "feature", JobLink: "https://drone.example.com/karlderkaefer/cdk-notifier/9"},
+		},
+		{
+			ci: CiAzurePipelines,
+			env: map[string]string{
+				"TF_BUILD":                          "True",
+				"SYSTEM_COLLECTIONURI":              "https://dev.azure.com/org/",
+				"SYSTEM_TEAMPROJECT":                "My Project",
+				"SYSTEM_PULLREQUEST_SOURCEBRANCH":   "refs/heads/feature",
+				"SYSTEM_PULLREQUEST_SOURCECOMMITID": "abc123",
+				"BUILD_SOURCEVERSION":               "merge123",
+				"BUILD_BUILDID":                     "42",
+			},
+			expected: CiInfo{CommitSha: "abc123", Branch: "feature", JobLink: "https://dev.azure.com/org/My%20Project/_build/results?buildId=42"},
+		},
+		{
+			ci: CiCodeBuild,
+			env: map[string]string{
+				"CODEBUILD_BUILD_ID":                "cdk-notifier:0b5a9d2c",
+				"CODEBUILD_WEBHOOK_TRIGGER":         "pr/24",
+				"CODEBUILD_SOURCE_VERSION":          "pr/24",
+				"CODEBUILD_SOURCE_REPO_URL":         "https://github.com/karlderkaefer/cdk-notifier.git",
+				"CODEBUILD_RESOLVED_SOURCE_VERSION": "abc123",
+				"CODEBUILD_WEBHOOK_HEAD_REF":        "refs/heads/feature",
+				"CODEBUILD_BUILD_URL":               "https://eu-west-1.console.aws.amazon.com/codesuite/codebuild/projects/cdk-notifier/build/cdk-notifier%3A0b5a9d2c",
+			},
+			expected: CiInfo{PullRequestID: 24, RepoOwner: "karlderkaefer", RepoName: "cdk-notifier", CommitSha: "abc123", Branch: "feature", JobLink: "https://eu-west-1.console.aws.amazon.com/codesuite/codebuild/projects/cdk-notifier/build/cdk-notifier%3A0b5a9d2c"},
+		},
+		{
+			ci: CiWoodpecker,
+			env: map[string]string{
+				"CI":                      "woodpecker",
+				"CI_COMMIT_SHA":           "abc123",
+				"CI_COMMIT_SOURCE_BRANCH": "feature",
+				"CI_COMMIT_BRANCH":        "main",
+				"CI_PIPELINE_URL":         "https://ci.codeberg.org/repos/12/pipeline/8",
+			},
+			expected: CiInfo{CommitSha: "abc123", Branch: "feature", JobLink: "https://ci.codeberg.org/repos/12/pipeline/8"},
+		},
+		{
+			ci: CiForgejoActions,
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"FORGEJO_ACTIONS":   "true",
+				"GITHUB_SERVER_URL": "https://codeberg.org",
+				"GITHUB_REPOSITORY": "karlderkaefer/cdk-notifier",
+				"GITHUB_EVENT_PATH": "",
+				"GITHUB_HEAD_REF":   "feature",
+				"GITHUB_SHA":        "abc123",
+				"GITHUB_RUN_ID":     "4711",
+				"GITHUB_RUN_NUMBER": "12",
+			},
+			expected: CiInfo{RepoOwner: "karlderkaefer", RepoName: "cdk-notifier", CommitSha: "abc123", Branch: "feature", JobLink: "https://codeberg.org/karlderkaefer/cdk-notifier/actions/runs/12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ci, func(t *testing.T) {
+			clearCiEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			detector := DetectCi()
+			assert.NotNil(t, detector)
+			assert.Equal(t, tt.ci, detector.Name)
+			info, err := detector.Info()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, info)
+		})
+	}
+}
+
+func TestCodeBuildInfo(t *testing.T) {
+	clearCiEnv(t)
+	t.Setenv("CODEBUILD_BUILD_ID", "cdk-notifier:0b5a9d2c")
+	t.Setenv("CODEBUILD_SOURCE_REPO_URL", "https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/my-repo")
+	t.Setenv("CODEBUILD_WEBHOOK_TRIGGER", "")
+	t.Setenv("CODEBUILD_SOURCE_VERSION", "refs/heads/main")
+	info, err := codeBuildInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "", info.RepoOwner)
+	assert.Equal(t, "my-repo", info.RepoName)
+	assert.Equal(t, 0, info.PullRequestID)
+
+	t.Setenv("CODEBUILD_WEBHOOK_TRIGGER", "branch/main")
+	t.Setenv("CODEBUILD_SOURCE_VERSION", "pr/25")
+	info, err = codeBuildInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, 25, info.PullRequestID)
+
+	t.Setenv("CODEBUILD_WEBHOOK_TRIGGER", "pr/abc")
+	_, err = codeBuildInfo()
+	assert.EqualError(t, err, "unable to parse pull request number 'abc' from CODEBUILD_WEBHOOK_TRIGGER: strconv.Atoi: parsing \"abc\": invalid syntax")
+}
+
+func TestNotifierConfig_SetCiInfo(t *testing.T) {
+	clearCiEnv(t)
+	t.Setenv("DRONE_PULL_REQUEST", "23")
+	t.Setenv("DRONE_REPO_OWNER", "karlderkaefer")
+	t.Setenv("DRONE_REPO_NAME", "cdk-notifier")
+	t.Setenv("DRONE_COMMIT_SHA", "abc123")
+	t.Setenv("DRONE_BUILD_LINK", "https://drone.example.com/karlderkaefer/cdk-notifier/9")
+
+	// values from CLI args or environment variables take precedence
+	c := NotifierConfig{Ci: CiDrone, RepoName: "other", PullRequestID: 5, JobLink: "https://example.com/job"}
+	assert.NoError(t, c.setCiInfo())
+	assert.Equal(t, "karlderkaefer", c.RepoOwner)
+	assert.Equal(t, "other", c.RepoName)
+	assert.Equal(t, 5, c.PullRequestID)
+	assert.Equal(t, "abc123", c.CommitSha)
+	assert.Equal(t, "https://example.com/job", c.JobLink)
+
+	t.Setenv("DRONE_PULL_REQUEST", "abc")
+	c = NotifierConfig{Ci: CiDrone}
+	assert.EqualError(t, c.setCiInfo(), "unable to parse pull request number 'abc' from DRONE_PULL_REQUEST: strconv.Atoi: parsing \"abc\": invalid syntax")
+
+	// unknown CI systems are ignored
+	c = NotifierConfig{Ci: "unknown"}
+	assert.NoError(t, c.setCiInfo())
+}
+
+func TestRepoFromGitUrl(t *testing.T) {
+	tests := []struct {
+		url   string
+		owner string
+		repo  string
+	}{
+		{url: "https://github.com/karlderkaefer/cdk-notifier.git", owner: "karlderkaefer", repo: "cdk-notifier"},
+		{url: "git@github.com:karlderkaefer/cdk-notifier.git", owner: "karlderkaefer", repo: "cdk-notifier"},
+		{url: "https://gitlab.com/group/subgroup/project", owner: "group/subgroup", repo: "project"},
+		{url: "ssh://git@bitbucket.org/workspace/repo.git", owner: "workspace", repo: "repo"},
+		{url: "", owner: "", repo: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo := repoFromGitUrl(tt.url)
+			assert.Equal(t, tt.owner, owner)
+			assert.Equal(t, tt.repo, repo)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gitRevParse runs git rev-parse in the working directory. It is replaced in tests.
+var gitRevParse = func(args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"rev-parse"}, args...)...).Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// setGitInfo fills commit sha and branch from the git repository if neither CLI args nor the CI system provided them
+func (c *NotifierConfig) setGitInfo() {
+	if c.CommitSha == "" {
+		sha, err := gitRevParse("HEAD")
+		if err != nil {
+			logrus.Debugf("Unable to read commit sha from git: %s", err)
+		} else {
+			c.CommitSha = sha
+		}
+	}
+	if c.Branch == "" {
+		branch, err := gitRevParse("--abbrev-ref", "HEAD")
+		// detached checkouts of CI systems have no branch
+		if err == nil && branch != "HEAD" {
+			c.Branch = branch
+		}
+	}
+}
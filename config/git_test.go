@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// tests must not depend on the repository they are running in
+	gitRevParse = func(args ...string) (string, error) {
+		return "", errors.New("not a git repository")
+	}
+}
+
+func TestNotifierConfig_SetGitInfo(t *testing.T) {
+	original := gitRevParse
+	defer func() { gitRevParse = original }()
+
+	branch := "feature"
+	gitRevParse = func(args ...string) (string, error) {
+		if args[0] == "HEAD" {
+			return "abc123", nil
+		}
+		return branch, nil
+	}
+	c := NotifierConfig{}
+	c.setGitInfo()
+	assert.Equal(t, "abc123", c.CommitSha)
+	assert.Equal(t, "feature", c.Branch)
+
+	// values of CLI args and CI system are kept
+	c = NotifierConfig{CommitSha: "def456", Branch: "main"}
+	c.setGitInfo()
+	assert.Equal(t, "def456", c.CommitSha)
+	assert.Equal(t, "main", c.Branch)
+
+	branch = "HEAD"
+	c = NotifierConfig{}
+	c.setGitInfo()
+	assert.Equal(t, "abc123", c.CommitSha)
+	assert.Empty(t, c.Branch, "expect no branch for detached checkouts")
+}
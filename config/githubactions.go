@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// EnvCiGithubRepository GitHub Actions variable for owner and repo name e.g. octocat/Hello-World
+	EnvCiGithubRepository = "GITHUB_REPOSITORY"
+	// EnvCiGithubEventName GitHub Actions variable for the name of the event that triggered the workflow
+	EnvCiGithubEventName = "GITHUB_EVENT_NAME"
+	// EnvCiGithubEventPath GitHub Actions variable for the path of the file with the complete event payload
+	EnvCiGithubEventPath = "GITHUB_EVENT_PATH"
+	// EnvCiGithubServerUrl GitHub Actions variable for the URL of the GitHub server e.g. https://github.com
+	EnvCiGithubServerUrl = "GITHUB_SERVER_URL"
+
+	githubServerUrl = "https://github.com"
+)
+
+// merge queue branches look like gh-readonly-queue/main/pr-123-5f2b1e0c...
+var regexMergeGroupHeadRef = regexp.MustCompile(`/pr-(\d+)-[0-9a-f]+$`)
+
+// githubEvent contains the fields of the GitHub Actions event payload used to find the pull request
+type githubEvent struct {
+	PullRequest *struct {
+		Number int `json:"number"`
+		Head   struct {
+			Sha string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	MergeGroup *struct {
+		HeadRef string `json:"head_ref"`
+	} `json:"merge_group"`
+	Issue *struct {
+		Number int `json:"number"`
+		// PullRequest is only set when the issue is a pull request
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+}
+
+// githubActionsInfo reads repo, owner, pull request and GitHub Enterprise host from the GitHub Actions environment
+func githubActionsInfo() (CiInfo, error) {
+	serverUrl := strings.TrimSuffix(os.Getenv(EnvCiGithubServerUrl), "/")
+	repository := os.Getenv(EnvCiGithubRepository)
+	info := CiInfo{
+		// GITHUB_HEAD_REF is only set for pull request events
+		Branch:    firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME"),
+		CommitSha: os.Getenv("GITHUB_SHA"),
+	}
+	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+		info.JobLink = fmt.Sprintf("%s/%s/actions/runs/%s", serverUrl, repository, runID)
+	}
+	if owner, repo, found := strings.Cut(repository, "/"); found {
+		info.RepoOwner = owner
+		info.RepoName = repo
+	}
+	if serverUrl != "" && serverUrl != githubServerUrl {
+		info.GithubHost = serverUrl
+	}
+	event, err := readGithubEvent(os.Getenv(EnvCiGithubEventPath))
+	if err != nil {
+		return info, err
+	}
+	if event.PullRequest != nil && event.PullRequest.Head.Sha != "" {
+		// GITHUB_SHA is the merge commit for pull request events
+		info.CommitSha = event.PullRequest.Head.Sha
+	}
+	info.PullRequestID, err = githubActionsPullRequest(os.Getenv(EnvCiGithubEventName), event)
+	return info, err
+}
+
+// readGithubEvent reads the event payload. An empty event is returned if no payload exists.
+func readGithubEvent(eventPath string) (*githubEvent, error) {
+	event := &githubEvent{}
+	if eventPath == "" {
+		return event, nil
+	}
+	content, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GitHub event payload '%s': %w", eventPath, err)
+	}
+	err = json.Unmarshal(content, event)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GitHub event payload '%s': %w", eventPath, err)
+	}
+	return event, nil
+}
+
+// githubActionsPullRequest returns the pull request number of the event.
+// Returns 0 if the event is not related to a pull request e.g. push.
+func githubActionsPullRequest(eventName string, event *githubEvent) (int, error) {
+	switch eventName {
+	case "pull_request", "pull_request_target", "pull_request_review", "pull_request_review_comment":
+		if event.PullRequest != nil {
+			return event.PullRequest.Number, nil
+		}
+	case "merge_group":
+		if event.MergeGroup == nil {
+			break
+		}
+		matches := regexMergeGroupHeadRef.FindStringSubmatch(event.MergeGroup.HeadRef)
+		if matches == nil {
+			return 0, fmt.Errorf("unable to extract pull request number from merge group head ref '%s'", event.MergeGroup.HeadRef)
+		}
+		return strconv.Atoi(matches[1])
+	case "issue_comment":
+		if event.Issue != nil && event.Issue.PullRequest != nil {
+			return event.Issue.Number, nil
+		}
+	}
+	logrus.Warnf("GitHub event '%s' is not related to a pull request", eventName)
+	return 0, nil
+}
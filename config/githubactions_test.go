@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeGithubEvent(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "event.json")
+	err := os.WriteFile(file, []byte(content), 0644)
+	assert.NoError(t, err)
+	return file
+}
+
+func TestGithubActionsPullRequest(t *testing.T) {
+	tests := []struct {
+		eventName string
+		payload   string
+		expected  int
+	}{
+		{eventName: "pull_request", payload: `{"number": 12, "pull_request": {"number": 12}}`, expected: 12},
+		{eventName: "pull_request_target", payload: `{"pull_request": {"number": 13}}`, expected: 13},
+		{eventName: "merge_group", payload: `{"merge_group": {"head_ref": "refs/heads/gh-readonly-queue/main/pr-14-f0fd6ae1e0bd0b8f5ae67e8b4b9cfc7eaf8f4a17"}}`, expected: 14},
+		{eventName: "issue_comment", payload: `{"issue": {"number": 15, "pull_request": {"url": "https://api.github.com/repos/octocat/Hello-World/pulls/15"}}}`, expected: 15},
+		{eventName: "issue_comment", payload: `{"issue": {"number": 16}}`, expected: 0},
+		{eventName: "push", payload: `{"ref": "refs/heads/main"}`, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventName, func(t *testing.T) {
+			event, err := readGithubEvent(writeGithubEvent(t, tt.payload))
+			assert.NoError(t, err)
+			number, err := githubActionsPullRequest(tt.eventName, event)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, number)
+		})
+	}
+}
+
+func TestGithubActionsPullRequestErrors(t *testing.T) {
+	event, err := readGithubEvent(writeGithubEvent(t, `{"merge_group": {"head_ref": "refs/heads/main"}}`))
+	assert.NoError(t, err)
+	_, err = githubActionsPullRequest("merge_group", event)
+	assert.EqualError(t, err, "unable to extract pull request number from merge group head ref 'refs/heads/main'")
+	_, err = readGithubEvent(writeGithubEvent(t, `{`))
+	assert.ErrorContains(t, err, "unable to parse GitHub event payload")
+	_, err = readGithubEvent("/tmp/nonexisting-dir/event.json")
+	assert.ErrorContains(t, err, "unable to read GitHub event payload")
+	event, err = readGithubEvent("")
+	assert.NoError(t, err)
+	number, err := githubActionsPullRequest("pull_request", event)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, number)
+}
+
+func TestNotifierConfig_SetGithubActionsInfo(t *testing.T) {
+	t.Setenv(EnvCiGithubRepository, "octocat/Hello-World")
+	t.Setenv(EnvCiGithubEventName, "pull_request")
+	t.Setenv(EnvCiGithubEventPath, writeGithubEvent(t, `{"pull_request": {"number": 42, "head": {"sha": "6dcb09b5b57875f334f61aebed695e2e4193db5e"}}}`))
+	t.Setenv(EnvCiGithubServerUrl, "https://github.com")
+	t.Setenv("GITHUB_RUN_ID", "1658821493")
+	t.Setenv("GITHUB_SHA", "ffac537e6cbbf934b08745a378932722df287a53")
+	t.Setenv("GITHUB_HEAD_REF", "feature-branch")
+
+	c := NotifierConfig{Ci: CiGithubActions, Vcs: VcsGithub, Token: "some-token"}
+	err := c.setCiInfo()
+	assert.NoError(t, err)
+	assert.NoError(t, c.validate())
+	assert.Equal(t, "octocat", c.RepoOwner)
+	assert.Equal(t, "Hello-World", c.RepoName)
+	assert.Equal(t, 42, c.PullRequestID)
+	assert.Equal(t, "6dcb09b5b57875f334f61aebed695e2e4193db5e", c.CommitSha)
+	assert.Equal(t, "feature-branch", c.Branch)
+	assert.Equal(t, "https://github.com/octocat/Hello-World/actions/runs/1658821493", c.JobLink)
+	assert.Equal(t, VcsGithub, c.Vcs)
+	assert.Equal(t, "", c.GithubHost)
+
+	// values from CLI args or environment variables are kept
+	t.Setenv(EnvCiGithubServerUrl, "https://github.example.com/")
+	c = NotifierConfig{Ci: CiGithubActions, Vcs: VcsGithub, RepoName: "other", PullRequestID: 7}
+	err = c.setCiInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "octocat", c.RepoOwner)
+	assert.Equal(t, "other", c.RepoName)
+	assert.Equal(t, 7, c.PullRequestID)
+	assert.Equal(t, VcsGithubEnterprise, c.Vcs)
+	assert.Equal(t, "https://github.example.com", c.GithubHost)
+}
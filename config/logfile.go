@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// LogFileStdin is used as log file to read the cdk diff from stdin
+	LogFileStdin = "-"
+	// logFileTagSeparator separates the tag id from the path in --log-files entries
+	logFileTagSeparator = "="
+)
+
+// LogInput is a single cdk diff log with the tag id used for its comment
+type LogInput struct {
+	TagID string
+	Path  string
+}
+
+// LogInputs returns all cdk diff logs to process.
+// Without LogFiles the LogFile is processed with the TagID.
+// Entries of LogFiles are either "tag=path" or a path or glob pattern. Without tag the file name without extension is used as tag id.
+func (c *NotifierConfig) LogInputs() ([]LogInput, error) {
+	if len(c.LogFiles) == 0 {
+		return []LogInput{{TagID: c.TagID, Path: c.LogFile}}, nil
+	}
+	var inputs []LogInput
+	tagIDs := make(map[string]string)
+	for _, entry := range c.LogFiles {
+		entryInputs, err := parseLogFilesEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range entryInputs {
+			if path, exists := tagIDs[input.TagID]; exists {
+				return nil, fmt.Errorf("tag id '%s' is used for log files '%s' and '%s'", input.TagID, path, input.Path)
+			}
+			tagIDs[input.TagID] = input.Path
+			inputs = append(inputs, input)
+		}
+	}
+	stdinCount := 0
+	for _, input := range inputs {
+		if input.Path == LogFileStdin {
+			stdinCount++
+		}
+	}
+	if stdinCount > 1 {
+		return nil, fmt.Errorf("stdin '%s' can only be used for a single log file", LogFileStdin)
+	}
+	return inputs, nil
+}
+
+func parseLogFilesEntry(entry string) ([]LogInput, error) {
+	tagID, pattern, tagged := strings.Cut(entry, logFileTagSeparator)
+	if !tagged {
+		pattern = entry
+		tagID = ""
+	}
+	if tagged && tagID == "" {
+		return nil, fmt.Errorf("missing tag id for log files entry '%s'", entry)
+	}
+	if pattern == LogFileStdin {
+		if !tagged {
+			return nil, fmt.Errorf("stdin '%s' requires a tag id e.g. 'stack=%s'", LogFileStdin, LogFileStdin)
+		}
+		return []LogInput{{TagID: tagID, Path: pattern}}, nil
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log files pattern '%s': %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no log file found for '%s'", pattern)
+	}
+	if tagged && len(paths) > 1 {
+		return nil, fmt.Errorf("tag id '%s' matches %d log files for '%s'", tagID, len(paths), pattern)
+	}
+	var inputs []LogInput
+	for _, path := range paths {
+		input := LogInput{TagID: tagID, Path: path}
+		if !tagged {
+			base := filepath.Base(path)
+			input.TagID = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifierConfig_LogInputs(t *testing.T) {
+	testCases := []struct {
+		description string
+		config      NotifierConfig
+		expected    []LogInput
+		err         string
+	}{
+		{
+			description: "single log file",
+			config:      NotifierConfig{LogFile: "cdk.log", TagID: "stack"},
+			expected:    []LogInput{{TagID: "stack", Path: "cdk.log"}},
+		},
+		{
+			description: "tagged log files and stdin",
+			config:      NotifierConfig{LogFile: "ignored.log", LogFiles: []string{"dev=../data/cdk-small.log", "prod=-"}},
+			expected:    []LogInput{{TagID: "dev", Path: "../data/cdk-small.log"}, {TagID: "prod", Path: "-"}},
+		},
+		{
+			description: "glob uses file name as tag id",
+			config:      NotifierConfig{LogFiles: []string{"../data/cdk-diff*.log"}},
+			expected: []LogInput{
+				{TagID: "cdk-diff-number-diff-replace", Path: "../data/cdk-diff-number-diff-replace.log"},
+				{TagID: "cdk-diff-resources-changes", Path: "../data/cdk-diff-resources-changes.log"},
+				{TagID: "cdk-diff1", Path: "../data/cdk-diff1.log"},
+			},
+		},
+		{
+			description: "duplicate tag id",
+			config:      NotifierConfig{LogFiles: []string{"../data/cdk-small.log", "cdk-small=../data/cdk-diff1.log"}},
+			err:         "tag id 'cdk-small' is used for log files '../data/cdk-small.log' and '../data/cdk-diff1.log'",
+		},
+		{
+			description: "tag id for several files",
+			config:      NotifierConfig{LogFiles: []string{"dev=../data/cdk-diff*.log"}},
+			err:         "tag id 'dev' matches 3 log files for '../data/cdk-diff*.log'",
+		},
+		{
+			description: "missing file",
+			config:      NotifierConfig{LogFiles: []string{"../data/not-existing.log"}},
+			err:         "no log file found for '../data/not-existing.log'",
+		},
+		{
+			description: "missing tag id",
+			config:      NotifierConfig{LogFiles: []string{"=../data/cdk-small.log"}},
+			err:         "missing tag id for log files entry '=../data/cdk-small.log'",
+		},
+		{
+			description: "stdin without tag id",
+			config:      NotifierConfig{LogFiles: []string{"-"}},
+			err:         "stdin '-' requires a tag id e.g. 'stack=-'",
+		},
+		{
+			description: "stdin used twice",
+			config:      NotifierConfig{LogFiles: []string{"dev=-", "prod=-"}},
+			err:         "stdin '-' can only be used for a single log file",
+		},
+	}
+	for _, c := range testCases {
+		t.Run(c.description, func(t *testing.T) {
+			inputs, err := c.config.LogInputs()
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, inputs)
+		})
+	}
+}
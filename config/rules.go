@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// RuleActionWarn shows a warning in the comment
+	RuleActionWarn = "warn"
+	// RuleActionLabel adds a label to the pull request
+	RuleActionLabel = "label"
+	// RuleActionFail shows an error in the comment and fails after posting the comment
+	RuleActionFail = "fail"
+)
+
+// Rule is a user defined policy which is evaluated for every change of the parsed diff
+type Rule struct {
+	Name string `mapstructure:"name"`
+	// When is the expression to match a change e.g. type == "AWS::IAM::Role" && kind == "removal"
+	When   string `mapstructure:"when"`
+	Action string `mapstructure:"action"`
+	// Label is added to the pull request for action label. Defaults to the name of the rule
+	Label   string `mapstructure:"label"`
+	Message string `mapstructure:"message"`
+}
+
+// rulesFile is the content of the rules file
+type rulesFile struct {
+	Rules []Rule `mapstructure:"rules"`
+}
+
+// loadRules reads the rules from RulesFile. The format is detected from the file extension e.g. yaml or json
+func (c *NotifierConfig) loadRules() error {
+	if c.RulesFile == "" {
+		return nil
+	}
+	// use a separate viper instance to not mix rules with the notifier config
+	v := viper.New()
+	v.SetConfigFile(c.RulesFile)
+	err := v.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("unable to read rules file '%s': %w", c.RulesFile, err)
+	}
+	var file rulesFile
+	err = v.Unmarshal(&file)
+	if err != nil {
+		return fmt.Errorf("unable to parse rules file '%s': %w", c.RulesFile, err)
+	}
+	names := make(map[string]bool)
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("missing name for rule %d in rules file '%s'", i+1, c.RulesFile)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule '%s' in rules file '%s'", rule.Name, c.RulesFile)
+		}
+		names[rule.Name] = true
+		if rule.When == "" {
+			return fmt.Errorf("missing when for rule '%s'", rule.Name)
+		}
+		switch rule.Action {
+		case RuleActionWarn, RuleActionFail:
+		case RuleActionLabel:
+			if rule.Label == "" {
+				file.Rules[i].Label = rule.Name
+			}
+		default:
+			return fmt.Errorf("unsupported action '%s' for rule '%s'. Use one of [%s|%s|%s]", rule.Action, rule.Name, RuleActionWarn, RuleActionLabel, RuleActionFail)
+		}
+	}
+	c.Rules = file.Rules
+	return nil
+}
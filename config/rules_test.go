@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRulesFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(file, []byte(content), 0644)
+	assert.NoError(t, err)
+	return file
+}
+
+func TestNotifierConfig_LoadRules(t *testing.T) {
+	file := writeRulesFile(t, "rules.yaml", `
+rules:
+  - name: no-table-replacement
+    when: type == "AWS::DynamoDB::Table" && replacement
+    action: fail
+    message: DynamoDB tables must not be replaced
+  - name: iam
+    when: section == "IAM Statement Changes"
+    action: label
+`)
+	c := NotifierConfig{RulesFile: file}
+	err := c.loadRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Name: "no-table-replacement", When: `type == "AWS::DynamoDB::Table" && replacement`, Action: RuleActionFail, Message: "DynamoDB tables must not be replaced"},
+		{Name: "iam", When: `section == "IAM Statement Changes"`, Action: RuleActionLabel, Label: "iam"},
+	}, c.Rules)
+
+	c = NotifierConfig{}
+	assert.NoError(t, c.loadRules())
+	assert.Nil(t, c.Rules)
+}
+
+func TestNotifierConfig_LoadRulesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "MissingName", content: `{"rules": [{"when": "replacement", "action": "fail"}]}`, expected: "missing name for rule 1 in rules file"},
+		{name: "MissingWhen", content: `{"rules": [{"name": "a", "action": "fail"}]}`, expected: "missing when for rule 'a'"},
+		{name: "UnknownAction", content: `{"rules": [{"name": "a", "when": "replacement", "action": "block"}]}`, expected: "unsupported action 'block' for rule 'a'. Use one of [warn|label|fail]"},
+		{name: "Duplicate", content: `{"rules": [{"name": "a", "when": "replacement", "action": "warn"}, {"name": "a", "when": "replacement", "action": "warn"}]}`, expected: "duplicate rule 'a' in rules file"},
+		{name: "InvalidJSON", content: `{"rules": [`, expected: "unable to read rules file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NotifierConfig{RulesFile: writeRulesFile(t, "rules.json", tt.content)}
+			err := c.loadRules()
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
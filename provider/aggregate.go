@@ -0,0 +1,382 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// aggregateMaxAttempts is the maximum number of read-modify-write cycles of the aggregate comment
+	aggregateMaxAttempts = 5
+	sectionMarkerPrefix  = "<!-- cdk-notifier-section "
+	sectionMarkerEnd     = "<!-- cdk-notifier-section-end -->"
+	// truncatedSectionSuffix closes the code block and details of a truncated section
+	truncatedSectionSuffix = "\n```\n</details>\n<br>\n\n**Warning**: Truncated section as the aggregate comment is greater than max comment size."
+)
+
+var sectionRegex = regexp.MustCompile(`(?s)` + regexp.QuoteMeta(sectionMarkerPrefix) + `(\{.*?\})` + regexp.QuoteMeta(commentMarkerSuffix) + `\n(.*?)\n` + regexp.QuoteMeta(sectionMarkerEnd))
+
+// aggregateRetryDelay is the wait before reading the aggregate comment again after a conflict.
+// The jitter makes parallel jobs retry at different times. It is replaced in tests.
+var aggregateRetryDelay = func(attempt int) time.Duration {
+	return time.Duration(attempt)*500*time.Millisecond + time.Duration(rand.Int63n(int64(500*time.Millisecond)))
+}
+
+// commentSection is the part of the aggregate comment owned by a single tag id
+type commentSection struct {
+	TagID   string
+	Content string
+}
+
+type sectionMarker struct {
+	TagID string `json:"tagId"`
+}
+
+// PostAggregateComment writes the groups as sections into a single comment shared with other jobs.
+// The comment is identified by the marker with tag id config.AggregateComment. Each group replaces only the section of its tag id.
+// The comment is read again before and after writing it. On a conflict with a parallel job the update is retried.
+// Providers with optimistic locking like Bitbucket Server reject writes of a changed comment which is retried as well.
+// GitHub, GitLab and Gitea have no conditional writes. Two jobs reading the comment before either writes it overwrite each other.
+// The read after writing detects most of these lost updates and writes the dropped sections of other jobs again.
+// A job overwriting the comment after that read can still drop a section until the job of the section runs again.
+// Sections without changes are removed depending on DeleteComment config.AppConfig. The comment is deleted without sections.
+func PostAggregateComment(ns NotifierService, config config.NotifierConfig, groups []CommentGroup, marker CommentMarker) (CommentOperation, error) {
+	config, err := resolveCommentAuthor(ns, config)
+	if err != nil {
+		return API_COMMENT_NOTHING, err
+	}
+	marker.TagID = config.AggregateComment
+	var restore []commentSection
+	for attempt := 1; attempt <= aggregateMaxAttempts; attempt++ {
+		operation, dropped, conflict, err := syncAggregateComment(ns, config, groups, marker, restore)
+		restore = append(restore, dropped...)
+		if err != nil || !conflict {
+			return operation, err
+		}
+		if attempt < aggregateMaxAttempts {
+			wait := aggregateRetryDelay(attempt)
+			logrus.Infof("Aggregate comment %s was changed by another job. Retrying in %s", config.AggregateComment, wait)
+			time.Sleep(wait)
+		}
+	}
+	return API_COMMENT_NOTHING, fmt.Errorf("unable to update aggregate comment %s after %d attempts because of concurrent updates", config.AggregateComment, aggregateMaxAttempts)
+}
+
+// syncAggregateComment is a single read-modify-write of the aggregate comment. It reports a conflict if another job changed the comment meanwhile.
+// The sections of other jobs dropped by a parallel write are returned to be restored by the next attempt.
+// The conflict check is not atomic without optimistic locking of the provider, see PostAggregateComment.
+func syncAggregateComment(ns NotifierService, config config.NotifierConfig, groups []CommentGroup, marker CommentMarker, restore []commentSection) (CommentOperation, []commentSection, bool, error) {
+	comment, duplicates, err := findAggregateComments(ns, config)
+	if err != nil {
+		return API_COMMENT_NOTHING, nil, false, err
+	}
+	var sections []commentSection
+	if comment != nil {
+		sections = parseCommentSections(comment.Body)
+	}
+	// parallel jobs may have created the comment at the same time. The comment with the lowest id is kept.
+	for _, duplicate := range duplicates {
+		for _, section := range parseCommentSections(duplicate.Body) {
+			if findSection(sections, section.TagID) == nil {
+				sections = append(sections, section)
+			}
+		}
+	}
+	for _, section := range restore {
+		if findSection(sections, section.TagID) == nil && !hasGroup(groups, section.TagID) {
+			sections = append(sections, section)
+		}
+	}
+	sections, err = aggregateSections(sections, groups, config)
+	if err != nil {
+		return API_COMMENT_NOTHING, nil, false, err
+	}
+
+	operation := API_COMMENT_NOTHING
+	if len(sections) > 0 {
+		ns.SetCommentContent(marker.Embed(renderCommentSections(sections)))
+		if comment != nil && len(duplicates) == 0 && sameCommentBody(comment.Body, ns.GetCommentContent()) && sameCommentCommit(comment.Body, ns.GetCommentContent()) {
+			logrus.Infof("Aggregate comment with id %d and tag id %s is unchanged. Skip updating diff.", comment.Id, config.AggregateComment)
+			return API_COMMENT_UNCHANGED, nil, false, nil
+		}
+	} else if comment == nil {
+		logrus.Infof("There is no diff detected for aggregate comment %s. Skip posting diff.", config.AggregateComment)
+		return API_COMMENT_NOTHING, nil, false, nil
+	}
+
+	changed, err := aggregateCommentChanged(ns, config, comment, duplicates)
+	if err != nil || changed {
+		return API_COMMENT_NOTHING, nil, changed, err
+	}
+	switch {
+	case len(sections) == 0:
+		err = ns.DeleteComment(comment.Id)
+		if errors.Is(err, errCommentConflict) {
+			return API_COMMENT_NOTHING, nil, true, nil
+		}
+		if err != nil {
+			return API_COMMENT_NOTHING, nil, false, err
+		}
+		logrus.Infof("Deleted aggregate comment with id %d and tag id %s because no changes detected", comment.Id, config.AggregateComment)
+		operation = API_COMMENT_DELETED
+	case comment == nil:
+		created, err := ns.CreateComment()
+		if err != nil {
+			return API_COMMENT_NOTHING, nil, false, err
+		}
+		logrus.Infof("Created aggregate comment with id %d and tag id %s %v", created.Id, config.AggregateComment, created.Link)
+		operation = API_COMMENT_CREATED
+	default:
+		updated, err := ns.UpdateComment(comment.Id)
+		if errors.Is(err, errCommentConflict) {
+			return API_COMMENT_NOTHING, nil, true, nil
+		}
+		if err != nil {
+			return API_COMMENT_NOTHING, nil, false, err
+		}
+		logrus.Infof("Updated aggregate comment with id %d and tag id %s %v", updated.Id, config.AggregateComment, updated.Link)
+		operation = API_COMMENT_UPDATED
+	}
+	for _, duplicate := range duplicates {
+		err = ns.DeleteComment(duplicate.Id)
+		if err != nil {
+			return operation, nil, false, err
+		}
+		logrus.Infof("Deleted duplicate aggregate comment with id %d and tag id %s", duplicate.Id, config.AggregateComment)
+	}
+
+	// another job could have written the comment between reading and writing it
+	dropped, written, err := aggregateSectionsWritten(ns, config, groups, sections)
+	if err != nil {
+		return operation, nil, false, err
+	}
+	return operation, dropped, !written, nil
+}
+
+// findAggregateComments returns the aggregate comment with the lowest id and all further aggregate comments
+func findAggregateComments(ns NotifierService, config config.NotifierConfig) (*Comment, []Comment, error) {
+	comments, err := ns.ListComments()
+	if err != nil {
+		return nil, nil, err
+	}
+	var aggregate []Comment
+	for _, comment := range ownComments(comments, config) {
+		if marker := parseCommentMarker(comment.Body); !comment.Hidden && marker != nil && marker.TagID == config.AggregateComment {
+			aggregate = append(aggregate, comment)
+		}
+	}
+	if len(aggregate) == 0 {
+		return nil, nil, nil
+	}
+	sort.Slice(aggregate, func(i, j int) bool {
+		return aggregate[i].Id < aggregate[j].Id
+	})
+	return &aggregate[0], aggregate[1:], nil
+}
+
+// aggregateCommentChanged reads the aggregate comments again and reports if they differ from the comments read before
+func aggregateCommentChanged(ns NotifierService, config config.NotifierConfig, comment *Comment, duplicates []Comment) (bool, error) {
+	current, currentDuplicates, err := findAggregateComments(ns, config)
+	if err != nil {
+		return false, err
+	}
+	if (comment == nil) != (current == nil) || len(duplicates) != len(currentDuplicates) {
+		return true, nil
+	}
+	if comment != nil && (comment.Id != current.Id || comment.Body != current.Body) {
+		return true, nil
+	}
+	for i := range duplicates {
+		if duplicates[i].Id != currentDuplicates[i].Id || duplicates[i].Body != currentDuplicates[i].Body {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// aggregateSectionsWritten reads the aggregate comment again and checks that it contains the sections of the groups.
+// It returns the sections of other jobs written along which were dropped by a job writing an older version of the comment.
+func aggregateSectionsWritten(ns NotifierService, config config.NotifierConfig, groups []CommentGroup, written []commentSection) ([]commentSection, bool, error) {
+	comment, duplicates, err := findAggregateComments(ns, config)
+	if err != nil {
+		return nil, false, err
+	}
+	var sections []commentSection
+	if comment != nil {
+		sections = parseCommentSections(comment.Body)
+	}
+	var dropped []commentSection
+	for _, section := range written {
+		if !hasGroup(groups, section.TagID) && findSection(sections, section.TagID) == nil {
+			logrus.Infof("Section %s of aggregate comment %s was dropped by another job", section.TagID, config.AggregateComment)
+			dropped = append(dropped, section)
+		}
+	}
+	if len(dropped) > 0 {
+		return dropped, false, nil
+	}
+	expected := applySections(append([]commentSection{}, sections...), groups, config)
+	if len(duplicates) > 0 || len(expected) != len(sections) {
+		return nil, false, nil
+	}
+	for i := range sections {
+		if sections[i].TagID != expected[i].TagID || !sameSectionContent(sections[i].Content, expected[i].Content) {
+			return nil, false, nil
+		}
+	}
+	return nil, true, nil
+}
+
+// hasGroup reports whether one of the groups has the tag id
+func hasGroup(groups []CommentGroup, tagID string) bool {
+	for _, group := range groups {
+		if group.TagID == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSectionContent reports whether the section shows the expected content. Truncated sections match the start of the content.
+func sameSectionContent(section, expected string) bool {
+	if sameCommentBody(section, expected) {
+		return true
+	}
+	section, expected = normalizeCommentBody(section), normalizeCommentBody(expected)
+	truncated, ok := strings.CutSuffix(section, normalizeCommentBody(truncatedSectionSuffix))
+	return ok && strings.HasPrefix(expected, strings.TrimRight(truncated, " \t\n"))
+}
+
+// applySections replaces the sections of the groups. Sections without changes are removed if DeleteComment is set.
+// The sections are sorted by tag id.
+func applySections(sections []commentSection, groups []CommentGroup, config config.NotifierConfig) []commentSection {
+	for _, group := range groups {
+		var contents []string
+		hasChanges := false
+		for _, comment := range group.Comments {
+			contents = append(contents, comment.Content)
+			contents = append(contents, comment.Continuations...)
+			hasChanges = hasChanges || diffHasChanges(comment.Content)
+		}
+		hasChanges = hasChanges && !config.ForceDeleteComment && !group.ForceDelete
+		existing := findSection(sections, group.TagID)
+		switch {
+		case !hasChanges && (config.DeleteComment || existing == nil):
+			sections = removeSection(sections, group.TagID)
+		case existing != nil:
+			existing.Content = strings.Join(contents, "\n\n")
+		default:
+			sections = append(sections, commentSection{TagID: group.TagID, Content: strings.Join(contents, "\n\n")})
+		}
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].TagID < sections[j].TagID
+	})
+	return sections
+}
+
+// aggregateSections applies the groups to the sections and shortens them to the max comment length of the VCS
+func aggregateSections(sections []commentSection, groups []CommentGroup, config config.NotifierConfig) ([]commentSection, error) {
+	sections = applySections(sections, groups, config)
+	maxLength := MaxCommentLength(config.Vcs, config.GithubMaxCommentLength)
+	if maxLength == 0 {
+		return sections, nil
+	}
+	// leave space for the marker embedded before posting
+	sections, err := fitCommentSections(sections, maxLength-CommentMarkerMaxLength)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update aggregate comment %s: %w", config.AggregateComment, err)
+	}
+	return sections, nil
+}
+
+// fitCommentSections truncates the longest sections until the rendered sections are not longer than maxLength chars.
+// All truncated sections are shortened to the same length, so the diff of a single job can not push out the sections of other jobs.
+func fitCommentSections(sections []commentSection, maxLength int) ([]commentSection, error) {
+	overflow := utf8.RuneCountInString(renderCommentSections(sections)) - maxLength
+	if overflow <= 0 {
+		return sections, nil
+	}
+	lengths := make([]int, len(sections))
+	available := -overflow
+	for i, section := range sections {
+		lengths[i] = utf8.RuneCountInString(section.Content)
+		available += lengths[i]
+	}
+	// the largest section length at which all sections fit
+	limit := sort.Search(maxLength+1, func(limit int) bool {
+		total := 0
+		for _, length := range lengths {
+			total += min(length, limit)
+		}
+		return total > available
+	}) - 1
+	suffixLength := utf8.RuneCountInString(truncatedSectionSuffix)
+	if limit <= suffixLength {
+		return nil, fmt.Errorf("%d sections do not fit into the max comment length of %d chars", len(sections), maxLength)
+	}
+	result := make([]commentSection, len(sections))
+	for i, section := range sections {
+		if lengths[i] > limit {
+			logrus.Warnf("Truncated section %s of the aggregate comment to %d chars to fit into the max comment length", section.TagID, limit)
+			section.Content = string([]rune(section.Content)[:limit-suffixLength]) + truncatedSectionSuffix
+		}
+		result[i] = section
+	}
+	return result, nil
+}
+
+func findSection(sections []commentSection, tagID string) *commentSection {
+	for i := range sections {
+		if sections[i].TagID == tagID {
+			return &sections[i]
+		}
+	}
+	return nil
+}
+
+func removeSection(sections []commentSection, tagID string) []commentSection {
+	var result []commentSection
+	for _, section := range sections {
+		if section.TagID != tagID {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// parseCommentSections returns the sections of the aggregate comment
+func parseCommentSections(body string) []commentSection {
+	var sections []commentSection
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	for _, match := range sectionRegex.FindAllStringSubmatch(body, -1) {
+		marker := sectionMarker{}
+		if err := json.Unmarshal([]byte(match[1]), &marker); err != nil || marker.TagID == "" {
+			logrus.Debugf("Ignoring section with invalid marker %s", match[1])
+			continue
+		}
+		sections = append(sections, commentSection{TagID: marker.TagID, Content: match[2]})
+	}
+	return sections
+}
+
+// renderCommentSections joins the sections enclosed by their markers
+func renderCommentSections(sections []commentSection) string {
+	var parts []string
+	for _, section := range sections {
+		// json.Marshal escapes < and > so the tag id can not end the HTML comment early
+		data, _ := json.Marshal(sectionMarker{TagID: section.TagID})
+		parts = append(parts, sectionMarkerPrefix+string(data)+commentMarkerSuffix+"\n"+section.Content+"\n"+sectionMarkerEnd)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,295 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	aggregateRetryDelay = func(attempt int) time.Duration {
+		return 0
+	}
+}
+
+func aggregateGroup(tagID string, diff string) []CommentGroup {
+	return []CommentGroup{{TagID: tagID, Comments: []ManagedComment{{TagID: tagID, Content: fmt.Sprintf("## cdk diff for %s\n%s", tagID, diff)}}}}
+}
+
+// aggregateGroupSections returns the sections written for aggregateGroup
+func aggregateGroupSections(tagID string, diff string) []commentSection {
+	return applySections(nil, aggregateGroup(tagID, diff), config.NotifierConfig{})
+}
+
+func TestPostAggregateComment(t *testing.T) {
+	store := &fakeCommentStore{comments: []Comment{{Id: 1, Body: "review comment"}}, nextId: 1}
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true}
+	marker := CommentMarker{Version: "1.2.3"}
+
+	for _, tagID := range []string{"prod", "dev", "staging"} {
+		operation, err := PostAggregateComment(store, cfg, aggregateGroup(tagID, "Resources\n[+] bucket"), marker)
+		assert.NoError(t, err)
+		assert.Contains(t, []CommentOperation{API_COMMENT_CREATED, API_COMMENT_UPDATED}, operation)
+	}
+	assert.Len(t, store.comments, 2)
+	aggregate := store.comments[1]
+	assert.Equal(t, "cdk", parseCommentMarker(aggregate.Body).TagID)
+	assert.Equal(t, []commentSection{
+		{TagID: "dev", Content: "## cdk diff for dev\nResources\n[+] bucket"},
+		{TagID: "prod", Content: "## cdk diff for prod\nResources\n[+] bucket"},
+		{TagID: "staging", Content: "## cdk diff for staging\nResources\n[+] bucket"},
+	}, parseCommentSections(aggregate.Body), "expect sections sorted by tag id")
+
+	operation, err := PostAggregateComment(store, cfg, aggregateGroup("prod", "Resources\n[+] bucket"), marker)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operation)
+
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("prod", "Resources\n[+] bucket"), CommentMarker{Version: "1.2.3", CommitSha: "def456"})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation, "expect the commit of the marker to be updated")
+	assert.Equal(t, "def456", parseCommentMarker(store.comments[1].Body).CommitSha)
+
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("prod", "Resources\n[~] bucket"), marker)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, "## cdk diff for prod\nResources\n[~] bucket", findSection(parseCommentSections(store.comments[1].Body), "prod").Content)
+
+	for _, tagID := range []string{"dev", "prod"} {
+		operation, err = PostAggregateComment(store, cfg, aggregateGroup(tagID, "There were no differences"), marker)
+		assert.NoError(t, err)
+		assert.Equal(t, API_COMMENT_UPDATED, operation)
+	}
+	assert.Equal(t, []commentSection{{TagID: "staging", Content: "## cdk diff for staging\nResources\n[+] bucket"}}, parseCommentSections(store.comments[1].Body))
+
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("staging", "There were no differences"), marker)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Equal(t, []string{"review comment"}, store.bodies())
+
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("dev", "There were no differences"), marker)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_NOTHING, operation)
+	assert.Len(t, store.comments, 1)
+}
+
+func TestPostAggregateCommentWithoutDelete(t *testing.T) {
+	store := &fakeCommentStore{}
+	cfg := config.NotifierConfig{AggregateComment: "cdk"}
+	_, err := PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+
+	operation, err := PostAggregateComment(store, cfg, aggregateGroup("dev", "There were no differences"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, []commentSection{{TagID: "dev", Content: "## cdk diff for dev\nThere were no differences"}}, parseCommentSections(store.comments[0].Body))
+
+	// a section is not added for a diff without changes
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("prod", "There were no differences"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operation)
+}
+
+// concurrentCommentStore simulates a parallel job writing the aggregate comment
+type concurrentCommentStore struct {
+	*fakeCommentStore
+	listCalls int
+	// writeOn is called before the list call with the same number
+	writeOn map[int]func()
+}
+
+func (c *concurrentCommentStore) ListComments() ([]Comment, error) {
+	c.listCalls++
+	if write, ok := c.writeOn[c.listCalls]; ok {
+		write()
+	}
+	return c.fakeCommentStore.ListComments()
+}
+
+// writeSection writes the section like another job which read the comment before
+func (c *concurrentCommentStore) writeSection(base string, tagID string) func() {
+	return func() {
+		sections := applySections(parseCommentSections(base), aggregateGroup(tagID, "Resources\n[+] queue"), config.NotifierConfig{})
+		body := CommentMarker{TagID: "cdk"}.Embed(renderCommentSections(sections))
+		if len(c.comments) == 0 {
+			c.nextId++
+			c.comments = append(c.comments, Comment{Id: c.nextId, Body: body})
+			return
+		}
+		c.comments[0].Body = body
+	}
+}
+
+func TestPostAggregateCommentConflict(t *testing.T) {
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true}
+
+	// the other job writes between reading and writing the comment
+	store := &concurrentCommentStore{fakeCommentStore: &fakeCommentStore{}}
+	store.writeOn = map[int]func(){2: store.writeSection("", "prod")}
+	operation, err := PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, store.comments, 1)
+	assert.Equal(t, []string{"dev", "prod"}, sectionTagIDs(store.comments[0].Body))
+	assert.Equal(t, 5, store.listCalls, "expect a second read-modify-write")
+
+	// the other job overwrites the comment with the version it read before
+	store = &concurrentCommentStore{fakeCommentStore: &fakeCommentStore{}}
+	store.writeOn = map[int]func(){3: store.writeSection("", "prod")}
+	_, err = PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Len(t, store.comments, 1)
+	assert.Equal(t, []string{"dev", "prod"}, sectionTagIDs(store.comments[0].Body))
+
+	// the other job overwrites the comment after the write with a version without the section of a third job
+	store = &concurrentCommentStore{fakeCommentStore: &fakeCommentStore{}}
+	_, err = PostAggregateComment(store, cfg, aggregateGroup("prod", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	dev := renderCommentSections(aggregateGroupSections("dev", "Resources\n[+] bucket"))
+	store.listCalls = 0
+	store.writeOn = map[int]func(){3: store.writeSection(dev, "staging")}
+	operation, err = PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, []string{"dev", "prod", "staging"}, sectionTagIDs(store.comments[0].Body), "expect the dropped section to be restored")
+	assert.Equal(t, 6, store.listCalls, "expect a second read-modify-write")
+
+	// the comment keeps changing
+	store = &concurrentCommentStore{fakeCommentStore: &fakeCommentStore{}, writeOn: map[int]func(){}}
+	for i := 2; i <= 2*aggregateMaxAttempts; i += 2 {
+		store.writeOn[i] = store.writeSection("", fmt.Sprintf("job-%d", i))
+	}
+	_, err = PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.EqualError(t, err, "unable to update aggregate comment cdk after 5 attempts because of concurrent updates")
+}
+
+// lockingCommentStore rejects the first writes like providers with optimistic locking
+type lockingCommentStore struct {
+	*fakeCommentStore
+	conflicts int
+}
+
+func (l *lockingCommentStore) UpdateComment(id int64) (*Comment, error) {
+	if l.conflicts > 0 {
+		l.conflicts--
+		return nil, errCommentConflict
+	}
+	return l.fakeCommentStore.UpdateComment(id)
+}
+
+func TestPostAggregateCommentLockingConflict(t *testing.T) {
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true}
+	store := &lockingCommentStore{fakeCommentStore: &fakeCommentStore{}, conflicts: 1}
+	_, err := PostAggregateComment(store, cfg, aggregateGroup("prod", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	operation, err := PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, []string{"dev", "prod"}, sectionTagIDs(store.comments[0].Body))
+
+	store.conflicts = aggregateMaxAttempts
+	_, err = PostAggregateComment(store, cfg, aggregateGroup("staging", "Resources\n[+] bucket"), CommentMarker{})
+	assert.EqualError(t, err, "unable to update aggregate comment cdk after 5 attempts because of concurrent updates")
+}
+
+func TestPostAggregateCommentMergesDuplicates(t *testing.T) {
+	store := &fakeCommentStore{
+		comments: []Comment{
+			{Id: 2, Body: CommentMarker{TagID: "cdk"}.Embed(renderCommentSections([]commentSection{{TagID: "prod", Content: "## cdk diff for prod\nResources"}}))},
+			{Id: 1, Body: CommentMarker{TagID: "cdk"}.Embed(renderCommentSections([]commentSection{{TagID: "staging", Content: "## cdk diff for staging\nResources"}}))},
+			{Id: 3, Body: CommentMarker{TagID: "other"}.Embed("## cdk diff for other\nResources")},
+		},
+		nextId: 3,
+	}
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true}
+	operation, err := PostAggregateComment(store, cfg, aggregateGroup("dev", "Resources\n[+] bucket"), CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, store.comments, 2)
+	assert.Equal(t, int64(1), store.comments[0].Id, "expect the comment with the lowest id to be kept")
+	assert.Equal(t, []string{"dev", "prod", "staging"}, sectionTagIDs(store.comments[0].Body))
+}
+
+func TestPostAggregateCommentMaxLength(t *testing.T) {
+	store := &fakeCommentStore{nextId: 0}
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true, Vcs: config.VcsCodeCommit}
+	small := "Resources\n[+] bucket"
+	large := "Resources\n" + strings.Repeat("[+] AWS::S3::Bucket bucket\n", 300)
+
+	for _, group := range []CommentGroup{aggregateGroup("dev", small)[0], aggregateGroup("prod", large)[0], aggregateGroup("staging", large)[0]} {
+		_, err := PostAggregateComment(store, cfg, []CommentGroup{group}, CommentMarker{})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len([]rune(store.comments[0].Body)), CodeCommitMaxCommentLength)
+	}
+	sections := parseCommentSections(store.comments[0].Body)
+	assert.Equal(t, []string{"dev", "prod", "staging"}, sectionTagIDs(store.comments[0].Body))
+	assert.Equal(t, "## cdk diff for dev\n"+small, sections[0].Content, "expect short sections to be kept")
+	for _, section := range sections[1:] {
+		assert.True(t, strings.HasSuffix(section.Content, truncatedSectionSuffix), section.TagID)
+		assert.True(t, strings.HasPrefix("## cdk diff for "+section.TagID+"\n"+large, strings.TrimSuffix(section.Content, truncatedSectionSuffix)))
+	}
+	assert.InDelta(t, len(sections[1].Content), len(sections[2].Content), 1, "expect long sections to be shortened evenly")
+
+	// the sections of all jobs do not fit
+	for i := 0; i < 50; i++ {
+		cfg.AggregateComment = "many"
+		_, err := PostAggregateComment(store, cfg, aggregateGroup(fmt.Sprintf("stack-%d", i), large), CommentMarker{})
+		if err != nil {
+			assert.EqualError(t, err, fmt.Sprintf("unable to update aggregate comment many: %d sections do not fit into the max comment length of %d chars", i+1, CodeCommitMaxCommentLength-CommentMarkerMaxLength))
+			return
+		}
+	}
+	t.Fatal("expect the aggregate comment to be rejected")
+}
+
+func TestFitCommentSections(t *testing.T) {
+	sections := []commentSection{
+		{TagID: "dev", Content: strings.Repeat("a", 100)},
+		{TagID: "prod", Content: strings.Repeat("b", 1000)},
+		{TagID: "staging", Content: strings.Repeat("c", 2000)},
+	}
+	fitted, err := fitCommentSections(sections, 10000)
+	assert.NoError(t, err)
+	assert.Equal(t, sections, fitted, "expect sections below the limit to be unchanged")
+
+	maxLength := len(renderCommentSections(sections)) - 1500
+	fitted, err = fitCommentSections(sections, maxLength)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(renderCommentSections(fitted)), maxLength)
+	assert.Equal(t, sections[0], fitted[0])
+	assert.Equal(t, len(fitted[1].Content), len(fitted[2].Content))
+	assert.Equal(t, strings.Repeat("c", 2000), sections[2].Content, "expect the sections not to be modified")
+
+	_, err = fitCommentSections(sections, 300)
+	assert.EqualError(t, err, "3 sections do not fit into the max comment length of 300 chars")
+}
+
+func TestSameSectionContent(t *testing.T) {
+	content := "## cdk diff for dev\nResources\n[+] bucket\n[+] queue"
+	assert.True(t, sameSectionContent(content, content))
+	assert.True(t, sameSectionContent("## cdk diff for dev\nResources\n[+] bu"+truncatedSectionSuffix, content))
+	assert.False(t, sameSectionContent("## cdk diff for dev\nResources\n[~] bu"+truncatedSectionSuffix, content))
+	assert.False(t, sameSectionContent("## cdk diff for dev\nResources", content))
+}
+
+func TestParseCommentSections(t *testing.T) {
+	sections := []commentSection{
+		{TagID: "dev-->", Content: "## cdk diff for dev\n\nResources"},
+		{TagID: "prod", Content: "## cdk diff for prod"},
+	}
+	body := renderCommentSections(sections)
+	assert.Equal(t, sections, parseCommentSections(body))
+	assert.Equal(t, sections, parseCommentSections(strings.ReplaceAll(body, "\n", "\r\n")))
+	assert.Nil(t, parseCommentSections("## cdk diff for dev\nResources"))
+}
+
+func sectionTagIDs(body string) []string {
+	var tagIDs []string
+	for _, section := range parseCommentSections(body) {
+		tagIDs = append(tagIDs, section.TagID)
+	}
+	return tagIDs
+}
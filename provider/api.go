@@ -2,8 +2,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/karlderkaefer/cdk-notifier/config"
 	"github.com/sirupsen/logrus"
@@ -14,21 +16,52 @@ const (
 	API_COMMENT_UPDATED
 	API_COMMENT_DELETED
 	API_COMMENT_NOTHING
+	API_COMMENT_HIDDEN
+	API_COMMENT_UNCHANGED
 	userAgent = "cdk-notifier"
 	// HeaderPrefix default prefix for comment message
 	HeaderPrefix = "## cdk diff for"
+	// StackTagSeparator separates the tag id from the stack name when comments are split per stack
+	StackTagSeparator = "::"
+	// PartTagSeparator separates the tag id from the part number of continuation comments
+	PartTagSeparator = "#"
 )
 
 type CommentOperation int
 
 func (d CommentOperation) String() string {
-	return [...]string{"CREATED", "UPDATED", "DELETED", "NOTHING"}[d]
+	return [...]string{"CREATED", "UPDATED", "DELETED", "NOTHING", "HIDDEN", "UNCHANGED"}[d]
 }
 
 type Comment struct {
 	Id   int64
 	Body string
 	Link string
+	// Ref identifies the comment for provider specific APIs e.g. the GraphQL node id on GitHub or the discussion id on GitLab
+	Ref string
+	// Hidden is set for comments that were hidden as outdated. They are not updated anymore.
+	Hidden bool
+	// Author identifies the user who created the comment e.g. the login on GitHub
+	Author string
+}
+
+// ManagedComment is the desired content of a single comment identified by its tag id
+type ManagedComment struct {
+	TagID   string
+	Content string
+	// Continuations are the following parts when the diff is split over several comments.
+	// They are posted with the tag id of PartTagID and created, updated or deleted together with Content.
+	Continuations []string
+	// Stacks with differences shown in the comment. They are recorded in the marker of the comment.
+	Stacks []string
+}
+
+// CommentGroup contains all comments managed for a single tag id
+type CommentGroup struct {
+	TagID    string
+	Comments []ManagedComment
+	// ForceDelete deletes all comments of the group e.g. if only hash changes were detected
+	ForceDelete bool
 }
 
 // NotifierService interface for public methods actions required for cdk-notifier
@@ -39,11 +72,112 @@ type NotifierService interface {
 	SetCommentContent(content string)
 	GetCommentContent() string
 	PostComment() (CommentOperation, error)
+	PostComments(groups []CommentGroup) (map[string]CommentOperation, error)
 	ListComments() ([]Comment, error)
 }
 
+// Labeler is implemented by providers which support labels on pull requests
+type Labeler interface {
+	// AddLabels adds the labels to the pull request. Existing labels are kept.
+	AddLabels(labels []string) error
+}
+
+// CommentMinimizer is implemented by providers which can hide outdated comments instead of deleting them
+type CommentMinimizer interface {
+	// MinimizeComment hides the comment as outdated. It stays in the history of the pull request.
+	MinimizeComment(comment Comment) error
+}
+
+// AuthorResolver is implemented by providers which can look up the user authenticated by the token
+type AuthorResolver interface {
+	// AuthenticatedUser returns the user of the token in the same form as Comment.Author
+	AuthenticatedUser() (string, error)
+}
+
+// CommentSearcher is implemented by providers which list the comments page by page newest first.
+// Searching stops at the first matching comment without listing the remaining pages.
+type CommentSearcher interface {
+	SearchComment(match func(comment Comment) bool) (*Comment, error)
+}
+
+// commentWalker visits the comments of the pull request newest first until visit returns false
+type commentWalker func(visit func(comment Comment) bool) error
+
+// listComments collects all comments visited by walk
+func listComments(walk commentWalker) ([]Comment, error) {
+	var result []Comment
+	err := walk(func(comment Comment) bool {
+		result = append(result, comment)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// searchComment returns the first comment visited by walk that matches or nil
+func searchComment(walk commentWalker, match func(comment Comment) bool) (*Comment, error) {
+	var found *Comment
+	err := walk(func(comment Comment) bool {
+		if match(comment) {
+			found = &comment
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
 func getHeaderTagID(c config.NotifierConfig) string {
-	return fmt.Sprintf("%s %s", HeaderPrefix, c.TagID)
+	return headerTag(c.TagID)
+}
+
+func headerTag(tagID string) string {
+	return fmt.Sprintf("%s %s", HeaderPrefix, tagID)
+}
+
+// StackTagID returns the tag id of the comment for a single stack.
+// Whitespace in the stack name is replaced, so the tag id stays a single token in the header.
+func StackTagID(tagID string, stackName string) string {
+	if stackName == "" {
+		return tagID
+	}
+	return tagID + StackTagSeparator + strings.Join(strings.Fields(stackName), "_")
+}
+
+// PartTagID returns the tag id of the continuation comment with the given part number starting at 1.
+// The first part keeps the tag id, so a diff split into parts still matches an existing single comment.
+func PartTagID(tagID string, part int) string {
+	if part <= 1 {
+		return tagID
+	}
+	return fmt.Sprintf("%s%s%d", tagID, PartTagSeparator, part)
+}
+
+// parseHeaderTagIDs returns all tag ids found in headers of the comment body
+func parseHeaderTagIDs(body string) []string {
+	regex := regexp.MustCompile(regexp.QuoteMeta(HeaderPrefix) + ` (\S+)`)
+	var tagIDs []string
+	for _, match := range regex.FindAllStringSubmatch(body, -1) {
+		tagIDs = append(tagIDs, match[1])
+	}
+	return tagIDs
+}
+
+// belongsToTag returns true if the tag id is the given tag or one of its per stack or continuation tags
+func belongsToTag(tagID string, baseTagID string) bool {
+	pattern := "^" + regexp.QuoteMeta(baseTagID) + "(" + StackTagSeparator + `[^\s` + PartTagSeparator + "]+)?(" + PartTagSeparator + `\d+)?$`
+	return regexp.MustCompile(pattern).MatchString(tagID)
+}
+
+// trimPartTagID removes the part number of a continuation tag id
+func trimPartTagID(tagID string) string {
+	regex := regexp.MustCompile(regexp.QuoteMeta(PartTagSeparator) + `\d+$`)
+	return regex.ReplaceAllString(tagID, "")
 }
 
 // matchesHeaderTag returns true only when the comment body contains the header
@@ -67,67 +201,372 @@ func CreateNotifierService(ctx context.Context, c config.NotifierConfig) (Notifi
 		return NewGithubClient(ctx, c)
 	case config.VcsBitbucket:
 		return NewBitbucketProvider(ctx, c), nil
+	case config.VcsBitbucketServer:
+		return NewBitbucketServerProvider(ctx, c)
 	case config.VcsGitlab:
 		return NewGitlabClient(ctx, c), nil
+	case config.VcsCodeCommit:
+		return NewCodeCommitProvider(ctx, c)
+	case config.VcsAzureDevops:
+		return NewAzureDevopsProvider(ctx, c)
+	case config.VcsGitea:
+		return NewGiteaProvider(ctx, c)
 	default:
 		return nil, fmt.Errorf("unspported Version Control System: %s", c.Vcs)
 	}
 }
 
+// MaxCommentLength returns the max number of chars of a comment of the VCS or 0 if it is unknown.
+// GitHub Enterprise can be configured with a different limit.
+func MaxCommentLength(vcs string, githubMaxCommentLength int) int {
+	switch vcs {
+	case config.VcsGithubEnterprise:
+		if githubMaxCommentLength != 0 {
+			return githubMaxCommentLength
+		}
+		return GithubMaxCommentLength
+	case config.VcsGithub:
+		return GithubMaxCommentLength
+	case config.VcsBitbucket:
+		return BitbucketMaxCommentLength
+	case config.VcsBitbucketServer:
+		return BitbucketServerMaxCommentLength
+	case config.VcsGitlab:
+		return GitlabMaxCommentLength
+	case config.VcsCodeCommit:
+		return CodeCommitMaxCommentLength
+	case config.VcsAzureDevops:
+		return AzureDevopsMaxCommentLength
+	case config.VcsGitea:
+		return GiteaMaxCommentLength
+	default:
+		return 0
+	}
+}
+
 // postComment contains business logic how to create, update or delete comments
 // PostComment will create GitHub comment if comment does not exist yet bases on FindComment
-// If the comment already exist the content will be updated.
+// If the comment already exist the content will be updated unless the diff is unchanged.
 // If there are no cdk differences the comment will be deleted depending on DeleteComment config.AppConfig
 func postComment(ns NotifierService, config config.NotifierConfig) (CommentOperation, error) {
+	config, err := resolveCommentAuthor(ns, config)
+	if err != nil {
+		return API_COMMENT_NOTHING, err
+	}
 	comment, err := findComment(ns, config)
 	if err != nil {
 		return API_COMMENT_NOTHING, err
 	}
+	hasChanges := !config.ForceDeleteComment && diffHasChanges(ns.GetCommentContent())
+	return syncComment(ns, config, comment, config.TagID, hasChanges)
+}
+
+// postComments manages the comments of several tag ids with a single listing of comments.
+// Every ManagedComment is created, updated or deleted like in postComment. Its continuations follow the decision of the first part.
+// Existing comments of a group's tag id or its per stack tags that are not part of the group are deleted
+// depending on DeleteComment config.AppConfig. Left over continuations of a diff with changes are always deleted.
+func postComments(ns NotifierService, config config.NotifierConfig, groups []CommentGroup) (map[string]CommentOperation, error) {
+	operations := make(map[string]CommentOperation)
+	config, err := resolveCommentAuthor(ns, config)
+	if err != nil {
+		return operations, err
+	}
+	existing, err := ns.ListComments()
+	if err != nil {
+		return operations, err
+	}
+	existing = ownComments(existing, config)
+	deleted := make(map[int64]bool)
+	for _, group := range groups {
+		groupConfig := config
+		groupConfig.TagID = group.TagID
+		groupConfig.ForceDeleteComment = config.ForceDeleteComment || group.ForceDelete
+		err = postCommentGroup(ns, groupConfig, existing, group.Comments, operations, deleted)
+		if err != nil {
+			return operations, err
+		}
+	}
+	return operations, nil
+}
+
+func postCommentGroup(ns NotifierService, config config.NotifierConfig, existing []Comment, comments []ManagedComment, operations map[string]CommentOperation, deleted map[int64]bool) error {
+	managed := make(map[string]bool)
+	withChanges := make(map[string]bool)
+	for _, managedComment := range comments {
+		hasChanges := !config.ForceDeleteComment && diffHasChanges(managedComment.Content)
+		withChanges[managedComment.TagID] = hasChanges
+		parts := append([]string{managedComment.Content}, managedComment.Continuations...)
+		for i, part := range parts {
+			tagID := PartTagID(managedComment.TagID, i+1)
+			managed[tagID] = true
+			ns.SetCommentContent(part)
+			comment := findCommentByTag(existing, tagID, config.CommentAuthor)
+			operation, err := syncComment(ns, config, comment, tagID, hasChanges)
+			if err != nil {
+				return err
+			}
+			if comment != nil && (operation == API_COMMENT_DELETED || operation == API_COMMENT_HIDDEN || operation == API_COMMENT_CREATED) {
+				deleted[comment.Id] = true
+			}
+			operations[tagID] = operation
+		}
+	}
+	for _, comment := range existing {
+		if deleted[comment.Id] || comment.Hidden {
+			continue
+		}
+		if parseCommentMarker(comment.Body) == nil && !isLegacyComment(comment, config.CommentAuthor) {
+			continue
+		}
+		for _, tagID := range commentTagIDs(comment.Body) {
+			if managed[tagID] || !belongsToTag(tagID, config.TagID) {
+				continue
+			}
+			if !config.DeleteComment && !withChanges[trimPartTagID(tagID)] {
+				continue
+			}
+			operation, err := removeComment(ns, config, comment, tagID)
+			if err != nil {
+				return err
+			}
+			deleted[comment.Id] = true
+			operations[tagID] = operation
+			break
+		}
+	}
+	return nil
+}
+
+// syncComment creates, updates or deletes a single comment with the current comment content.
+// A comment showing the same diff is not edited apart from job link and timestamps.
+// It is only updated if the commit of its marker changed, so the marker shows the commit of the diff.
+// With HideOutdated comments are hidden instead of deleted and a new comment is created instead of updating the hidden one.
+func syncComment(ns NotifierService, config config.NotifierConfig, comment *Comment, tagID string, hasChanges bool) (CommentOperation, error) {
+	var err error
+	minimizer, hideOutdated := commentMinimizer(ns, config)
 	if comment != nil {
 		// if commit exists but there are no change then delete comment in case DeleteComment is active
 		// always execute if DeleteComment and ForceDeleteComment is true
-		if config.DeleteComment && (config.ForceDeleteComment || !diffHasChanges(ns.GetCommentContent())) {
-			err = ns.DeleteComment(comment.Id)
+		if config.DeleteComment && !hasChanges {
+			return removeComment(ns, config, *comment, tagID)
+		}
+		// editing an identical comment only notifies the reviewers again
+		sameBody := hasChanges && sameCommentBody(comment.Body, ns.GetCommentContent())
+		if sameBody && sameCommentCommit(comment.Body, ns.GetCommentContent()) {
+			logrus.Infof("Comment with id %d and tag id %s is unchanged. Skip updating diff.", comment.Id, tagID)
+			return API_COMMENT_UNCHANGED, nil
+		}
+		// the same diff of a new commit is updated in place instead of posting it again
+		if !hideOutdated || sameBody {
+			// if comment exists and there are diff then update existing comment
+			comment, err = ns.UpdateComment(comment.Id)
 			if err != nil {
 				logrus.Error(err)
 				return API_COMMENT_NOTHING, err
 			}
-			logrus.Infof("Deleted comment with id %d and tag id %s because no changes detected", comment.Id, config.TagID)
-			return API_COMMENT_DELETED, nil
+			logrus.Infof("Updated comment with id %d and tag id %s %v", comment.Id, tagID, comment.Link)
+			return API_COMMENT_UPDATED, nil
 		}
-		// if comment exists and there are diff then update existing comment
-		comment, err = ns.UpdateComment(comment.Id)
+	}
+	if !hasChanges {
+		logrus.Infof("There is no diff detected for tag id %s. Skip posting diff.", tagID)
+		return API_COMMENT_NOTHING, nil
+	}
+	created, err := ns.CreateComment()
+	if err != nil {
+		logrus.Error(err)
+		return API_COMMENT_NOTHING, err
+	}
+	logrus.Infof("Created comment with id %d and tag id %s %v", created.Id, tagID, created.Link)
+	if comment != nil {
+		// the previous diff is hidden after the new comment exists
+		err = minimizer.MinimizeComment(*comment)
 		if err != nil {
 			logrus.Error(err)
-			return API_COMMENT_NOTHING, err
+			return API_COMMENT_CREATED, err
 		}
-		logrus.Infof("Updated comment with id %d and tag id %s %v", comment.Id, config.TagID, comment.Link)
-		return API_COMMENT_UPDATED, nil
+		logrus.Infof("Hid outdated comment with id %d and tag id %s", comment.Id, tagID)
 	}
-	if config.ForceDeleteComment || !diffHasChanges(ns.GetCommentContent()) {
-		logrus.Infof("There is no diff detected for tag id %s. Skip posting diff.", config.TagID)
-		return API_COMMENT_NOTHING, nil
+	return API_COMMENT_CREATED, nil
+}
+
+// removeComment deletes the comment or hides it as outdated if HideOutdated is set and supported by the VCS
+func removeComment(ns NotifierService, config config.NotifierConfig, comment Comment, tagID string) (CommentOperation, error) {
+	if minimizer, ok := commentMinimizer(ns, config); ok {
+		err := minimizer.MinimizeComment(comment)
+		if err != nil {
+			logrus.Error(err)
+			return API_COMMENT_NOTHING, err
+		}
+		logrus.Infof("Hid comment with id %d and tag id %s because no changes detected", comment.Id, tagID)
+		return API_COMMENT_HIDDEN, nil
 	}
-	comment, err = ns.CreateComment()
+	err := ns.DeleteComment(comment.Id)
 	if err != nil {
 		logrus.Error(err)
 		return API_COMMENT_NOTHING, err
 	}
-	logrus.Infof("Created comment with id %d and tag id %s %v", comment.Id, config.TagID, comment.Link)
-	return API_COMMENT_CREATED, nil
+	logrus.Infof("Deleted comment with id %d and tag id %s because no changes detected", comment.Id, tagID)
+	return API_COMMENT_DELETED, nil
+}
+
+// commentMinimizer returns the CommentMinimizer of the provider if comments should be hidden instead of deleted
+func commentMinimizer(ns NotifierService, config config.NotifierConfig) (CommentMinimizer, bool) {
+	if !config.HideOutdated {
+		return nil, false
+	}
+	minimizer, ok := ns.(CommentMinimizer)
+	return minimizer, ok
+}
+
+// errCommentConflict is returned by providers with optimistic locking if a comment was changed after it was listed
+var errCommentConflict = errors.New("comment was changed by another job")
+
+var (
+	jobLinkRegex   = regexp.MustCompile(`\[Job Link\]\([^)]*\)`)
+	timestampRegex = regexp.MustCompile(regexp.QuoteMeta(TimestampMarker) + `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(Z|[+-]\d{2}:\d{2})`)
+)
+
+// normalizeCommentBody removes the parts of a comment which change with every pipeline run like the marker, job link and the timestamp of the run.
+// Line endings and trailing whitespace are normalized because some VCS change them when storing the comment.
+func normalizeCommentBody(body string) string {
+	body = stripCommentMarker(body)
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = jobLinkRegex.ReplaceAllString(body, "")
+	body = timestampRegex.ReplaceAllString(body, "")
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// sameCommentBody reports whether the existing comment shows the same diff as the new content
+func sameCommentBody(existing, content string) bool {
+	return normalizeCommentBody(existing) == normalizeCommentBody(content)
 }
 
-// findComment is finding the comment containing the cdk stack id
+// findComment is finding the comment containing the cdk stack id.
+// Comments with a marker of the tag id are preferred over comments only matching the header like replies quoting it.
 func findComment(ns NotifierService, config config.NotifierConfig) (*Comment, error) {
+	if searcher, ok := ns.(CommentSearcher); ok {
+		var legacy *Comment
+		comment, err := searcher.SearchComment(func(comment Comment) bool {
+			if comment.Hidden || !isOwnComment(comment, config) {
+				return false
+			}
+			if marker := parseCommentMarker(comment.Body); marker != nil {
+				return marker.TagID == config.TagID
+			}
+			if legacy == nil && matchesHeaderTag(comment.Body, headerTag(config.TagID)) && isLegacyComment(comment, config.CommentAuthor) {
+				legacy = &comment
+			}
+			return false
+		})
+		if comment == nil && err == nil {
+			comment = legacy
+		}
+		if comment != nil {
+			logrus.Debugf("Found existing comment for %s", config.TagID)
+		}
+		return comment, err
+	}
 	comments, err := ns.ListComments()
 	if err != nil {
 		return nil, err
 	}
+	return findCommentByTag(ownComments(comments, config), config.TagID, config.CommentAuthor), nil
+}
+
+// findCommentByTag returns the first comment with the marker of the tag id or nil. Hidden comments are ignored.
+// Without such comment the first comment without marker containing the header of the tag id is returned. It must be authored by the author if set.
+func findCommentByTag(comments []Comment, tagID string, author string) *Comment {
+	var legacy *Comment
+	for i := range comments {
+		comment := comments[i]
+		if comment.Hidden {
+			continue
+		}
+		if marker := parseCommentMarker(comment.Body); marker != nil {
+			if marker.TagID == tagID {
+				logrus.Debugf("Found existing comment for %s", tagID)
+				return &comment
+			}
+			continue
+		}
+		if legacy == nil && matchesHeaderTag(comment.Body, headerTag(tagID)) && isLegacyComment(comment, author) {
+			legacy = &comment
+		}
+	}
+	if legacy != nil {
+		logrus.Debugf("Found existing comment for %s by its header", tagID)
+	}
+	return legacy
+}
+
+// resolveCommentAuthor looks up the user of the token if only own comments should be managed and no author is configured.
+// The returned config contains the author.
+func resolveCommentAuthor(ns NotifierService, config config.NotifierConfig) (config.NotifierConfig, error) {
+	if !config.OnlyOwnComments || config.CommentAuthor != "" {
+		return config, nil
+	}
+	resolver, ok := ns.(AuthorResolver)
+	if !ok {
+		return config, fmt.Errorf("unable to determine the user of the token for %s. Set comment-author to manage only own comments", config.Vcs)
+	}
+	author, err := resolver.AuthenticatedUser()
+	if err != nil {
+		return config, fmt.Errorf("unable to determine the user of the token to manage only own comments. Set comment-author if the token has no access to its user: %w", err)
+	}
+	if author == "" {
+		return config, fmt.Errorf("unable to determine the user of the token to manage only own comments. Set comment-author")
+	}
+	logrus.Debugf("Managing comments authored by %s", author)
+	config.CommentAuthor = author
+	return config, nil
+}
+
+// isLegacyComment reports whether a comment without marker may be taken over by its header.
+// If the author is known only comments of the author are taken over, so comments of other users showing the header are not edited.
+// Without author all comments starting with the header are taken over like by older versions.
+func isLegacyComment(comment Comment, author string) bool {
+	if author == "" || sameAuthor(comment.Author, author) {
+		return true
+	}
+	logrus.Debugf("Ignoring comment with id %d without marker authored by %s", comment.Id, comment.Author)
+	return false
+}
+
+// isOwnComment reports whether the comment may be updated or deleted
+func isOwnComment(comment Comment, config config.NotifierConfig) bool {
+	return !config.OnlyOwnComments || sameAuthor(comment.Author, config.CommentAuthor)
+}
+
+// ownComments returns the comments which may be updated or deleted
+func ownComments(comments []Comment, config config.NotifierConfig) []Comment {
+	if !config.OnlyOwnComments {
+		return comments
+	}
+	var result []Comment
 	for _, comment := range comments {
-		if matchesHeaderTag(comment.Body, getHeaderTagID(config)) {
-			logrus.Debugf("Found existing comment for %s", config.TagID)
-			return &comment, nil
+		if isOwnComment(comment, config) {
+			result = append(result, comment)
+		} else if strings.Contains(comment.Body, HeaderPrefix) {
+			logrus.Debugf("Ignoring comment with id %d of %s", comment.Id, comment.Author)
 		}
 	}
-	return nil, nil
+	return result
+}
+
+var assumedRoleRegex = regexp.MustCompile(`^(arn:[^:]+:sts::\d+:assumed-role/[^/]+)/.*$`)
+
+// sameAuthor compares the authors case-insensitive.
+// The suffix [bot] of GitHub Apps is ignored because the GraphQL API omits it.
+// Assumed roles of AWS are compared without session name because CI systems like CodeBuild use a new session for every build.
+func sameAuthor(a, b string) bool {
+	normalize := func(author string) string {
+		author = strings.TrimSuffix(strings.ToLower(author), "[bot]")
+		return assumedRoleRegex.ReplaceAllString(author, "$1")
+	}
+	return a != "" && normalize(a) == normalize(b)
 }
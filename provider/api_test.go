@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/karlderkaefer/cdk-notifier/config"
@@ -61,20 +62,28 @@ func (m *mockNotifierService) PostComment() (CommentOperation, error) {
 	return API_COMMENT_NOTHING, nil
 }
 
+func (m *mockNotifierService) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return nil, nil
+}
+
 // ListComments is called within findComment()
 func (m *mockNotifierService) ListComments() ([]Comment, error) {
 	if m.returnFindErr {
 		return nil, errors.New("mock: error on findComment")
 	}
 	if m.comments != nil {
-		return m.comments, nil
+		return withFakeAuthor(m.comments), nil
 	}
 	if m.commentExists {
-		return []Comment{{Id: 123, Body: "## cdk diff for myTag\nSomething"}}, nil
+		return []Comment{{Id: 123, Body: "## cdk diff for myTag\nSomething", Author: fakeAuthor}}, nil
 	}
 	return []Comment{}, nil
 }
 
+func (m *mockNotifierService) AuthenticatedUser() (string, error) {
+	return fakeAuthor, nil
+}
+
 // TestPostCommentDataDriven tests all branches of postComment with data-driven style.
 func TestPostCommentDataDriven(t *testing.T) {
     logrus.SetLevel(logrus.DebugLevel)
@@ -298,3 +307,620 @@ func TestPostCommentPrefixTagRegression(t *testing.T) {
 	assert.Equal(t, API_COMMENT_UPDATED, op)
 	assert.Equal(t, int64(1), ms.updatedCommentId, "must update the foo comment, not foo-bar")
 }
+
+// fakeAuthor is the user of the token in tests. Comments of the tests without author are authored by this user.
+const fakeAuthor = "cdk-bot"
+
+func withFakeAuthor(comments []Comment) []Comment {
+	result := append([]Comment{}, comments...)
+	for i := range result {
+		if result[i].Author == "" {
+			result[i].Author = fakeAuthor
+		}
+	}
+	return result
+}
+
+// fakeCommentStore is an in-memory NotifierService keeping all comments of a pull request
+type fakeCommentStore struct {
+	comments       []Comment
+	commentContent string
+	nextId         int64
+	config         config.NotifierConfig
+}
+
+func (f *fakeCommentStore) CreateComment() (*Comment, error) {
+	f.nextId++
+	comment := Comment{Id: f.nextId, Body: f.commentContent}
+	f.comments = append(f.comments, comment)
+	return &comment, nil
+}
+
+func (f *fakeCommentStore) UpdateComment(id int64) (*Comment, error) {
+	for i := range f.comments {
+		if f.comments[i].Id == id {
+			f.comments[i].Body = f.commentContent
+			return &f.comments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find comment with id %d", id)
+}
+
+func (f *fakeCommentStore) DeleteComment(id int64) error {
+	for i := range f.comments {
+		if f.comments[i].Id == id {
+			f.comments = append(f.comments[:i], f.comments[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("could not find comment to delete with id %d", id)
+}
+
+func (f *fakeCommentStore) SetCommentContent(content string) {
+	f.commentContent = content
+}
+
+func (f *fakeCommentStore) GetCommentContent() string {
+	return f.commentContent
+}
+
+func (f *fakeCommentStore) PostComment() (CommentOperation, error) {
+	return postComment(f, f.config)
+}
+
+func (f *fakeCommentStore) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(f, f.config, groups)
+}
+
+func (f *fakeCommentStore) ListComments() ([]Comment, error) {
+	return withFakeAuthor(f.comments), nil
+}
+
+func (f *fakeCommentStore) AuthenticatedUser() (string, error) {
+	return fakeAuthor, nil
+}
+
+func (f *fakeCommentStore) bodies() []string {
+	var bodies []string
+	for _, comment := range f.comments {
+		bodies = append(bodies, comment.Body)
+	}
+	return bodies
+}
+
+func TestStackTagID(t *testing.T) {
+	assert.Equal(t, "dev::network", StackTagID("dev", "network"))
+	assert.Equal(t, "dev::Stage/App_(Stage-App)", StackTagID("dev", "Stage/App (Stage-App)"))
+	assert.Equal(t, "dev", StackTagID("dev", ""))
+}
+
+func TestParseHeaderTagIDs(t *testing.T) {
+	assert.Equal(t, []string{"dev::network"}, parseHeaderTagIDs("\n## cdk diff for dev::network [Job Link](https://ci)\ncontent"))
+	assert.Equal(t, []string{"dev", "prod"}, parseHeaderTagIDs("## cdk diff for dev\n## cdk diff for prod"))
+	assert.Nil(t, parseHeaderTagIDs("some comment"))
+}
+
+func TestPostComments(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev::network\nResources\nold"},
+			{Id: 2, Body: "## cdk diff for dev::database\nResources\nold"},
+			{Id: 3, Body: "## cdk diff for dev-other::database\nResources\nold"},
+			{Id: 4, Body: "## cdk diff for dev\nResources\nsingle comment before splitting"},
+			{Id: 5, Body: "a comment of a reviewer"},
+		},
+		nextId: 5,
+	}
+	operations, err := store.PostComments([]CommentGroup{{
+		TagID: "dev",
+		Comments: []ManagedComment{
+			{TagID: "dev::network", Content: "## cdk diff for dev::network\nResources\nnew"},
+			{TagID: "dev::app", Content: "## cdk diff for dev::app\nResources\nnew"},
+		},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev::network":  API_COMMENT_UPDATED,
+		"dev::app":      API_COMMENT_CREATED,
+		"dev::database": API_COMMENT_DELETED,
+		"dev":           API_COMMENT_DELETED,
+	}, operations)
+	assert.Equal(t, []string{
+		"## cdk diff for dev::network\nResources\nnew",
+		"## cdk diff for dev-other::database\nResources\nold",
+		"a comment of a reviewer",
+		"## cdk diff for dev::app\nResources\nnew",
+	}, store.bodies())
+}
+
+func TestPostCommentsWithoutDelete(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: false},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev::database\nResources\nold"},
+		},
+		nextId: 1,
+	}
+	operations, err := store.PostComments([]CommentGroup{{TagID: "dev"}})
+	assert.NoError(t, err)
+	assert.Empty(t, operations)
+	assert.Len(t, store.comments, 1)
+}
+
+func TestBelongsToTag(t *testing.T) {
+	assert.True(t, belongsToTag("dev", "dev"))
+	assert.True(t, belongsToTag("dev#2", "dev"))
+	assert.True(t, belongsToTag("dev::app", "dev"))
+	assert.True(t, belongsToTag("dev::app#12", "dev"))
+	assert.False(t, belongsToTag("dev-other", "dev"))
+	assert.False(t, belongsToTag("dev#2a", "dev"))
+	assert.False(t, belongsToTag("de", "dev"))
+	assert.Equal(t, "dev::app", trimPartTagID("dev::app#3"))
+}
+
+func TestPostCommentsWithContinuations(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: false},
+	}
+	operations, err := store.PostComments([]CommentGroup{{TagID: "dev", Comments: []ManagedComment{{
+		TagID:         "dev",
+		Content:       "## cdk diff for dev (part 1/3)\nResources\n1",
+		Continuations: []string{"## cdk diff for dev#2 (part 2/3)\n2", "## cdk diff for dev#3 (part 3/3)\n3"},
+	}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev":   API_COMMENT_CREATED,
+		"dev#2": API_COMMENT_CREATED,
+		"dev#3": API_COMMENT_CREATED,
+	}, operations)
+	assert.Len(t, store.comments, 3)
+
+	// left over continuations are deleted even if delete comment is disabled
+	operations, err = store.PostComments([]CommentGroup{{TagID: "dev", Comments: []ManagedComment{{
+		TagID:         "dev",
+		Content:       "## cdk diff for dev (part 1/2)\nResources\n1",
+		Continuations: []string{"## cdk diff for dev#2 (part 2/2)\n2"},
+	}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev":   API_COMMENT_UPDATED,
+		"dev#2": API_COMMENT_UPDATED,
+		"dev#3": API_COMMENT_DELETED,
+	}, operations)
+	assert.Equal(t, []string{"## cdk diff for dev (part 1/2)\nResources\n1", "## cdk diff for dev#2 (part 2/2)\n2"}, store.bodies())
+
+	// all parts are deleted together once there are no changes
+	store.config.DeleteComment = true
+	operations, err = store.PostComments([]CommentGroup{{TagID: "dev", Comments: []ManagedComment{{TagID: "dev", Content: "## cdk diff for dev\nThere were no differences"}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev":   API_COMMENT_DELETED,
+		"dev#2": API_COMMENT_DELETED,
+	}, operations)
+	assert.Empty(t, store.comments)
+}
+
+func TestPostCommentsMultipleGroups(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{DeleteComment: true},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev\nResources\nold"},
+			{Id: 2, Body: "## cdk diff for prod\nResources\nold"},
+			{Id: 3, Body: "## cdk diff for staging\nResources\nold"},
+		},
+		nextId: 3,
+	}
+	operations, err := store.PostComments([]CommentGroup{
+		{TagID: "dev", Comments: []ManagedComment{{TagID: "dev", Content: "## cdk diff for dev\nResources\nnew"}}},
+		{TagID: "prod", Comments: []ManagedComment{{TagID: "prod", Content: "## cdk diff for prod\nResources\nonly hashes"}}, ForceDelete: true},
+		{TagID: "test", Comments: []ManagedComment{{TagID: "test", Content: "## cdk diff for test\nResources\nnew"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev":  API_COMMENT_UPDATED,
+		"prod": API_COMMENT_DELETED,
+		"test": API_COMMENT_CREATED,
+	}, operations)
+	assert.Equal(t, []string{
+		"## cdk diff for dev\nResources\nnew",
+		"## cdk diff for staging\nResources\nold",
+		"## cdk diff for test\nResources\nnew",
+	}, store.bodies())
+}
+
+// minimizingCommentStore hides comments instead of deleting them like GitHub and GitLab
+type minimizingCommentStore struct {
+	*fakeCommentStore
+}
+
+func (m *minimizingCommentStore) MinimizeComment(comment Comment) error {
+	for i := range m.comments {
+		if m.comments[i].Id == comment.Id {
+			m.comments[i].Hidden = true
+			return nil
+		}
+	}
+	return fmt.Errorf("could not find comment to hide with id %d", comment.Id)
+}
+
+func (m *minimizingCommentStore) PostComment() (CommentOperation, error) {
+	return postComment(m, m.config)
+}
+
+func (m *minimizingCommentStore) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(m, m.config, groups)
+}
+
+func TestPostCommentHideOutdated(t *testing.T) {
+	store := &minimizingCommentStore{&fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true, HideOutdated: true},
+		comments: []Comment{{Id: 1, Body: "## cdk diff for dev\nResources\nold"}},
+		nextId:   1,
+	}}
+	// a new diff is posted as new comment and the previous diff is hidden
+	store.SetCommentContent("## cdk diff for dev\nResources\nnew")
+	operation, err := store.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.Equal(t, []Comment{
+		{Id: 1, Body: "## cdk diff for dev\nResources\nold", Hidden: true},
+		{Id: 2, Body: "## cdk diff for dev\nResources\nnew"},
+	}, store.comments)
+
+	store.SetCommentContent("## cdk diff for dev\nThere were no differences")
+	operation, err = store.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_HIDDEN, operation)
+	assert.True(t, store.comments[1].Hidden)
+
+	// hidden comments are not hidden again
+	operation, err = store.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_NOTHING, operation)
+	assert.Len(t, store.comments, 2)
+
+	// comments are deleted if the VCS does not support hiding
+	unsupported := &fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true, HideOutdated: true},
+		comments: []Comment{{Id: 1, Body: "## cdk diff for dev\nResources\nold"}},
+		nextId:   1,
+	}
+	unsupported.SetCommentContent("## cdk diff for dev\nThere were no differences")
+	operation, err = unsupported.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Empty(t, unsupported.comments)
+}
+
+func TestPostCommentsHideOutdated(t *testing.T) {
+	store := &minimizingCommentStore{&fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true, HideOutdated: true},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev::network\nResources\nold"},
+			{Id: 2, Body: "## cdk diff for dev::database\nResources\nold"},
+			{Id: 3, Body: "## cdk diff for dev::app\nResources\nolder", Hidden: true},
+		},
+		nextId: 3,
+	}}
+	operations, err := store.PostComments([]CommentGroup{{
+		TagID: "dev",
+		Comments: []ManagedComment{
+			{TagID: "dev::network", Content: "## cdk diff for dev::network\nResources\nnew"},
+			{TagID: "dev::app", Content: "## cdk diff for dev::app\nResources\nnew"},
+		},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev::network":  API_COMMENT_CREATED,
+		"dev::app":      API_COMMENT_CREATED,
+		"dev::database": API_COMMENT_HIDDEN,
+	}, operations)
+	var visible []string
+	for _, comment := range store.comments {
+		if !comment.Hidden {
+			visible = append(visible, comment.Body)
+		}
+	}
+	assert.Equal(t, []string{
+		"## cdk diff for dev::network\nResources\nnew",
+		"## cdk diff for dev::app\nResources\nnew",
+	}, visible)
+	assert.Len(t, store.comments, 5)
+}
+
+func TestNormalizeCommentBody(t *testing.T) {
+	tests := []struct {
+		existing string
+		content  string
+		expected bool
+	}{
+		{"## cdk diff for dev [Job Link](https://ci/job/1)\nResources\n[+] bucket", "## cdk diff for dev [Job Link](https://ci/job/2)\nResources\n[+] bucket", true},
+		{"## cdk diff for dev\r\nResources  \r\n[+] bucket\r\n", "## cdk diff for dev\nResources\n[+] bucket", true},
+		{"## cdk diff for dev\nupdated " + TimestampMarker + "2024-05-01T12:00:00Z\nResources", "## cdk diff for dev\nupdated " + TimestampMarker + "2024-05-02T08:30:15+02:00\nResources", true},
+		// timestamps of the diff are changes
+		{"## cdk diff for dev\nResources\n[~] DEPLOYED_AT: 2024-05-01T12:00:00Z", "## cdk diff for dev\nResources\n[~] DEPLOYED_AT: 2024-05-02T12:00:00Z", false},
+		{"## cdk diff for dev\nupdated 2024-05-01 12:00 UTC\nResources", "## cdk diff for dev\nupdated 2024-05-02 13:00 UTC\nResources", false},
+		{"## cdk diff for dev\nResources\n[+] bucket", "## cdk diff for dev\nResources\n[-] bucket", false},
+		{"## cdk diff for dev\nResources\n[+] bucket", "## cdk diff for prod\nResources\n[+] bucket", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sameCommentBody(tt.existing, tt.content), "%q and %q", tt.existing, tt.content)
+	}
+}
+
+// updateCountingStore counts the edits of comments
+type updateCountingStore struct {
+	*fakeCommentStore
+	updates int
+}
+
+func (u *updateCountingStore) UpdateComment(id int64) (*Comment, error) {
+	u.updates++
+	return u.fakeCommentStore.UpdateComment(id)
+}
+
+func TestPostCommentUnchanged(t *testing.T) {
+	store := &updateCountingStore{fakeCommentStore: &fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true},
+		comments: []Comment{{Id: 1, Body: "## cdk diff for dev [Job Link](https://ci/job/1)\r\nResources\r\n[+] bucket"}},
+		nextId:   1,
+	}}
+	store.SetCommentContent("## cdk diff for dev [Job Link](https://ci/job/2)\nResources\n[+] bucket")
+	operation, err := postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operation)
+	assert.Equal(t, 0, store.updates)
+
+	store.SetCommentContent("## cdk diff for dev [Job Link](https://ci/job/2)\nResources\n[+] queue")
+	operation, err = postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, 1, store.updates)
+
+	// an unchanged diff is not posted again when outdated comments are hidden
+	hiding := &minimizingCommentStore{&fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true, HideOutdated: true},
+		comments: []Comment{{Id: 1, Body: "## cdk diff for dev\nResources\n[+] bucket"}},
+		nextId:   1,
+	}}
+	hiding.SetCommentContent("## cdk diff for dev\nResources\n[+] bucket")
+	operation, err = hiding.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operation)
+	assert.Len(t, hiding.comments, 1)
+}
+
+func TestPostCommentUnchangedNewCommit(t *testing.T) {
+	content := "## cdk diff for dev\nResources\n[+] bucket"
+	previous := CommentMarker{TagID: "dev", CommitSha: "abc123"}.Embed(content)
+	store := &updateCountingStore{fakeCommentStore: &fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true},
+		comments: []Comment{{Id: 1, Body: previous}},
+		nextId:   1,
+	}}
+	// the same diff of a new commit updates the commit of the marker
+	store.SetCommentContent(CommentMarker{TagID: "dev", CommitSha: "def456"}.Embed(content))
+	operation, err := postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, 1, store.updates)
+	assert.Equal(t, "def456", parseCommentMarker(store.comments[0].Body).CommitSha)
+
+	operation, err = postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operation)
+	assert.Equal(t, 1, store.updates)
+
+	// the comment is updated in place instead of posting the same diff again when outdated comments are hidden
+	hiding := &minimizingCommentStore{&fakeCommentStore{
+		config:   config.NotifierConfig{TagID: "dev", DeleteComment: true, HideOutdated: true},
+		comments: []Comment{{Id: 1, Body: previous}},
+		nextId:   1,
+	}}
+	hiding.SetCommentContent(CommentMarker{TagID: "dev", CommitSha: "def456"}.Embed(content))
+	operation, err = hiding.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, hiding.comments, 1)
+	assert.False(t, hiding.comments[0].Hidden)
+	assert.Equal(t, "def456", parseCommentMarker(hiding.comments[0].Body).CommitSha)
+}
+
+func TestPostCommentsUnchanged(t *testing.T) {
+	store := &updateCountingStore{fakeCommentStore: &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev::network\nResources\n[+] vpc"},
+			{Id: 2, Body: "## cdk diff for dev::app\nResources\n[+] bucket"},
+		},
+		nextId: 2,
+	}}
+	operations, err := postComments(store, store.config, []CommentGroup{{
+		TagID: "dev",
+		Comments: []ManagedComment{
+			{TagID: "dev::network", Content: "## cdk diff for dev::network\nResources\n[+] vpc"},
+			{TagID: "dev::app", Content: "## cdk diff for dev::app\nResources\n[+] queue"},
+		},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev::network": API_COMMENT_UNCHANGED,
+		"dev::app":     API_COMMENT_UPDATED,
+	}, operations)
+	assert.Equal(t, 1, store.updates)
+	assert.Len(t, store.comments, 2)
+}
+
+// authoredCommentStore creates comments as the user of the token
+type authoredCommentStore struct {
+	*fakeCommentStore
+	user string
+	err  error
+}
+
+func (a *authoredCommentStore) CreateComment() (*Comment, error) {
+	comment, err := a.fakeCommentStore.CreateComment()
+	if err != nil {
+		return nil, err
+	}
+	a.comments[len(a.comments)-1].Author = a.user
+	comment.Author = a.user
+	return comment, nil
+}
+
+func (a *authoredCommentStore) AuthenticatedUser() (string, error) {
+	return a.user, a.err
+}
+
+func TestPostCommentOnlyOwnComments(t *testing.T) {
+	quote := Comment{Id: 1, Body: "> ## cdk diff for dev\n> Resources\n> old\nwhy is the bucket replaced?", Author: "alice"}
+	store := &authoredCommentStore{fakeCommentStore: &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true, OnlyOwnComments: true},
+		comments: []Comment{
+			quote,
+			{Id: 2, Body: "## cdk diff for dev\nResources\nold", Author: "other-bot"},
+			{Id: 3, Body: "## cdk diff for dev\nResources\nold", Author: "cdk-bot"},
+		},
+		nextId: 3,
+	}, user: "cdk-bot"}
+
+	store.SetCommentContent("## cdk diff for dev\nResources\nnew")
+	operation, err := postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, []string{quote.Body, "## cdk diff for dev\nResources\nold", "## cdk diff for dev\nResources\nnew"}, store.bodies())
+
+	store.SetCommentContent("## cdk diff for dev\nThere were no differences")
+	operation, err = postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Equal(t, []int64{1, 2}, []int64{store.comments[0].Id, store.comments[1].Id})
+
+	operation, err = postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_NOTHING, operation)
+	assert.Len(t, store.comments, 2)
+
+	// comments of other users are not deleted when managing several comments
+	store.comments = append(store.comments, Comment{Id: 4, Body: "## cdk diff for dev::network\nResources\nold", Author: "alice"})
+	operations, err := postComments(store, store.config, []CommentGroup{{
+		TagID:    "dev",
+		Comments: []ManagedComment{{TagID: "dev::app", Content: "## cdk diff for dev::app\nResources\nnew"}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{"dev::app": API_COMMENT_CREATED}, operations)
+	assert.Len(t, store.comments, 4)
+}
+
+func TestResolveCommentAuthor(t *testing.T) {
+	cfg := config.NotifierConfig{TagID: "dev", Vcs: "codecommit", OnlyOwnComments: true}
+	store := &fakeCommentStore{config: cfg}
+	// hides AuthenticatedUser of the store
+	unsupported := struct{ NotifierService }{store}
+	_, err := resolveCommentAuthor(unsupported, cfg)
+	assert.EqualError(t, err, "unable to determine the user of the token for codecommit. Set comment-author to manage only own comments")
+
+	cfg.CommentAuthor = "arn:aws:sts::123456789012:assumed-role/codebuild"
+	resolved, err := resolveCommentAuthor(unsupported, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/codebuild", resolved.CommentAuthor)
+
+	cfg.CommentAuthor = ""
+	failing := &authoredCommentStore{fakeCommentStore: store, err: errors.New("403 Resource not accessible by integration")}
+	_, err = resolveCommentAuthor(failing, cfg)
+	assert.EqualError(t, err, "unable to determine the user of the token to manage only own comments. Set comment-author if the token has no access to its user: 403 Resource not accessible by integration")
+
+	resolved, err = resolveCommentAuthor(&authoredCommentStore{fakeCommentStore: store, user: "cdk-bot"}, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "cdk-bot", resolved.CommentAuthor)
+
+	// the author is only required if only own comments are managed
+	cfg.OnlyOwnComments = false
+	resolved, err = resolveCommentAuthor(failing, cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved.CommentAuthor)
+	resolved, err = resolveCommentAuthor(unsupported, cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved.CommentAuthor)
+	resolved, err = resolveCommentAuthor(store, cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, resolved.CommentAuthor, "expect no lookup without only own comments")
+}
+
+func TestPostCommentLegacyCommentsOfOtherUsers(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true, CommentAuthor: fakeAuthor},
+		comments: []Comment{
+			{Id: 1, Body: "## cdk diff for dev\nResources\ncopied by alice", Author: "alice"},
+			{Id: 2, Body: "## cdk diff for dev::app\nResources\ncopied by alice", Author: "alice"},
+		},
+		nextId: 2,
+	}
+	store.SetCommentContent("## cdk diff for dev\nResources\nnew")
+	operation, err := postComment(store, store.config)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+
+	operations, err := postComments(store, store.config, []CommentGroup{{
+		TagID:    "dev",
+		Comments: []ManagedComment{{TagID: "dev", Content: "## cdk diff for dev\nThere were no differences"}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{"dev": API_COMMENT_DELETED}, operations)
+	assert.Equal(t, []int64{1, 2}, []int64{store.comments[0].Id, store.comments[1].Id}, "expect the comments of alice to be kept")
+
+	// without author comments of older versions are taken over by their header like before
+	failing := &authoredCommentStore{fakeCommentStore: store, err: errors.New("403 Resource not accessible by integration")}
+	cfg := config.NotifierConfig{TagID: "dev", DeleteComment: true}
+	failing.SetCommentContent("## cdk diff for dev\nResources\nnew")
+	operation, err = postComment(failing, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, store.comments, 2)
+	assert.Equal(t, "## cdk diff for dev\nResources\nnew", store.comments[0].Body)
+}
+
+func TestSameAuthor(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{"cdk-bot", "cdk-bot", true},
+		{"CDK-Bot", "cdk-bot", true},
+		{"github-actions[bot]", "github-actions", true},
+		{"arn:aws:sts::123456789012:assumed-role/codebuild/AWSCodeBuild-1", "arn:aws:sts::123456789012:assumed-role/codebuild/AWSCodeBuild-2", true},
+		{"arn:aws:sts::123456789012:assumed-role/codebuild/AWSCodeBuild-1", "arn:aws:sts::123456789012:assumed-role/codebuild", true},
+		{"arn:aws:sts::123456789012:assumed-role/codebuild/session", "arn:aws:sts::123456789012:assumed-role/developer/session", false},
+		{"alice", "cdk-bot", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sameAuthor(tt.a, tt.b), "%s and %s", tt.a, tt.b)
+	}
+}
+
+func TestPostCommentsWithMarker(t *testing.T) {
+	store := &fakeCommentStore{
+		config: config.NotifierConfig{TagID: "dev", DeleteComment: true},
+		comments: []Comment{
+			// reply quoting the header of the managed comment
+			{Id: 1, Body: "> ## cdk diff for dev\nWhy is the bucket replaced?"},
+			{Id: 2, Body: CommentMarker{TagID: "dev"}.Embed("### Custom template\nResources\n[+] bucket")},
+			{Id: 3, Body: CommentMarker{TagID: PartTagID("dev", 2)}.Embed("### Custom template part 2\nResources\n[+] queue")},
+		},
+		nextId: 3,
+	}
+	content := CommentMarker{TagID: "dev"}.Embed("### Custom template\nResources\n[~] bucket")
+	operations, err := postComments(store, store.config, []CommentGroup{{
+		TagID:    "dev",
+		Comments: []ManagedComment{{TagID: "dev", Content: content}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{
+		"dev":   API_COMMENT_UPDATED,
+		"dev#2": API_COMMENT_DELETED,
+	}, operations)
+	assert.Equal(t, []string{"> ## cdk diff for dev\nWhy is the bucket replaced?", content}, store.bodies())
+}
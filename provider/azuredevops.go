@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+// AzureDevopsMaxCommentLength is the maximum number of chars allowed by Azure DevOps in a single comment.
+const AzureDevopsMaxCommentLength = 150000
+
+// AzureDevopsProvider manages a pull request thread per tag id. The diff is the first comment of the thread.
+// The id of a Comment is the id of the thread.
+type AzureDevopsProvider struct {
+	Service        IAzureDevopsThreadService
+	Connection     IAzureDevopsConnectionService
+	Context        context.Context
+	Config         config.NotifierConfig
+	CommentContent string
+	// commentIDs maps thread ids to the id of their first comment
+	commentIDs map[int64]int64
+}
+
+func NewAzureDevopsProvider(ctx context.Context, config config.NotifierConfig) (*AzureDevopsProvider, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client, err := NewAzureDevopsClient(config.AzureDevopsUrl, config.TokenUser, config.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &AzureDevopsProvider{
+		Service:    client,
+		Connection: client,
+		Context:    ctx,
+		Config:     config,
+	}, nil
+}
+
+func (a *AzureDevopsProvider) transform(thread *AzureDevopsThread) *Comment {
+	comment := &Comment{}
+	if thread == nil {
+		return comment
+	}
+	comment.Id = thread.ID
+	if len(thread.Comments) > 0 {
+		if a.commentIDs == nil {
+			a.commentIDs = make(map[int64]int64)
+		}
+		a.commentIDs[thread.ID] = thread.Comments[0].ID
+		comment.Body = thread.Comments[0].Content
+		if thread.Comments[0].Author != nil {
+			comment.Author = thread.Comments[0].Author.ID
+		}
+	}
+	comment.Link = fmt.Sprintf("%s%s/_git/%s/pullrequest/%d?discussionId=%d", withTrailingSlash(a.Config.AzureDevopsUrl), url.PathEscape(a.Config.RepoOwner), url.PathEscape(a.Config.RepoName), a.Config.PullRequestID, thread.ID)
+	return comment
+}
+
+// firstCommentID returns the id of the comment containing the diff. Azure DevOps starts comment ids of a thread with 1.
+func (a *AzureDevopsProvider) firstCommentID(threadID int64) int64 {
+	if id, ok := a.commentIDs[threadID]; ok {
+		return id
+	}
+	return 1
+}
+
+func (a *AzureDevopsProvider) CreateComment() (*Comment, error) {
+	if a.CommentContent == "" {
+		return nil, errContentEmpty
+	}
+	thread, err := a.Service.CreateThread(a.Context, a.Config.RepoOwner, a.Config.RepoName, a.Config.PullRequestID, &AzureDevopsThread{
+		Status: AzureDevopsThreadStatusActive,
+		Comments: []AzureDevopsComment{
+			{Content: a.CommentContent, CommentType: 1},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.transform(thread), nil
+}
+
+func (a *AzureDevopsProvider) UpdateComment(id int64) (*Comment, error) {
+	comment, err := a.Service.UpdateComment(a.Context, a.Config.RepoOwner, a.Config.RepoName, a.Config.PullRequestID, id, a.firstCommentID(id), &AzureDevopsComment{
+		Content: a.CommentContent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.transform(&AzureDevopsThread{ID: id, Comments: []AzureDevopsComment{*comment}}), nil
+}
+
+// DeleteComment deletes the first comment, which removes the thread.
+// The thread is closed instead when AzureDevopsCloseThread is set.
+func (a *AzureDevopsProvider) DeleteComment(id int64) error {
+	if a.Config.AzureDevopsCloseThread {
+		_, err := a.Service.UpdateThread(a.Context, a.Config.RepoOwner, a.Config.RepoName, a.Config.PullRequestID, id, &AzureDevopsThread{
+			Status: AzureDevopsThreadStatusClosed,
+		})
+		if err != nil {
+			return err
+		}
+		logrus.Debugf("closed thread with id %d\n", id)
+		return nil
+	}
+	err := a.Service.DeleteComment(a.Context, a.Config.RepoOwner, a.Config.RepoName, a.Config.PullRequestID, id, a.firstCommentID(id))
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("deleted thread with id %d\n", id)
+	return nil
+}
+
+// AuthenticatedUser returns the identity id of the token
+func (a *AzureDevopsProvider) AuthenticatedUser() (string, error) {
+	user, err := a.Connection.GetAuthenticatedUser(a.Context)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func (a *AzureDevopsProvider) SetCommentContent(content string) {
+	a.CommentContent = content
+}
+
+func (a *AzureDevopsProvider) GetCommentContent() string {
+	return a.CommentContent
+}
+
+func (a *AzureDevopsProvider) PostComment() (CommentOperation, error) {
+	return postComment(a, a.Config)
+}
+
+func (a *AzureDevopsProvider) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(a, a.Config, groups)
+}
+
+// ListComments returns the first comment of all threads which are neither deleted nor closed
+func (a *AzureDevopsProvider) ListComments() ([]Comment, error) {
+	threads, err := a.Service.ListThreads(a.Context, a.Config.RepoOwner, a.Config.RepoName, a.Config.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	var result []Comment
+	for i, thread := range threads {
+		if thread.IsDeleted || thread.Status == AzureDevopsThreadStatusClosed || len(thread.Comments) == 0 || thread.Comments[0].IsDeleted {
+			continue
+		}
+		result = append(result, *a.transform(&threads[i]))
+	}
+	return result, nil
+}
+
+func withTrailingSlash(u string) string {
+	if strings.HasSuffix(u, "/") {
+		return u
+	}
+	return u + "/"
+}
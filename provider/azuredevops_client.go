@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	azureDevopsApiVersion = "7.1"
+	// AzureDevopsThreadStatusActive is the status of new threads
+	AzureDevopsThreadStatusActive = "active"
+	// AzureDevopsThreadStatusClosed is the status of threads without diff when threads are closed instead of deleted
+	AzureDevopsThreadStatusClosed = "closed"
+)
+
+type IAzureDevopsThreadService interface {
+	ListThreads(ctx context.Context, project string, repo string, prId int) ([]AzureDevopsThread, error)
+	CreateThread(ctx context.Context, project string, repo string, prId int, thread *AzureDevopsThread) (*AzureDevopsThread, error)
+	UpdateThread(ctx context.Context, project string, repo string, prId int, threadID int64, thread *AzureDevopsThread) (*AzureDevopsThread, error)
+	UpdateComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64, comment *AzureDevopsComment) (*AzureDevopsComment, error)
+	DeleteComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64) error
+}
+
+type AzureDevopsThread struct {
+	ID        int64                `json:"id,omitempty"`
+	Status    string               `json:"status,omitempty"`
+	IsDeleted bool                 `json:"isDeleted,omitempty"`
+	Comments  []AzureDevopsComment `json:"comments,omitempty"`
+}
+
+type IAzureDevopsConnectionService interface {
+	GetAuthenticatedUser(ctx context.Context) (*AzureDevopsIdentity, error)
+}
+
+type AzureDevopsComment struct {
+	ID              int64  `json:"id,omitempty"`
+	ParentCommentID int64  `json:"parentCommentId,omitempty"`
+	Content         string `json:"content,omitempty"`
+	// CommentType 1 is a text comment
+	CommentType int                  `json:"commentType,omitempty"`
+	IsDeleted   bool                 `json:"isDeleted,omitempty"`
+	Author      *AzureDevopsIdentity `json:"author,omitempty"`
+}
+
+// AzureDevopsIdentity is the author of a comment or the user of the token
+type AzureDevopsIdentity struct {
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type azureDevopsThreads struct {
+	Value []AzureDevopsThread `json:"value"`
+}
+
+// AzureDevopsProxy authenticates requests with a personal access token
+type AzureDevopsProxy struct {
+	Proxied  http.RoundTripper
+	username string
+	token    string
+}
+
+func (proxy AzureDevopsProxy) RoundTrip(req *http.Request) (*http.Response, error) {
+	logrus.Debugf("Sending request to %s%s", req.URL.Host, req.URL.Path)
+	// the request must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	// personal access tokens are accepted with any username
+	req.SetBasicAuth(proxy.username, proxy.token)
+	req.Header.Set("Accept", "application/json")
+	return proxy.Proxied.RoundTrip(req)
+}
+
+// AzureDevopsClient calls the pull request threads API of Azure DevOps
+type AzureDevopsClient struct {
+	*http.Client
+	// BaseURL is the organization url e.g. https://dev.azure.com/org/
+	BaseURL   *url.URL
+	UserAgent string
+}
+
+// NewAzureDevopsClient creates a client for the organization url
+func NewAzureDevopsClient(organizationUrl string, username string, token string) (*AzureDevopsClient, error) {
+	baseURL, err := url.Parse(withTrailingSlash(organizationUrl))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Azure DevOps url '%s': %w", organizationUrl, err)
+	}
+	httpClient := &http.Client{
+		Transport: NewRetryTransport(AzureDevopsProxy{
+			http.DefaultTransport, username, token,
+		}),
+	}
+	return &AzureDevopsClient{
+		Client:    httpClient,
+		BaseURL:   baseURL,
+		UserAgent: userAgent,
+	}, nil
+}
+
+func threadsPath(project string, repo string, prId int) string {
+	return fmt.Sprintf("%s/_apis/git/repositories/%s/pullRequests/%d/threads", url.PathEscape(project), url.PathEscape(repo), prId)
+}
+
+func (c *AzureDevopsClient) ListThreads(ctx context.Context, project string, repo string, prId int) ([]AzureDevopsThread, error) {
+	threads := &azureDevopsThreads{}
+	err := c.Do(ctx, http.MethodGet, threadsPath(project, repo, prId), nil, threads)
+	if err != nil {
+		return nil, err
+	}
+	return threads.Value, nil
+}
+
+func (c *AzureDevopsClient) CreateThread(ctx context.Context, project string, repo string, prId int, thread *AzureDevopsThread) (*AzureDevopsThread, error) {
+	threadResp := &AzureDevopsThread{}
+	err := c.Do(ctx, http.MethodPost, threadsPath(project, repo, prId), thread, threadResp)
+	if err != nil {
+		return nil, err
+	}
+	return threadResp, nil
+}
+
+func (c *AzureDevopsClient) UpdateThread(ctx context.Context, project string, repo string, prId int, threadID int64, thread *AzureDevopsThread) (*AzureDevopsThread, error) {
+	u := fmt.Sprintf("%s/%d", threadsPath(project, repo, prId), threadID)
+	threadResp := &AzureDevopsThread{}
+	err := c.Do(ctx, http.MethodPatch, u, thread, threadResp)
+	if err != nil {
+		return nil, err
+	}
+	return threadResp, nil
+}
+
+func (c *AzureDevopsClient) UpdateComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64, comment *AzureDevopsComment) (*AzureDevopsComment, error) {
+	u := fmt.Sprintf("%s/%d/comments/%d", threadsPath(project, repo, prId), threadID, commentID)
+	commentResp := &AzureDevopsComment{}
+	err := c.Do(ctx, http.MethodPatch, u, comment, commentResp)
+	if err != nil {
+		return nil, err
+	}
+	return commentResp, nil
+}
+
+func (c *AzureDevopsClient) DeleteComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64) error {
+	u := fmt.Sprintf("%s/%d/comments/%d", threadsPath(project, repo, prId), threadID, commentID)
+	return c.Do(ctx, http.MethodDelete, u, nil, nil)
+}
+
+// GetAuthenticatedUser returns the identity of the token from the connection data of the organization
+func (c *AzureDevopsClient) GetAuthenticatedUser(ctx context.Context) (*AzureDevopsIdentity, error) {
+	connectionData := &struct {
+		AuthenticatedUser *AzureDevopsIdentity `json:"authenticatedUser"`
+	}{}
+	err := c.Do(ctx, http.MethodGet, "_apis/connectionData?api-version="+azureDevopsApiVersion+"-preview", nil, connectionData)
+	if err != nil {
+		return nil, err
+	}
+	if connectionData.AuthenticatedUser == nil {
+		return nil, fmt.Errorf("Azure DevOps API Error: connection data has no authenticated user")
+	}
+	return connectionData.AuthenticatedUser, nil
+}
+
+// Do sends the request to the path relative to BaseURL and decodes the response into v if not nil
+func (c *AzureDevopsClient) Do(ctx context.Context, method string, path string, body interface{}, v interface{}) error {
+	if ctx == nil {
+		return errNonNilContext
+	}
+	u, err := c.BaseURL.Parse(path)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	// some APIs are only available as preview version
+	if query.Get("api-version") == "" {
+		query.Set("api-version", azureDevopsApiVersion)
+	}
+	u.RawQuery = query.Encode()
+
+	var buf io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		buf = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.Warnf("failed to close response body: %v", err)
+		}
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Azure DevOps API Error: %s %s", resp.Status, respBody)
+	}
+	if v == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, v)
+}
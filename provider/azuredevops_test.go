@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAzureDevopsThreadService struct {
+	threads []AzureDevopsThread
+}
+
+func (m *MockAzureDevopsThreadService) findThread(threadID int64) (*AzureDevopsThread, error) {
+	for i, thread := range m.threads {
+		if thread.ID == threadID {
+			return &m.threads[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find thread with id %d", threadID)
+}
+
+func (m *MockAzureDevopsThreadService) ListThreads(ctx context.Context, project string, repo string, prId int) ([]AzureDevopsThread, error) {
+	return m.threads, nil
+}
+
+func (m *MockAzureDevopsThreadService) CreateThread(ctx context.Context, project string, repo string, prId int, thread *AzureDevopsThread) (*AzureDevopsThread, error) {
+	created := *thread
+	created.ID = int64(len(m.threads) + 1)
+	created.Comments = []AzureDevopsComment{{ID: 1, Content: thread.Comments[0].Content, CommentType: thread.Comments[0].CommentType, Author: &AzureDevopsIdentity{ID: "6b2f"}}}
+	m.threads = append(m.threads, created)
+	return &created, nil
+}
+
+func (m *MockAzureDevopsThreadService) UpdateThread(ctx context.Context, project string, repo string, prId int, threadID int64, thread *AzureDevopsThread) (*AzureDevopsThread, error) {
+	existing, err := m.findThread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	existing.Status = thread.Status
+	return existing, nil
+}
+
+func (m *MockAzureDevopsThreadService) UpdateComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64, comment *AzureDevopsComment) (*AzureDevopsComment, error) {
+	thread, err := m.findThread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	for i, existing := range thread.Comments {
+		if existing.ID == commentID {
+			thread.Comments[i].Content = comment.Content
+			return &thread.Comments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find comment with id %d in thread %d", commentID, threadID)
+}
+
+func (m *MockAzureDevopsThreadService) DeleteComment(ctx context.Context, project string, repo string, prId int, threadID int64, commentID int64) error {
+	thread, err := m.findThread(threadID)
+	if err != nil {
+		return err
+	}
+	// deleting the only comment deletes the thread
+	thread.IsDeleted = true
+	thread.Comments[0].IsDeleted = true
+	return nil
+}
+
+func defaultTestAzureDevopsProvider(threads []AzureDevopsThread) (*AzureDevopsProvider, *MockAzureDevopsThreadService) {
+	mock := &MockAzureDevopsThreadService{threads: threads}
+	return &AzureDevopsProvider{
+		Service: mock,
+		Context: context.Background(),
+		Config: config.NotifierConfig{
+			TagID:          defaultTag,
+			DeleteComment:  true,
+			CommentAuthor:  "6b2f",
+			AzureDevopsUrl: "https://dev.azure.com/org",
+			RepoOwner:      "My Project",
+			RepoName:       "my-repo",
+			PullRequestID:  3,
+		},
+		CommentContent: defaultTag,
+	}, mock
+}
+
+func TestAzureDevopsProvider_PostComment(t *testing.T) {
+	initLogger()
+	client, mock := defaultTestAzureDevopsProvider([]AzureDevopsThread{
+		{ID: 1, Status: "active", Comments: []AzureDevopsComment{{ID: 1, Content: "some review comment"}}},
+		// system threads e.g. votes have no comments
+		{ID: 2},
+	})
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Policy Changes detected"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.Equal(t, AzureDevopsThreadStatusActive, mock.threads[2].Status)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Resources\nchanges"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, client.CommentContent, mock.threads[2].Comments[0].Content)
+
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Equal(t, int64(3), comments[1].Id)
+	assert.Equal(t, "https://dev.azure.com/org/My%20Project/_git/my-repo/pullrequest/3?discussionId=3", comments[1].Link)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.True(t, mock.threads[2].IsDeleted)
+	comments, err = client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+}
+
+func TestAzureDevopsProvider_CloseThread(t *testing.T) {
+	initLogger()
+	client, mock := defaultTestAzureDevopsProvider([]AzureDevopsThread{
+		{ID: 7, Status: "active", Comments: []AzureDevopsComment{{ID: 1, Content: fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nchanged"), Author: &AzureDevopsIdentity{ID: "6b2f"}}}},
+	})
+	client.Config.AzureDevopsCloseThread = true
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Equal(t, AzureDevopsThreadStatusClosed, mock.threads[0].Status)
+	assert.False(t, mock.threads[0].IsDeleted)
+
+	// closed threads are not updated again
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 0)
+}
+
+func TestAzureDevopsClient(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		username, token, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "", username)
+		assert.Equal(t, "pat", token)
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"value":[{"id":5,"status":"active","comments":[{"id":1,"content":"diff","commentType":1}]}],"count":1}`))
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			thread := AzureDevopsThread{}
+			assert.NoError(t, json.Unmarshal(body, &thread))
+			assert.Equal(t, AzureDevopsThread{Status: "active", Comments: []AzureDevopsComment{{Content: "new diff", CommentType: 1}}}, thread)
+			_, _ = w.Write([]byte(`{"id":6,"status":"active","comments":[{"id":1,"content":"new diff","commentType":1}]}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"thread not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	notifierConfig := config.NotifierConfig{AzureDevopsUrl: server.URL + "/org", Token: "pat", RepoOwner: "My Project", RepoName: "my-repo", PullRequestID: 3}
+	client, err := NewAzureDevopsProvider(context.Background(), notifierConfig)
+	assert.NoError(t, err)
+
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Comment{{Id: 5, Body: "diff", Link: server.URL + "/org/My%20Project/_git/my-repo/pullrequest/3?discussionId=5"}}, comments)
+
+	client.SetCommentContent("new diff")
+	comment, err := client.CreateComment()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), comment.Id)
+
+	err = client.DeleteComment(5)
+	assert.EqualError(t, err, `Azure DevOps API Error: 404 Not Found {"message":"thread not found"}`)
+
+	assert.Equal(t, []string{
+		"GET /org/My%20Project/_apis/git/repositories/my-repo/pullRequests/3/threads?api-version=7.1",
+		"POST /org/My%20Project/_apis/git/repositories/my-repo/pullRequests/3/threads?api-version=7.1",
+		"DELETE /org/My%20Project/_apis/git/repositories/my-repo/pullRequests/3/threads/5/comments/1?api-version=7.1",
+	}, requests)
+}
+
+func TestAzureDevopsProvider_AuthenticatedUser(t *testing.T) {
+	var request string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r.URL.Path + "?" + r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"authenticatedUser":{"id":"6b2f","providerDisplayName":"Build Service"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAzureDevopsProvider(context.Background(), config.NotifierConfig{AzureDevopsUrl: server.URL + "/org", Token: "pat"})
+	assert.NoError(t, err)
+	user, err := client.AuthenticatedUser()
+	assert.NoError(t, err)
+	assert.Equal(t, "6b2f", user)
+	assert.Equal(t, "/org/_apis/connectionData?api-version=7.1-preview", request)
+
+	thread := &AzureDevopsThread{ID: 1, Comments: []AzureDevopsComment{{ID: 1, Content: "diff", Author: &AzureDevopsIdentity{ID: "6b2f"}}}}
+	assert.Equal(t, "6b2f", client.transform(thread).Author)
+}
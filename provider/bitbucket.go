@@ -51,6 +51,9 @@ func (c *BitbucketComment) transform() *Comment {
 	if c.Links != nil {
 		comment.Link = c.Links.Html.Href
 	}
+	if c.User != nil {
+		comment.Author = c.User.UUID
+	}
 	return comment
 }
 
@@ -101,6 +104,15 @@ func (b *BitbucketProvider) DeleteComment(id int64) error {
 	return nil
 }
 
+// AuthenticatedUser returns the uuid of the token's user
+func (b *BitbucketProvider) AuthenticatedUser() (string, error) {
+	user, _, err := b.Client.Users.GetCurrentUser(b.Context)
+	if err != nil {
+		return "", err
+	}
+	return user.UUID, nil
+}
+
 func (b *BitbucketProvider) SetCommentContent(content string) {
 	b.CommentContent = content
 }
@@ -113,30 +125,53 @@ func (b *BitbucketProvider) PostComment() (CommentOperation, error) {
 	return postComment(b, b.Config)
 }
 
+func (b *BitbucketProvider) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(b, b.Config, groups)
+}
+
+// ListComments returns all comments of the pull request newest first
 func (b *BitbucketProvider) ListComments() ([]Comment, error) {
+	return listComments(b.walkComments)
+}
+
+func (b *BitbucketProvider) SearchComment(match func(comment Comment) bool) (*Comment, error) {
+	return searchComment(b.walkComments, match)
+}
+
+// walkComments visits the comments newest first until the response has no next page
+func (b *BitbucketProvider) walkComments(visit func(comment Comment) bool) error {
 	opts := &ListCommentOptions{
 		// filter out deleted comments
 		Query: "deleted=false",
 		// filter out content only to save bandwidth
-		Fields: "values.id,values.content.raw,values.links.html.href",
-		// set maximum pagelength so I don't need to bother with paging
+		Fields: "next,values.id,values.content.raw,values.links.html.href,values.user.uuid",
+		Sort:   "-created_on",
+		// maximum page length allowed by Bitbucket
 		PageLength: 100,
+		Page:       1,
 	}
-	comments, _, err := b.Service.ListComments(
-		b.Context,
-		b.Config.RepoOwner,
-		b.Config.RepoName,
-		int64(b.Config.PullRequestID),
-		opts,
-	)
-	if err != nil {
-		return nil, err
-	}
-	var result []Comment
-	if comments != nil && comments.Values != nil {
+	for {
+		comments, _, err := b.Service.ListComments(
+			b.Context,
+			b.Config.RepoOwner,
+			b.Config.RepoName,
+			int64(b.Config.PullRequestID),
+			opts,
+		)
+		if err != nil {
+			return err
+		}
+		if comments == nil {
+			return nil
+		}
 		for _, comment := range comments.Values {
-			result = append(result, *comment.transform())
+			if !visit(*comment.transform()) {
+				return nil
+			}
+		}
+		if comments.Next == "" || len(comments.Values) == 0 {
+			return nil
 		}
+		opts.Page++
 	}
-	return result, nil
 }
@@ -14,7 +14,6 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/google/go-querystring/query"
 	"github.com/sirupsen/logrus"
@@ -30,6 +29,8 @@ const (
 
 type BitbucketRepositoryService bitbucketService
 
+type BitbucketUserService bitbucketService
+
 type service struct {
 	client *BitbucketClient
 }
@@ -40,6 +41,7 @@ type BitbucketClient struct {
 	BaseURL      *url.URL
 	UserAgent    string
 	Repositories *BitbucketRepositoryService
+	Users        *BitbucketUserService
 }
 
 type bitbucketService struct {
@@ -57,6 +59,13 @@ type BitbucketComment struct {
 	Content *BitbucketContent `json:"content,omitempty"`
 	Id      *int64            `json:"id,omitempty"`
 	Links   *BitbucketLinks   `json:"links,omitempty"`
+	User    *BitbucketUser    `json:"user,omitempty"`
+}
+
+// BitbucketUser is the author of a comment or the user of the token
+type BitbucketUser struct {
+	UUID        string `json:"uuid,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
 }
 
 type BitbucketContent struct {
@@ -65,6 +74,8 @@ type BitbucketContent struct {
 
 type BitbucketComments struct {
 	Values []BitbucketComment `json:"values,omitempty"`
+	// Next is the url of the next page. It is empty on the last page.
+	Next string `json:"next,omitempty"`
 }
 
 type BitbucketLinks struct {
@@ -84,7 +95,9 @@ type BitbucketProxy struct {
 type ListCommentOptions struct {
 	Query      string `url:"q,omitempty"`
 	Fields     string `url:"fields,omitempty"`
+	Sort       string `url:"sort,omitempty"`
 	PageLength int    `url:"pagelen,omitempty"`
+	Page       int    `url:"page,omitempty"`
 }
 
 func (proxy BitbucketProxy) RoundTrip(req *http.Request) (res *http.Response, e error) {
@@ -107,10 +120,9 @@ func NewBitbucketComment(content string) *BitbucketComment {
 
 func NewBitbucketClient(username string, password string) *BitbucketClient {
 	httpClient := &http.Client{
-		Timeout: time.Duration(15) * time.Second,
-		Transport: BitbucketProxy{
+		Transport: NewRetryTransport(BitbucketProxy{
 			http.DefaultTransport, username, password,
-		},
+		}),
 	}
 	baseURL, _ := url.Parse(BitbucketDefaultBaseURL)
 	client := &BitbucketClient{
@@ -120,6 +132,7 @@ func NewBitbucketClient(username string, password string) *BitbucketClient {
 	}
 	client.common.client = client
 	client.Repositories = (*BitbucketRepositoryService)(&client.common)
+	client.Users = (*BitbucketUserService)(&client.common)
 	return client
 }
 
@@ -141,6 +154,20 @@ func (s *BitbucketRepositoryService) ListComments(ctx context.Context, owner str
 	return comments, resp, nil
 }
 
+// GetCurrentUser returns the user of the token. Workspace and repository access tokens have their own bot user.
+func (s *BitbucketUserService) GetCurrentUser(ctx context.Context) (*BitbucketUser, *http.Response, error) {
+	req, err := s.client.NewRequest(http.MethodGet, "user", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := &BitbucketUser{}
+	resp, err := s.client.Do(ctx, req, user)
+	if err != nil {
+		return nil, resp, err
+	}
+	return user, resp, nil
+}
+
 func (s *BitbucketRepositoryService) EditComment(ctx context.Context, owner string, repo string, prId int64, commentID int64, comment *BitbucketComment) (*BitbucketComment, *http.Response, error) {
 	u := fmt.Sprintf("repositories/%s/%s/pullrequests/%d/comments/%d", owner, repo, prId, commentID)
 	req, err := s.client.NewRequest(http.MethodPut, u, comment)
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+// BitbucketServerMaxCommentLength is the default maximum number of chars allowed by Bitbucket Server in a single comment.
+const BitbucketServerMaxCommentLength = 32768
+
+type BitbucketServerProvider struct {
+	Service        IBitbucketServerPullRequestService
+	Context        context.Context
+	Client         *BitbucketServerClient
+	Config         config.NotifierConfig
+	CommentContent string
+	// versions are the comment versions of the last listing or write. They are sent with edits to detect concurrent changes.
+	versions map[int64]int
+}
+
+func NewBitbucketServerProvider(ctx context.Context, config config.NotifierConfig) (*BitbucketServerProvider, error) {
+	client, err := NewBitbucketServerClient(config.BitbucketServerUrl, config.TokenUser, config.Token)
+	if err != nil {
+		return nil, err
+	}
+	b := &BitbucketServerProvider{
+		Context: ctx,
+		Client:  client,
+		Config:  config,
+		Service: client.PullRequests,
+	}
+	if b.Context == nil {
+		b.Context = context.Background()
+	}
+	return b, nil
+}
+
+func (b *BitbucketServerProvider) transform(c *BitbucketServerComment) *Comment {
+	var comment = &Comment{}
+	if c == nil {
+		return comment
+	}
+	comment.Id = c.Id
+	comment.Body = c.Text
+	if b.versions == nil {
+		b.versions = make(map[int64]int)
+	}
+	b.versions[c.Id] = c.Version
+	if c.Author != nil {
+		comment.Author = c.Author.Name
+	}
+	comment.Link = fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d/overview?commentId=%d", strings.TrimSuffix(b.Config.BitbucketServerUrl, "/"), url.PathEscape(b.Config.RepoOwner), url.PathEscape(b.Config.RepoName), b.Config.PullRequestID, c.Id)
+	return comment
+}
+
+// commentVersion returns the version of a comment returned by ListComments or CreateComment
+func (b *BitbucketServerProvider) commentVersion(id int64) (int, error) {
+	version, ok := b.versions[id]
+	if !ok {
+		return 0, fmt.Errorf("unknown Bitbucket Server comment with id %d", id)
+	}
+	return version, nil
+}
+
+// bitbucketServerWriteError reports a conflict if the comment has a newer version than listed
+func bitbucketServerWriteError(id int64, resp *http.Response, err error) error {
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: comment with id %d has a newer version than listed", errCommentConflict, id)
+	}
+	return err
+}
+
+func (b *BitbucketServerProvider) CreateComment() (*Comment, error) {
+	if b.CommentContent == "" {
+		return nil, errContentEmpty
+	}
+	comment, _, err := b.Service.CreateComment(
+		b.Context,
+		b.Config.RepoOwner,
+		b.Config.RepoName,
+		int64(b.Config.PullRequestID),
+		&BitbucketServerComment{Text: b.CommentContent},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return b.transform(comment), nil
+}
+
+func (b *BitbucketServerProvider) UpdateComment(id int64) (*Comment, error) {
+	version, err := b.commentVersion(id)
+	if err != nil {
+		return nil, err
+	}
+	comment, resp, err := b.Service.EditComment(
+		b.Context,
+		b.Config.RepoOwner,
+		b.Config.RepoName,
+		int64(b.Config.PullRequestID),
+		id,
+		&BitbucketServerComment{Text: b.CommentContent, Version: version},
+	)
+	if err != nil {
+		return nil, bitbucketServerWriteError(id, resp, err)
+	}
+	return b.transform(comment), nil
+}
+
+func (b *BitbucketServerProvider) DeleteComment(id int64) error {
+	version, err := b.commentVersion(id)
+	if err != nil {
+		return err
+	}
+	resp, err := b.Service.DeleteComment(
+		b.Context,
+		b.Config.RepoOwner,
+		b.Config.RepoName,
+		int64(b.Config.PullRequestID),
+		id,
+		version,
+	)
+	if err != nil {
+		return bitbucketServerWriteError(id, resp, err)
+	}
+	delete(b.versions, id)
+	logrus.Debugf("deleted comment with id %d\n", id)
+	return nil
+}
+
+// AuthenticatedUser returns the username of the token
+func (b *BitbucketServerProvider) AuthenticatedUser() (string, error) {
+	username, _, err := b.Client.GetCurrentUsername(b.Context)
+	return username, err
+}
+
+func (b *BitbucketServerProvider) SetCommentContent(content string) {
+	b.CommentContent = content
+}
+
+func (b *BitbucketServerProvider) GetCommentContent() string {
+	return b.CommentContent
+}
+
+func (b *BitbucketServerProvider) PostComment() (CommentOperation, error) {
+	return postComment(b, b.Config)
+}
+
+func (b *BitbucketServerProvider) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(b, b.Config, groups)
+}
+
+// ListComments returns the comments of all comment activities. Replies and deleted comments are not included.
+func (b *BitbucketServerProvider) ListComments() ([]Comment, error) {
+	var result []Comment
+	seen := make(map[int64]bool)
+	opts := &BitbucketServerPageOptions{Limit: 100}
+	for {
+		activities, _, err := b.Service.ListActivities(
+			b.Context,
+			b.Config.RepoOwner,
+			b.Config.RepoName,
+			int64(b.Config.PullRequestID),
+			opts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, activity := range activities.Values {
+			if activity.Action != "COMMENTED" || activity.Comment == nil || seen[activity.Comment.Id] {
+				continue
+			}
+			seen[activity.Comment.Id] = true
+			result = append(result, *b.transform(activity.Comment))
+		}
+		if activities.IsLastPage || len(activities.Values) == 0 {
+			return result, nil
+		}
+		opts.Start = activities.NextPageStart
+	}
+}
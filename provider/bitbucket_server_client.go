@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	bitbucketServerApiPath = "rest/api/1.0/"
+)
+
+type BitbucketServerPullRequestService bitbucketService
+
+// BitbucketServerClient uses the REST API 1.0 of Bitbucket Server and Data Center
+type BitbucketServerClient struct {
+	*BitbucketClient
+	PullRequests *BitbucketServerPullRequestService
+}
+
+type IBitbucketServerPullRequestService interface {
+	ListActivities(ctx context.Context, project string, repo string, prId int64, opts *BitbucketServerPageOptions) (*BitbucketServerActivities, *http.Response, error)
+	CreateComment(ctx context.Context, project string, repo string, prId int64, comment *BitbucketServerComment) (*BitbucketServerComment, *http.Response, error)
+	EditComment(ctx context.Context, project string, repo string, prId int64, commentID int64, comment *BitbucketServerComment) (*BitbucketServerComment, *http.Response, error)
+	DeleteComment(ctx context.Context, project string, repo string, prId int64, commentID int64, version int) (*http.Response, error)
+}
+
+type BitbucketServerComment struct {
+	Id   int64  `json:"id,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Version has to match the current version of the comment to edit or delete it
+	Version int                  `json:"version"`
+	Author  *BitbucketServerUser `json:"author,omitempty"`
+}
+
+type BitbucketServerUser struct {
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type BitbucketServerActivity struct {
+	Action        string                  `json:"action,omitempty"`
+	CommentAction string                  `json:"commentAction,omitempty"`
+	Comment       *BitbucketServerComment `json:"comment,omitempty"`
+}
+
+type BitbucketServerActivities struct {
+	Values        []BitbucketServerActivity `json:"values,omitempty"`
+	IsLastPage    bool                      `json:"isLastPage"`
+	NextPageStart int                       `json:"nextPageStart,omitempty"`
+}
+
+type BitbucketServerPageOptions struct {
+	Start int `url:"start,omitempty"`
+	Limit int `url:"limit,omitempty"`
+}
+
+// NewBitbucketServerClient creates a client for the Bitbucket Server url e.g. https://bitbucket.example.com.
+// Without username the token is sent as bearer token.
+func NewBitbucketServerClient(serverUrl string, username string, token string) (*BitbucketServerClient, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(serverUrl, "/") + "/" + bitbucketServerApiPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Bitbucket Server url '%s': %w", serverUrl, err)
+	}
+	client := &BitbucketServerClient{BitbucketClient: NewBitbucketClient(username, token)}
+	client.BaseURL = baseURL
+	client.PullRequests = &BitbucketServerPullRequestService{client: client.BitbucketClient}
+	return client, nil
+}
+
+// GetCurrentUsername returns the user of the token. Bitbucket Server has no endpoint for the current user
+// but sends the username in the header X-AUSERNAME of authenticated requests.
+func (c *BitbucketServerClient) GetCurrentUsername(ctx context.Context) (string, *http.Response, error) {
+	req, err := c.NewRequest(http.MethodGet, "application-properties", nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := c.Do(ctx, req, &struct{}{})
+	if err != nil {
+		return "", resp, err
+	}
+	username := resp.Header.Get("X-AUSERNAME")
+	if username == "" {
+		return "", resp, fmt.Errorf("BitBucket API Error: response has no header X-AUSERNAME")
+	}
+	return username, resp, nil
+}
+
+func bitbucketServerCommentsPath(project string, repo string, prId int64) string {
+	return fmt.Sprintf("projects/%s/repos/%s/pull-requests/%d/comments", url.PathEscape(project), url.PathEscape(repo), prId)
+}
+
+// ListActivities returns the activities of the pull request. Comments can only be listed as activities without a file path.
+func (s *BitbucketServerPullRequestService) ListActivities(ctx context.Context, project string, repo string, prId int64, opts *BitbucketServerPageOptions) (*BitbucketServerActivities, *http.Response, error) {
+	u := fmt.Sprintf("projects/%s/repos/%s/pull-requests/%d/activities", url.PathEscape(project), url.PathEscape(repo), prId)
+	u, err := addOptions(u, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	activities := &BitbucketServerActivities{}
+	resp, err := s.client.Do(ctx, req, activities)
+	if err != nil {
+		return nil, resp, err
+	}
+	return activities, resp, nil
+}
+
+func (s *BitbucketServerPullRequestService) CreateComment(ctx context.Context, project string, repo string, prId int64, comment *BitbucketServerComment) (*BitbucketServerComment, *http.Response, error) {
+	req, err := s.client.NewRequest(http.MethodPost, bitbucketServerCommentsPath(project, repo, prId), comment)
+	if err != nil {
+		return nil, nil, err
+	}
+	commentResp := &BitbucketServerComment{}
+	resp, err := s.client.Do(ctx, req, commentResp)
+	if err != nil {
+		return nil, resp, err
+	}
+	return commentResp, resp, nil
+}
+
+func (s *BitbucketServerPullRequestService) EditComment(ctx context.Context, project string, repo string, prId int64, commentID int64, comment *BitbucketServerComment) (*BitbucketServerComment, *http.Response, error) {
+	u := fmt.Sprintf("%s/%d", bitbucketServerCommentsPath(project, repo, prId), commentID)
+	req, err := s.client.NewRequest(http.MethodPut, u, comment)
+	if err != nil {
+		return nil, nil, err
+	}
+	commentResp := &BitbucketServerComment{}
+	resp, err := s.client.Do(ctx, req, commentResp)
+	if err != nil {
+		return nil, resp, err
+	}
+	return commentResp, resp, nil
+}
+
+func (s *BitbucketServerPullRequestService) DeleteComment(ctx context.Context, project string, repo string, prId int64, commentID int64, version int) (*http.Response, error) {
+	u := fmt.Sprintf("%s/%d?version=%d", bitbucketServerCommentsPath(project, repo, prId), commentID, version)
+	req, err := s.client.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(ctx, req, &BitbucketServerComment{})
+}
@@ -0,0 +1,218 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBitbucketServer implements the comment API of Bitbucket Server including comment versions
+type fakeBitbucketServer struct {
+	sync.Mutex
+	comments []*BitbucketServerComment
+	nextID   int64
+	pageSize int
+}
+
+func (f *fakeBitbucketServer) find(id int64) *BitbucketServerComment {
+	for _, comment := range f.comments {
+		if comment.Id == id {
+			return comment
+		}
+	}
+	return nil
+}
+
+func (f *fakeBitbucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.Header.Get("Authorization") != "Bearer some-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	base := "/rest/api/1.0/projects/PROJ/repos/my-repo/pull-requests/5/"
+	path := strings.TrimPrefix(r.URL.Path, base)
+	encoder := json.NewEncoder(w)
+	switch {
+	case r.Method == http.MethodGet && path == "activities":
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		end := min(start+f.pageSize, len(f.comments))
+		activities := BitbucketServerActivities{IsLastPage: end == len(f.comments), NextPageStart: end}
+		// newest activities first like Bitbucket Server
+		for i := len(f.comments) - 1 - start; i >= len(f.comments)-end; i-- {
+			activities.Values = append(activities.Values, BitbucketServerActivity{Action: "COMMENTED", CommentAction: "ADDED", Comment: f.comments[i]})
+		}
+		activities.Values = append(activities.Values, BitbucketServerActivity{Action: "APPROVED"})
+		_ = encoder.Encode(activities)
+	case r.Method == http.MethodPost && path == "comments":
+		comment := &BitbucketServerComment{}
+		_ = json.NewDecoder(r.Body).Decode(comment)
+		f.nextID++
+		comment.Id = f.nextID
+		comment.Author = &BitbucketServerUser{Name: "cdk-bot"}
+		f.comments = append(f.comments, comment)
+		w.WriteHeader(http.StatusCreated)
+		_ = encoder.Encode(comment)
+	case strings.HasPrefix(path, "comments/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "comments/"), 10, 64)
+		comment := f.find(id)
+		if comment == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_ = encoder.Encode(comment)
+		case http.MethodPut:
+			update := &BitbucketServerComment{}
+			_ = json.NewDecoder(r.Body).Decode(update)
+			if update.Version != comment.Version {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			comment.Text = update.Text
+			comment.Version++
+			_ = encoder.Encode(comment)
+		case http.MethodDelete:
+			if r.URL.Query().Get("version") != strconv.Itoa(comment.Version) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			for i, existing := range f.comments {
+				if existing.Id == id {
+					f.comments = append(f.comments[:i], f.comments[i+1:]...)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/api/1.0/application-properties":
+		w.Header().Set("X-AUSERNAME", "cdk-bot")
+		_ = encoder.Encode(map[string]string{"displayName": "Bitbucket"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func defaultTestBitbucketServerProvider(t *testing.T, fake *fakeBitbucketServer) *BitbucketServerProvider {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := NewBitbucketServerProvider(context.Background(), config.NotifierConfig{
+		TagID:              defaultTag,
+		DeleteComment:      true,
+		Token:              "some-token",
+		RepoOwner:          "PROJ",
+		RepoName:           "my-repo",
+		PullRequestID:      5,
+		BitbucketServerUrl: server.URL + "/",
+	})
+	assert.NoError(t, err)
+	return client
+}
+
+func TestBitbucketServerProvider_PostComment(t *testing.T) {
+	initLogger()
+	fake := &fakeBitbucketServer{pageSize: 2}
+	for i := 1; i <= 3; i++ {
+		fake.nextID++
+		fake.comments = append(fake.comments, &BitbucketServerComment{Id: fake.nextID, Text: fmt.Sprintf("review comment %d", i), Version: 2})
+	}
+	client := defaultTestBitbucketServerProvider(t, fake)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Policy Changes detected"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+
+	// edits send the current version of the comment
+	for i := 0; i < 2; i++ {
+		client.SetCommentContent(fmt.Sprintf("%s %s\n%s %d", HeaderPrefix, defaultTag, "there are Resources\nchanges", i))
+		operation, err = client.PostComment()
+		assert.NoError(t, err)
+		assert.Equal(t, API_COMMENT_UPDATED, operation)
+	}
+	assert.Equal(t, 2, fake.find(4).Version)
+
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 4)
+	assert.Equal(t, client.CommentContent, comments[0].Body)
+	assert.Equal(t, fmt.Sprintf("%s/projects/PROJ/repos/my-repo/pull-requests/5/overview?commentId=4", strings.TrimSuffix(client.Config.BitbucketServerUrl, "/")), comments[0].Link)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Nil(t, fake.find(4))
+}
+
+func TestBitbucketServerProvider_Errors(t *testing.T) {
+	initLogger()
+	client := defaultTestBitbucketServerProvider(t, &fakeBitbucketServer{pageSize: 2})
+	_, err := client.UpdateComment(42)
+	assert.EqualError(t, err, "unknown Bitbucket Server comment with id 42")
+
+	client.Client.Transport = BitbucketProxy{http.DefaultTransport, "", "wrong-token"}
+	_, err = client.ListComments()
+	assert.EqualError(t, err, "BitBucket API Error: 401 Unauthorized ")
+}
+
+func TestBitbucketServerProvider_OnlyOwnComments(t *testing.T) {
+	initLogger()
+	body := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	fake := &fakeBitbucketServer{pageSize: 2, nextID: 2, comments: []*BitbucketServerComment{
+		{Id: 1, Text: body, Author: &BitbucketServerUser{Name: "alice"}},
+		{Id: 2, Text: body, Author: &BitbucketServerUser{Name: "cdk-bot"}},
+	}}
+	client := defaultTestBitbucketServerProvider(t, fake)
+	client.Config.OnlyOwnComments = true
+
+	user, err := client.AuthenticatedUser()
+	assert.NoError(t, err)
+	assert.Equal(t, "cdk-bot", user)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Nil(t, fake.find(2))
+	assert.NotNil(t, fake.find(1))
+}
+
+func TestBitbucketServerProvider_Conflict(t *testing.T) {
+	initLogger()
+	body := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	fake := &fakeBitbucketServer{pageSize: 2, nextID: 1, comments: []*BitbucketServerComment{{Id: 1, Text: body, Version: 3, Author: &BitbucketServerUser{Name: "cdk-bot"}}}}
+	client := defaultTestBitbucketServerProvider(t, fake)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+
+	// another job edits the comment after it was listed
+	fake.find(1).Version++
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nnew"))
+	_, err = client.UpdateComment(1)
+	assert.ErrorIs(t, err, errCommentConflict)
+	assert.EqualError(t, err, "comment was changed by another job: comment with id 1 has a newer version than listed")
+	err = client.DeleteComment(1)
+	assert.ErrorIs(t, err, errCommentConflict)
+	assert.Equal(t, body, fake.find(1).Text)
+
+	// the version of the last listing is sent
+	_, err = client.ListComments()
+	assert.NoError(t, err)
+	updated, err := client.UpdateComment(1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated.Id)
+	assert.Equal(t, 5, fake.find(1).Version)
+	assert.NoError(t, client.DeleteComment(1))
+	assert.Nil(t, fake.find(1))
+}
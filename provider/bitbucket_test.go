@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"slices"
 	"sync"
 	"testing"
 
@@ -15,13 +18,26 @@ import (
 
 type MockBitbucketRepositoryService struct {
 	sync.RWMutex
-	comments []*BitbucketComment
+	comments  []*BitbucketComment
+	listCalls int
 }
 
+// ListComments returns the comments paginated and sorted like Bitbucket
 func (m *MockBitbucketRepositoryService) ListComments(ctx context.Context, owner string, repo string, prId int64, opts *ListCommentOptions) (*BitbucketComments, *http.Response, error) {
-	comments := &BitbucketComments{}
+	m.listCalls++
+	var values []BitbucketComment
 	for _, comment := range m.comments {
-		comments.Values = append(comments.Values, *comment)
+		values = append(values, *comment)
+	}
+	if opts.Sort == "-created_on" {
+		slices.Reverse(values)
+	}
+	page := max(opts.Page, 1)
+	start := min((page-1)*opts.PageLength, len(values))
+	end := min(page*opts.PageLength, len(values))
+	comments := &BitbucketComments{Values: values[start:end]}
+	if end < len(values) {
+		comments.Next = fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d/comments?page=%d", owner, repo, prId, page+1)
 	}
 	return comments, nil, nil
 }
@@ -47,6 +63,7 @@ func (m *MockBitbucketRepositoryService) CreateComment(ctx context.Context, owne
 	defer m.Unlock()
 	id := int64(len(m.comments))
 	comment.Id = &id
+	comment.User = &BitbucketUser{UUID: "{cdk-bot}"}
 	m.comments = append(m.comments, comment)
 	return comment, nil, nil
 }
@@ -74,7 +91,7 @@ func defaultTestBitbucketProvider(comments []*BitbucketComment) *BitbucketProvid
 	return &BitbucketProvider{
 		Service:        mock,
 		Context:        context.Background(),
-		Config:         config.NotifierConfig{TagID: "test-tag", DeleteComment: true},
+		Config:         config.NotifierConfig{TagID: "test-tag", DeleteComment: true, CommentAuthor: "{cdk-bot}"},
 		CommentContent: "test-content",
 	}
 }
@@ -115,6 +132,34 @@ func TestBitbucketProvider_CreateAndDeleteComment(t *testing.T) {
 
 }
 
+func TestBitbucketProvider_ListCommentsPaginated(t *testing.T) {
+	initLogger()
+	var commentsMock []*BitbucketComment
+	for i := int64(1); i <= 250; i++ {
+		commentsMock = append(commentsMock, &BitbucketComment{
+			Id:      &i,
+			Content: &BitbucketContent{fmt.Sprintf("%s example-%d", HeaderPrefix, i)},
+		})
+	}
+	client := defaultTestBitbucketProvider(commentsMock)
+	mock := client.Service.(*MockBitbucketRepositoryService)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 250)
+	assert.Equal(t, int64(250), comments[0].Id, "expect newest comment first")
+	assert.Equal(t, 3, mock.listCalls)
+
+	mock.listCalls = 0
+	comment, err := client.SearchComment(func(comment Comment) bool { return comment.Id == 120 })
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120), comment.Id)
+	assert.Equal(t, 2, mock.listCalls)
+
+	comment, err = client.SearchComment(func(comment Comment) bool { return comment.Id == 251 })
+	assert.NoError(t, err)
+	assert.Nil(t, comment)
+}
+
 type bitbucketTestObject struct {
 	input                string
 	tag                  string
@@ -222,3 +267,23 @@ func TestBitBucketApi(t *testing.T) {
 	}()
 
 }
+
+func TestBitbucketProvider_AuthenticatedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/user" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"uuid": "{b7c1}", "display_name": "cdk-bot"}`))
+	}))
+	defer server.Close()
+	client := NewBitbucketProvider(context.Background(), config.NotifierConfig{Token: "some-token"})
+	client.Client.BaseURL, _ = url.Parse(server.URL + "/2.0/")
+	user, err := client.AuthenticatedUser()
+	assert.NoError(t, err)
+	assert.Equal(t, "{b7c1}", user)
+
+	id := int64(1)
+	comment := &BitbucketComment{Id: &id, Content: &BitbucketContent{"diff"}, User: &BitbucketUser{UUID: "{b7c1}"}}
+	assert.Equal(t, &Comment{Id: 1, Body: "diff", Author: "{b7c1}"}, comment.transform())
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"path"
+	"strings"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+// managedTagID returns the tag id of a comment managed by cdk-notifier.
+// Comments without marker are only managed if they start with the header, so replies quoting the header are not taken over.
+// They have to be authored by the author as well. Without author only comments with marker are managed,
+// because comments of other users could start with the header.
+func managedTagID(comment Comment, author string) (string, bool) {
+	if marker := parseCommentMarker(comment.Body); marker != nil {
+		return marker.TagID, true
+	}
+	if author == "" || !sameAuthor(comment.Author, author) {
+		return "", false
+	}
+	body := strings.TrimSpace(staleBannerRegex.ReplaceAllString(comment.Body, ""))
+	if !strings.HasPrefix(body, HeaderPrefix) {
+		return "", false
+	}
+	tagIDs := parseHeaderTagIDs(body)
+	if len(tagIDs) == 0 {
+		return "", false
+	}
+	return tagIDs[0], true
+}
+
+// Cleanup removes all comments managed by cdk-notifier whose tag id matches the glob pattern. An empty pattern matches all tag ids.
+// Comments of older versions without marker are only removed if config.CommentAuthor is known and authored them.
+// Comments are hidden instead of deleted if HideOutdated is set and supported by the VCS. Otherwise hidden comments are deleted as well.
+// The operations are returned by comment id.
+func Cleanup(ns NotifierService, config config.NotifierConfig, pattern string) (map[int64]CommentOperation, error) {
+	operations := make(map[int64]CommentOperation)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return operations, err
+	}
+	config, err := resolveCommentAuthor(ns, config)
+	if err != nil {
+		return operations, err
+	}
+	comments, err := ns.ListComments()
+	if err != nil {
+		return operations, err
+	}
+	if config.CommentAuthor == "" {
+		logrus.Infof("Skipping comments of older versions without marker because the author is unknown. Set comment-author to remove them")
+	}
+	minimizer, hide := commentMinimizer(ns, config)
+	for _, comment := range ownComments(comments, config) {
+		tagID, ok := managedTagID(comment, config.CommentAuthor)
+		if !ok || (hide && comment.Hidden) {
+			continue
+		}
+		if matched, _ := path.Match(pattern, tagID); pattern != "" && !matched {
+			continue
+		}
+		if hide {
+			err = minimizer.MinimizeComment(comment)
+			if err != nil {
+				logrus.Error(err)
+				return operations, err
+			}
+			logrus.Infof("Hid comment with id %d and tag id %s", comment.Id, tagID)
+			operations[comment.Id] = API_COMMENT_HIDDEN
+			continue
+		}
+		err = ns.DeleteComment(comment.Id)
+		if err != nil {
+			logrus.Error(err)
+			return operations, err
+		}
+		logrus.Infof("Deleted comment with id %d and tag id %s", comment.Id, tagID)
+		operations[comment.Id] = API_COMMENT_DELETED
+	}
+	return operations, nil
+}
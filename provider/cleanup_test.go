@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func cleanupComments() []Comment {
+	return []Comment{
+		{Id: 1, Body: CommentMarker{TagID: "dev"}.Embed("### Custom template\nResources")},
+		{Id: 2, Body: "## cdk diff for dev::app\nResources"},
+		{Id: 3, Body: "## cdk diff for prod#2 (part 2/2)\nResources"},
+		{Id: 4, Body: "> ## cdk diff for dev\nWhy is the bucket replaced?"},
+		{Id: 5, Body: "review comment"},
+		{Id: 6, Body: staleBanner("abc") + "## cdk diff for old-tag\nResources"},
+		{Id: 7, Body: "## cdk diff for dev\nold diff", Hidden: true},
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	store := &fakeCommentStore{comments: cleanupComments()}
+	cfg := config.NotifierConfig{CommentAuthor: fakeAuthor}
+	operations, err := Cleanup(store, cfg, "dev*")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{1: API_COMMENT_DELETED, 2: API_COMMENT_DELETED, 7: API_COMMENT_DELETED}, operations)
+
+	operations, err = Cleanup(store, cfg, "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{3: API_COMMENT_DELETED, 6: API_COMMENT_DELETED}, operations)
+	assert.Equal(t, []string{"> ## cdk diff for dev\nWhy is the bucket replaced?", "review comment"}, store.bodies())
+
+	_, err = Cleanup(store, cfg, "[")
+	assert.EqualError(t, err, "syntax error in pattern")
+}
+
+func TestCleanupHideOutdated(t *testing.T) {
+	store := &minimizingCommentStore{&fakeCommentStore{comments: cleanupComments()}}
+	operations, err := Cleanup(store, config.NotifierConfig{HideOutdated: true, CommentAuthor: fakeAuthor}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{
+		1: API_COMMENT_HIDDEN,
+		2: API_COMMENT_HIDDEN,
+		3: API_COMMENT_HIDDEN,
+		6: API_COMMENT_HIDDEN,
+	}, operations, "expect hidden comments to be skipped")
+	assert.Len(t, store.comments, 7)
+}
+
+func TestCleanupOnlyOwnComments(t *testing.T) {
+	store := &fakeCommentStore{comments: []Comment{
+		{Id: 1, Body: "## cdk diff for dev\nResources", Author: "cdk-bot"},
+		{Id: 2, Body: "## cdk diff for dev\nResources", Author: "other-bot"},
+	}}
+	operations, err := Cleanup(store, config.NotifierConfig{OnlyOwnComments: true, CommentAuthor: "cdk-bot"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{1: API_COMMENT_DELETED}, operations)
+}
+
+func TestCleanupCommentsOfOtherUsers(t *testing.T) {
+	store := &fakeCommentStore{comments: []Comment{
+		{Id: 1, Body: CommentMarker{TagID: "dev"}.Embed("## cdk diff for dev\nResources")},
+		{Id: 2, Body: "## cdk diff for dev\nResources\npasted by alice", Author: "alice"},
+		{Id: 3, Body: "## cdk diff for dev\nResources"},
+	}}
+	// without author only comments with marker are removed
+	operations, err := Cleanup(store, config.NotifierConfig{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{1: API_COMMENT_DELETED}, operations)
+
+	operations, err = Cleanup(store, config.NotifierConfig{CommentAuthor: fakeAuthor}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]CommentOperation{3: API_COMMENT_DELETED}, operations)
+	assert.Equal(t, []string{"## cdk diff for dev\nResources\npasted by alice"}, store.bodies())
+}
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+// CodeCommitMaxCommentLength is the maximum number of chars allowed by CodeCommit in a single comment.
+const CodeCommitMaxCommentLength = 10240
+
+// CodeCommitIdentityService interface for the required STS actions to look up the caller
+type CodeCommitIdentityService interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+type CodeCommitProvider struct {
+	Service        ICodeCommitService
+	Identity       CodeCommitIdentityService
+	Context        context.Context
+	Config         config.NotifierConfig
+	CommentContent string
+	// commentIDs maps the ids used by NotifierService to the CodeCommit comment ids
+	commentIDs map[int64]string
+	target     *CodeCommitPullRequestTarget
+}
+
+// NewCodeCommitProvider creates a provider for CodeCommit using the AWS credentials of the environment
+func NewCodeCommitProvider(ctx context.Context, config config.NotifierConfig) (*CodeCommitProvider, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	awsConfig, err := LoadAwsConfig(ctx, config.AwsRegion)
+	if err != nil {
+		return nil, err
+	}
+	return &CodeCommitProvider{
+		Service:  NewCodeCommitClient(config.AwsRegion, awsConfig.Credentials),
+		Identity: sts.NewFromConfig(awsConfig),
+		Context:  ctx,
+		Config:   config,
+	}, nil
+}
+
+// codeCommitCommentID converts the CodeCommit comment id into the int64 id used by NotifierService
+func codeCommitCommentID(commentID string) int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(commentID))
+	return int64(hash.Sum64() & math.MaxInt64)
+}
+
+func (c *CodeCommitProvider) transform(comment *CodeCommitComment) *Comment {
+	if comment == nil {
+		return &Comment{}
+	}
+	id := codeCommitCommentID(comment.CommentID)
+	if c.commentIDs == nil {
+		c.commentIDs = make(map[int64]string)
+	}
+	c.commentIDs[id] = comment.CommentID
+	return &Comment{
+		Id:     id,
+		Body:   comment.Content,
+		Link:   fmt.Sprintf("https://%s.console.aws.amazon.com/codesuite/codecommit/repositories/%s/pull-requests/%d/activity", c.Config.AwsRegion, c.Config.RepoName, c.Config.PullRequestID),
+		Author: comment.AuthorArn,
+	}
+}
+
+// lookupCommentID returns the CodeCommit comment id of a comment returned by ListComments or CreateComment
+func (c *CodeCommitProvider) lookupCommentID(id int64) (string, error) {
+	commentID, ok := c.commentIDs[id]
+	if !ok {
+		return "", fmt.Errorf("unknown CodeCommit comment with id %d", id)
+	}
+	return commentID, nil
+}
+
+// pullRequestTarget returns the commits of the pull request. Comments are posted on the latest source commit.
+func (c *CodeCommitProvider) pullRequestTarget() (*CodeCommitPullRequestTarget, error) {
+	if c.target != nil {
+		return c.target, nil
+	}
+	pullRequest, err := c.Service.GetPullRequest(c.Context, strconv.Itoa(c.Config.PullRequestID))
+	if err != nil {
+		return nil, err
+	}
+	if pullRequest != nil {
+		for i, target := range pullRequest.PullRequestTargets {
+			if target.RepositoryName == c.Config.RepoName {
+				c.target = &pullRequest.PullRequestTargets[i]
+				return c.target, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("pull request %d has no target in repository %s", c.Config.PullRequestID, c.Config.RepoName)
+}
+
+func (c *CodeCommitProvider) CreateComment() (*Comment, error) {
+	if c.CommentContent == "" {
+		return nil, errContentEmpty
+	}
+	target, err := c.pullRequestTarget()
+	if err != nil {
+		return nil, err
+	}
+	comment, err := c.Service.PostCommentForPullRequest(c.Context, &PostCommentForPullRequestInput{
+		PullRequestID:  strconv.Itoa(c.Config.PullRequestID),
+		RepositoryName: c.Config.RepoName,
+		BeforeCommitID: target.DestinationCommit,
+		AfterCommitID:  target.SourceCommit,
+		Content:        c.CommentContent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.transform(comment), nil
+}
+
+func (c *CodeCommitProvider) UpdateComment(id int64) (*Comment, error) {
+	commentID, err := c.lookupCommentID(id)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := c.Service.UpdateComment(c.Context, commentID, c.CommentContent)
+	if err != nil {
+		return nil, err
+	}
+	return c.transform(comment), nil
+}
+
+// DeleteComment removes the content of the comment. CodeCommit keeps deleted comments as placeholder.
+func (c *CodeCommitProvider) DeleteComment(id int64) error {
+	commentID, err := c.lookupCommentID(id)
+	if err != nil {
+		return err
+	}
+	_, err = c.Service.DeleteCommentContent(c.Context, commentID)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("deleted comment with id %s\n", commentID)
+	return nil
+}
+
+// AuthenticatedUser returns the ARN of the AWS credentials. Comments of assumed roles are compared without session name.
+func (c *CodeCommitProvider) AuthenticatedUser() (string, error) {
+	identity, err := c.Identity.GetCallerIdentity(c.Context, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(identity.Arn), nil
+}
+
+func (c *CodeCommitProvider) SetCommentContent(content string) {
+	c.CommentContent = content
+}
+
+func (c *CodeCommitProvider) GetCommentContent() string {
+	return c.CommentContent
+}
+
+func (c *CodeCommitProvider) PostComment() (CommentOperation, error) {
+	return postComment(c, c.Config)
+}
+
+func (c *CodeCommitProvider) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(c, c.Config, groups)
+}
+
+func (c *CodeCommitProvider) ListComments() ([]Comment, error) {
+	var result []Comment
+	input := &GetCommentsForPullRequestInput{
+		PullRequestID:  strconv.Itoa(c.Config.PullRequestID),
+		RepositoryName: c.Config.RepoName,
+		MaxResults:     100,
+	}
+	for {
+		output, err := c.Service.GetCommentsForPullRequest(c.Context, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range output.CommentsForPullRequestData {
+			for i, comment := range data.Comments {
+				if comment.Deleted {
+					continue
+				}
+				result = append(result, *c.transform(&data.Comments[i]))
+			}
+		}
+		if output.NextToken == "" {
+			return result, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	codeCommitService      = "codecommit"
+	codeCommitTargetPrefix = "CodeCommit_20150413."
+)
+
+type ICodeCommitService interface {
+	GetPullRequest(ctx context.Context, pullRequestID string) (*CodeCommitPullRequest, error)
+	GetCommentsForPullRequest(ctx context.Context, input *GetCommentsForPullRequestInput) (*GetCommentsForPullRequestOutput, error)
+	PostCommentForPullRequest(ctx context.Context, input *PostCommentForPullRequestInput) (*CodeCommitComment, error)
+	UpdateComment(ctx context.Context, commentID string, content string) (*CodeCommitComment, error)
+	DeleteCommentContent(ctx context.Context, commentID string) (*CodeCommitComment, error)
+}
+
+type CodeCommitComment struct {
+	CommentID string `json:"commentId,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Deleted   bool   `json:"deleted,omitempty"`
+	// AuthorArn is the ARN of the IAM user or assumed role session which posted the comment
+	AuthorArn string `json:"authorArn,omitempty"`
+}
+
+type CodeCommitPullRequest struct {
+	PullRequestID      string                        `json:"pullRequestId,omitempty"`
+	PullRequestTargets []CodeCommitPullRequestTarget `json:"pullRequestTargets,omitempty"`
+}
+
+type CodeCommitPullRequestTarget struct {
+	RepositoryName    string `json:"repositoryName,omitempty"`
+	SourceCommit      string `json:"sourceCommit,omitempty"`
+	DestinationCommit string `json:"destinationCommit,omitempty"`
+}
+
+type GetCommentsForPullRequestInput struct {
+	PullRequestID  string `json:"pullRequestId"`
+	RepositoryName string `json:"repositoryName,omitempty"`
+	NextToken      string `json:"nextToken,omitempty"`
+	MaxResults     int    `json:"maxResults,omitempty"`
+}
+
+type GetCommentsForPullRequestOutput struct {
+	CommentsForPullRequestData []struct {
+		Comments []CodeCommitComment `json:"comments,omitempty"`
+	} `json:"commentsForPullRequestData,omitempty"`
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+type PostCommentForPullRequestInput struct {
+	PullRequestID  string `json:"pullRequestId"`
+	RepositoryName string `json:"repositoryName"`
+	BeforeCommitID string `json:"beforeCommitId"`
+	AfterCommitID  string `json:"afterCommitId"`
+	Content        string `json:"content"`
+}
+
+// CodeCommitClient calls the CodeCommit JSON API. Requests are signed by CodeCommitSigner.
+type CodeCommitClient struct {
+	*http.Client
+	BaseURL   *url.URL
+	UserAgent string
+}
+
+// CodeCommitSigner signs requests with AWS Signature Version 4
+type CodeCommitSigner struct {
+	Proxied     http.RoundTripper
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+	// now is used for the signing time and can be replaced in tests
+	now func() time.Time
+}
+
+func (signer CodeCommitSigner) RoundTrip(req *http.Request) (*http.Response, error) {
+	logrus.Debugf("Sending request to %s %s", req.URL.Host, req.Header.Get("X-Amz-Target"))
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = req.Body.Close(); err != nil {
+			return nil, err
+		}
+	}
+	credentials, err := signer.credentials.Retrieve(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	// the request must not be modified by a RoundTripper
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	payloadHash := sha256.Sum256(body)
+	err = signer.signer.SignHTTP(req.Context(), credentials, signed, hex.EncodeToString(payloadHash[:]), codeCommitService, signer.region, signer.now())
+	if err != nil {
+		return nil, err
+	}
+	return signer.Proxied.RoundTrip(signed)
+}
+
+// NewCodeCommitClient creates a client for the CodeCommit API of the region
+func NewCodeCommitClient(region string, credentials aws.CredentialsProvider) *CodeCommitClient {
+	httpClient := &http.Client{
+		Transport: NewRetryTransport(CodeCommitSigner{
+			Proxied:     http.DefaultTransport,
+			credentials: credentials,
+			signer:      v4.NewSigner(),
+			region:      region,
+			now:         time.Now,
+		}),
+	}
+	baseURL, _ := url.Parse(fmt.Sprintf("https://%s.%s.amazonaws.com/", codeCommitService, region))
+	return &CodeCommitClient{
+		Client:    httpClient,
+		BaseURL:   baseURL,
+		UserAgent: userAgent,
+	}
+}
+
+func (c *CodeCommitClient) GetPullRequest(ctx context.Context, pullRequestID string) (*CodeCommitPullRequest, error) {
+	input := map[string]string{"pullRequestId": pullRequestID}
+	output := &struct {
+		PullRequest *CodeCommitPullRequest `json:"pullRequest"`
+	}{}
+	err := c.Do(ctx, "GetPullRequest", input, output)
+	if err != nil {
+		return nil, err
+	}
+	return output.PullRequest, nil
+}
+
+func (c *CodeCommitClient) GetCommentsForPullRequest(ctx context.Context, input *GetCommentsForPullRequestInput) (*GetCommentsForPullRequestOutput, error) {
+	output := &GetCommentsForPullRequestOutput{}
+	err := c.Do(ctx, "GetCommentsForPullRequest", input, output)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (c *CodeCommitClient) PostCommentForPullRequest(ctx context.Context, input *PostCommentForPullRequestInput) (*CodeCommitComment, error) {
+	output := &struct {
+		Comment *CodeCommitComment `json:"comment"`
+	}{}
+	err := c.Do(ctx, "PostCommentForPullRequest", input, output)
+	if err != nil {
+		return nil, err
+	}
+	return output.Comment, nil
+}
+
+func (c *CodeCommitClient) UpdateComment(ctx context.Context, commentID string, content string) (*CodeCommitComment, error) {
+	input := map[string]string{"commentId": commentID, "content": content}
+	output := &struct {
+		Comment *CodeCommitComment `json:"comment"`
+	}{}
+	err := c.Do(ctx, "UpdateComment", input, output)
+	if err != nil {
+		return nil, err
+	}
+	return output.Comment, nil
+}
+
+func (c *CodeCommitClient) DeleteCommentContent(ctx context.Context, commentID string) (*CodeCommitComment, error) {
+	input := map[string]string{"commentId": commentID}
+	output := &struct {
+		Comment *CodeCommitComment `json:"comment"`
+	}{}
+	err := c.Do(ctx, "DeleteCommentContent", input, output)
+	if err != nil {
+		return nil, err
+	}
+	return output.Comment, nil
+}
+
+// Do calls the operation of the CodeCommit API and decodes the response into output
+func (c *CodeCommitClient) Do(ctx context.Context, operation string, input interface{}, output interface{}) error {
+	if ctx == nil {
+		return errNonNilContext
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", codeCommitTargetPrefix+operation)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.Warnf("failed to close response body: %v", err)
+		}
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("CodeCommit API Error: %s %s", resp.Status, respBody)
+	}
+	return json.Unmarshal(respBody, output)
+}
+
+// LoadAwsConfig reads the AWS configuration with the default credential chain of the AWS SDK,
+// e.g. environment variables, shared config and SSO profiles, web identity and the credentials of CodeBuild, ECS and EC2.
+// The credentials are retrieved once to fail early if none are found.
+func LoadAwsConfig(ctx context.Context, region string) (aws.Config, error) {
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return awsConfig, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	if _, err = awsConfig.Credentials.Retrieve(ctx); err != nil {
+		return awsConfig, fmt.Errorf("no AWS credentials found: %w", err)
+	}
+	return awsConfig, nil
+}
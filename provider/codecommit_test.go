@@ -0,0 +1,253 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockCodeCommitService struct {
+	comments []CodeCommitComment
+	posted   []*PostCommentForPullRequestInput
+	// pageSize splits the comments into several pages
+	pageSize int
+}
+
+func (m *MockCodeCommitService) GetPullRequest(ctx context.Context, pullRequestID string) (*CodeCommitPullRequest, error) {
+	return &CodeCommitPullRequest{
+		PullRequestID: pullRequestID,
+		PullRequestTargets: []CodeCommitPullRequestTarget{
+			{RepositoryName: "other-repo", SourceCommit: "other-source", DestinationCommit: "other-destination"},
+			{RepositoryName: "my-repo", SourceCommit: "source", DestinationCommit: "destination"},
+		},
+	}, nil
+}
+
+func (m *MockCodeCommitService) GetCommentsForPullRequest(ctx context.Context, input *GetCommentsForPullRequestInput) (*GetCommentsForPullRequestOutput, error) {
+	start := 0
+	if input.NextToken != "" {
+		_, _ = fmt.Sscanf(input.NextToken, "%d", &start)
+	}
+	end := len(m.comments)
+	if m.pageSize > 0 && start+m.pageSize < end {
+		end = start + m.pageSize
+	}
+	output := &GetCommentsForPullRequestOutput{}
+	output.CommentsForPullRequestData = append(output.CommentsForPullRequestData, struct {
+		Comments []CodeCommitComment `json:"comments,omitempty"`
+	}{Comments: m.comments[start:end]})
+	if end < len(m.comments) {
+		output.NextToken = fmt.Sprintf("%d", end)
+	}
+	return output, nil
+}
+
+func (m *MockCodeCommitService) PostCommentForPullRequest(ctx context.Context, input *PostCommentForPullRequestInput) (*CodeCommitComment, error) {
+	m.posted = append(m.posted, input)
+	comment := CodeCommitComment{CommentID: fmt.Sprintf("comment-%d", len(m.comments)+1), Content: input.Content, AuthorArn: codeCommitRoleArn}
+	m.comments = append(m.comments, comment)
+	return &comment, nil
+}
+
+func (m *MockCodeCommitService) UpdateComment(ctx context.Context, commentID string, content string) (*CodeCommitComment, error) {
+	for i, comment := range m.comments {
+		if comment.CommentID == commentID && !comment.Deleted {
+			m.comments[i].Content = content
+			return &m.comments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find comment with id %s", commentID)
+}
+
+func (m *MockCodeCommitService) DeleteCommentContent(ctx context.Context, commentID string) (*CodeCommitComment, error) {
+	for i, comment := range m.comments {
+		if comment.CommentID == commentID {
+			m.comments[i].Content = ""
+			m.comments[i].Deleted = true
+			return &m.comments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find comment to delete with id %s", commentID)
+}
+
+// codeCommitRoleArn is the assumed role session of the CodeBuild job running cdk-notifier
+const codeCommitRoleArn = "arn:aws:sts::123456789012:assumed-role/codebuild/AWSCodeBuild-2"
+
+type MockCodeCommitIdentityService struct {
+	arn string
+	err error
+}
+
+func (m *MockCodeCommitIdentityService) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(m.arn)}, nil
+}
+
+func defaultTestCodeCommitProvider(comments []CodeCommitComment) (*CodeCommitProvider, *MockCodeCommitService) {
+	mock := &MockCodeCommitService{comments: comments}
+	return &CodeCommitProvider{
+		Service:        mock,
+		Identity:       &MockCodeCommitIdentityService{arn: codeCommitRoleArn},
+		Context:        context.Background(),
+		Config:         config.NotifierConfig{TagID: defaultTag, DeleteComment: true, RepoName: "my-repo", PullRequestID: 7, AwsRegion: "eu-central-1"},
+		CommentContent: defaultTag,
+	}, mock
+}
+
+func TestCodeCommitProvider_PostComment(t *testing.T) {
+	initLogger()
+	client, mock := defaultTestCodeCommitProvider([]CodeCommitComment{
+		{CommentID: "deleted", Deleted: true},
+		{CommentID: "other", Content: "some other comment"},
+	})
+
+	// create comment on the commits of the repository
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Policy Changes detected"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.Len(t, mock.posted, 1)
+	assert.Equal(t, &PostCommentForPullRequestInput{
+		PullRequestID:  "7",
+		RepositoryName: "my-repo",
+		BeforeCommitID: "destination",
+		AfterCommitID:  "source",
+		Content:        client.CommentContent,
+	}, mock.posted[0])
+
+	// update existing comment
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Resources\nchanges"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Equal(t, client.CommentContent, mock.comments[2].Content)
+
+	// delete comment without changes
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	assert.Equal(t, "some other comment", comments[0].Body)
+}
+
+func TestCodeCommitProvider_AuthenticatedUser(t *testing.T) {
+	initLogger()
+	legacy := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	client, mock := defaultTestCodeCommitProvider([]CodeCommitComment{
+		{CommentID: "other-role", Content: legacy, AuthorArn: "arn:aws:sts::123456789012:assumed-role/developer/alice"},
+		{CommentID: "previous-build", Content: legacy, AuthorArn: "arn:aws:sts::123456789012:assumed-role/codebuild/AWSCodeBuild-1"},
+	})
+	client.Config.OnlyOwnComments = true
+	author, err := client.AuthenticatedUser()
+	assert.NoError(t, err)
+	assert.Equal(t, codeCommitRoleArn, author)
+
+	// the comment of a previous build with another session of the role is taken over
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nnew"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Empty(t, mock.posted)
+	assert.Equal(t, legacy, mock.comments[0].Content)
+	assert.Equal(t, client.CommentContent, mock.comments[1].Content)
+
+	client.Identity = &MockCodeCommitIdentityService{err: fmt.Errorf("ExpiredToken: The security token included in the request is expired")}
+	_, err = client.AuthenticatedUser()
+	assert.EqualError(t, err, "ExpiredToken: The security token included in the request is expired")
+}
+
+func TestCodeCommitProvider_ListComments(t *testing.T) {
+	var codeCommitComments []CodeCommitComment
+	for i := 1; i <= 5; i++ {
+		codeCommitComments = append(codeCommitComments, CodeCommitComment{CommentID: fmt.Sprintf("id-%d", i), Content: fmt.Sprintf("comment %d", i)})
+	}
+	client, mock := defaultTestCodeCommitProvider(codeCommitComments)
+	mock.pageSize = 2
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 5)
+	assert.Equal(t, codeCommitCommentID("id-5"), comments[4].Id)
+	assert.Equal(t, "https://eu-central-1.console.aws.amazon.com/codesuite/codecommit/repositories/my-repo/pull-requests/7/activity", comments[4].Link)
+	id, err := client.lookupCommentID(comments[4].Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "id-5", id)
+
+	_, err = client.UpdateComment(42)
+	assert.EqualError(t, err, "unknown CodeCommit comment with id 42")
+}
+
+func TestCodeCommitProvider_CreateCommentWithoutTarget(t *testing.T) {
+	client, _ := defaultTestCodeCommitProvider(nil)
+	client.Config.RepoName = "missing-repo"
+	_, err := client.CreateComment()
+	assert.EqualError(t, err, "pull request 7 has no target in repository missing-repo")
+	client.SetCommentContent("")
+	_, err = client.CreateComment()
+	assert.Equal(t, errContentEmpty, err)
+}
+
+func TestCodeCommitClient_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "CodeCommit_20150413.UpdateComment", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=key/20240102/eu-west-1/codecommit/aws4_request, SignedHeaders=content-length;content-type;host;x-amz-date;x-amz-target,")
+		body, _ := io.ReadAll(r.Body)
+		input := map[string]string{}
+		assert.NoError(t, json.Unmarshal(body, &input))
+		if input["commentId"] == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"CommentDoesNotExistException"}`))
+			return
+		}
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"comment":{"commentId":"%s","content":"%s"}}`, input["commentId"], input["content"])))
+	}))
+	defer server.Close()
+
+	client := NewCodeCommitClient("eu-west-1", credentials.NewStaticCredentialsProvider("key", "secret", ""))
+	retry := client.Transport.(*RetryTransport)
+	signer := retry.Proxied.(CodeCommitSigner)
+	signer.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	retry.Proxied = signer
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	comment, err := client.UpdateComment(context.Background(), "abc", "new content")
+	assert.NoError(t, err)
+	assert.Equal(t, &CodeCommitComment{CommentID: "abc", Content: "new content"}, comment)
+
+	_, err = client.UpdateComment(context.Background(), "missing", "new content")
+	assert.EqualError(t, err, `CodeCommit API Error: 400 Bad Request {"__type":"CommentDoesNotExistException"}`)
+}
+
+func TestLoadAwsConfig(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	awsConfig, err := LoadAwsConfig(context.Background(), "eu-west-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", awsConfig.Region)
+	retrieved, err := awsConfig.Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "key", retrieved.AccessKeyID)
+	assert.Equal(t, "secret", retrieved.SecretAccessKey)
+	assert.Equal(t, "session", retrieved.SessionToken)
+}
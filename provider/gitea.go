@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+// GiteaMaxCommentLength is the maximum number of chars posted to Gitea in a single comment.
+// Gitea does not limit comments, the GitHub limit keeps comments readable.
+const GiteaMaxCommentLength = 65536
+
+type GiteaProvider struct {
+	Service        IGiteaIssueService
+	Users          IGiteaUserService
+	Context        context.Context
+	Config         config.NotifierConfig
+	CommentContent string
+}
+
+func NewGiteaProvider(ctx context.Context, config config.NotifierConfig) (*GiteaProvider, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client, err := NewGiteaClient(config.GiteaUrl, config.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &GiteaProvider{
+		Service: client,
+		Users:   client,
+		Context: ctx,
+		Config:  config,
+	}, nil
+}
+
+func (c *GiteaComment) transform() *Comment {
+	if c == nil {
+		return &Comment{}
+	}
+	comment := &Comment{
+		Id:   c.ID,
+		Body: c.Body,
+		Link: c.HTMLURL,
+	}
+	if c.User != nil {
+		comment.Author = c.User.Login
+	}
+	return comment
+}
+
+func (g *GiteaProvider) CreateComment() (*Comment, error) {
+	if g.CommentContent == "" {
+		return nil, errContentEmpty
+	}
+	comment, err := g.Service.CreateComment(g.Context, g.Config.RepoOwner, g.Config.RepoName, g.Config.PullRequestID, &GiteaComment{Body: g.CommentContent})
+	if err != nil {
+		return nil, err
+	}
+	return comment.transform(), nil
+}
+
+func (g *GiteaProvider) UpdateComment(id int64) (*Comment, error) {
+	comment, err := g.Service.EditComment(g.Context, g.Config.RepoOwner, g.Config.RepoName, id, &GiteaComment{Body: g.CommentContent})
+	if err != nil {
+		return nil, err
+	}
+	return comment.transform(), nil
+}
+
+func (g *GiteaProvider) DeleteComment(id int64) error {
+	err := g.Service.DeleteComment(g.Context, g.Config.RepoOwner, g.Config.RepoName, id)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("deleted comment with id %d\n", id)
+	return nil
+}
+
+// AuthenticatedUser returns the login of the token
+func (g *GiteaProvider) AuthenticatedUser() (string, error) {
+	user, err := g.Users.GetCurrentUser(g.Context)
+	if err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+func (g *GiteaProvider) SetCommentContent(content string) {
+	g.CommentContent = content
+}
+
+func (g *GiteaProvider) GetCommentContent() string {
+	return g.CommentContent
+}
+
+func (g *GiteaProvider) PostComment() (CommentOperation, error) {
+	return postComment(g, g.Config)
+}
+
+func (g *GiteaProvider) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(g, g.Config, groups)
+}
+
+func (g *GiteaProvider) ListComments() ([]Comment, error) {
+	comments, err := g.Service.ListComments(g.Context, g.Config.RepoOwner, g.Config.RepoName, g.Config.PullRequestID)
+	if err != nil {
+		return nil, err
+	}
+	var result []Comment
+	for _, comment := range comments {
+		result = append(result, *comment.transform())
+	}
+	return result, nil
+}
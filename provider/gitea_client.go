@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	giteaApiPath = "api/v1/"
+)
+
+type IGiteaIssueService interface {
+	ListComments(ctx context.Context, owner string, repo string, index int) ([]GiteaComment, error)
+	CreateComment(ctx context.Context, owner string, repo string, index int, comment *GiteaComment) (*GiteaComment, error)
+	EditComment(ctx context.Context, owner string, repo string, commentID int64, comment *GiteaComment) (*GiteaComment, error)
+	DeleteComment(ctx context.Context, owner string, repo string, commentID int64) error
+}
+
+type IGiteaUserService interface {
+	GetCurrentUser(ctx context.Context) (*GiteaUser, error)
+}
+
+type GiteaComment struct {
+	ID      int64      `json:"id,omitempty"`
+	Body    string     `json:"body"`
+	HTMLURL string     `json:"html_url,omitempty"`
+	User    *GiteaUser `json:"user,omitempty"`
+}
+
+type GiteaUser struct {
+	Login string `json:"login"`
+}
+
+// GiteaProxy authenticates requests with an access token
+type GiteaProxy struct {
+	Proxied http.RoundTripper
+	token   string
+}
+
+func (proxy GiteaProxy) RoundTrip(req *http.Request) (*http.Response, error) {
+	logrus.Debugf("Sending request to %s%s", req.URL.Host, req.URL.Path)
+	// the request must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", proxy.token))
+	req.Header.Set("Accept", "application/json")
+	return proxy.Proxied.RoundTrip(req)
+}
+
+// GiteaClient calls the issue comments API of Gitea and Forgejo. Pull requests are issues in Gitea.
+type GiteaClient struct {
+	*http.Client
+	// BaseURL is the API url e.g. https://codeberg.org/api/v1/
+	BaseURL   *url.URL
+	UserAgent string
+}
+
+// NewGiteaClient creates a client for the Gitea url e.g. https://codeberg.org
+func NewGiteaClient(giteaUrl string, token string) (*GiteaClient, error) {
+	baseURL, err := url.Parse(withTrailingSlash(giteaUrl) + giteaApiPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Gitea url '%s': %w", giteaUrl, err)
+	}
+	httpClient := &http.Client{
+		Transport: NewRetryTransport(GiteaProxy{
+			http.DefaultTransport, token,
+		}),
+	}
+	return &GiteaClient{
+		Client:    httpClient,
+		BaseURL:   baseURL,
+		UserAgent: userAgent,
+	}, nil
+}
+
+func (c *GiteaClient) ListComments(ctx context.Context, owner string, repo string, index int) ([]GiteaComment, error) {
+	u := fmt.Sprintf("repos/%s/%s/issues/%d/comments", url.PathEscape(owner), url.PathEscape(repo), index)
+	var comments []GiteaComment
+	err := c.Do(ctx, http.MethodGet, u, nil, &comments)
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (c *GiteaClient) CreateComment(ctx context.Context, owner string, repo string, index int, comment *GiteaComment) (*GiteaComment, error) {
+	u := fmt.Sprintf("repos/%s/%s/issues/%d/comments", url.PathEscape(owner), url.PathEscape(repo), index)
+	commentResp := &GiteaComment{}
+	err := c.Do(ctx, http.MethodPost, u, comment, commentResp)
+	if err != nil {
+		return nil, err
+	}
+	return commentResp, nil
+}
+
+func (c *GiteaClient) EditComment(ctx context.Context, owner string, repo string, commentID int64, comment *GiteaComment) (*GiteaComment, error) {
+	u := fmt.Sprintf("repos/%s/%s/issues/comments/%d", url.PathEscape(owner), url.PathEscape(repo), commentID)
+	commentResp := &GiteaComment{}
+	err := c.Do(ctx, http.MethodPatch, u, comment, commentResp)
+	if err != nil {
+		return nil, err
+	}
+	return commentResp, nil
+}
+
+func (c *GiteaClient) GetCurrentUser(ctx context.Context) (*GiteaUser, error) {
+	user := &GiteaUser{}
+	err := c.Do(ctx, http.MethodGet, "user", nil, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *GiteaClient) DeleteComment(ctx context.Context, owner string, repo string, commentID int64) error {
+	u := fmt.Sprintf("repos/%s/%s/issues/comments/%d", url.PathEscape(owner), url.PathEscape(repo), commentID)
+	return c.Do(ctx, http.MethodDelete, u, nil, nil)
+}
+
+// Do sends the request to the path relative to BaseURL and decodes the response into v if not nil
+func (c *GiteaClient) Do(ctx context.Context, method string, path string, body interface{}, v interface{}) error {
+	if ctx == nil {
+		return errNonNilContext
+	}
+	u, err := c.BaseURL.Parse(path)
+	if err != nil {
+		return err
+	}
+	var buf io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		buf = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.Warnf("failed to close response body: %v", err)
+		}
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Gitea API Error: %s %s", resp.Status, respBody)
+	}
+	if v == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, v)
+}
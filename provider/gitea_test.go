@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGitea implements the issue comments API of Gitea
+type fakeGitea struct {
+	sync.Mutex
+	url      string
+	comments []*GiteaComment
+	nextID   int64
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	if r.Header.Get("Authorization") != "token some-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	base := "/api/v1/repos/my-org/my-repo/issues/"
+	path := strings.TrimPrefix(r.URL.Path, base)
+	encoder := json.NewEncoder(w)
+	switch {
+	case r.Method == http.MethodGet && path == "3/comments":
+		_ = encoder.Encode(f.comments)
+	case r.Method == http.MethodPost && path == "3/comments":
+		comment := &GiteaComment{}
+		_ = json.NewDecoder(r.Body).Decode(comment)
+		f.nextID++
+		comment.ID = f.nextID
+		comment.User = &GiteaUser{Login: "cdk-bot"}
+		comment.HTMLURL = fmt.Sprintf("%s/my-org/my-repo/pulls/3#issuecomment-%d", f.url, comment.ID)
+		f.comments = append(f.comments, comment)
+		w.WriteHeader(http.StatusCreated)
+		_ = encoder.Encode(comment)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/user":
+		_ = encoder.Encode(GiteaUser{Login: "cdk-bot"})
+	case strings.HasPrefix(path, "comments/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "comments/"), 10, 64)
+		for i, comment := range f.comments {
+			if comment.ID != id {
+				continue
+			}
+			switch r.Method {
+			case http.MethodPatch:
+				update := &GiteaComment{}
+				_ = json.NewDecoder(r.Body).Decode(update)
+				comment.Body = update.Body
+				_ = encoder.Encode(comment)
+			case http.MethodDelete:
+				f.comments = append(f.comments[:i], f.comments[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func defaultTestGiteaProvider(t *testing.T, fake *fakeGitea) *GiteaProvider {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+	client, err := NewGiteaProvider(context.Background(), config.NotifierConfig{
+		TagID:         defaultTag,
+		DeleteComment: true,
+		Token:         "some-token",
+		RepoOwner:     "my-org",
+		RepoName:      "my-repo",
+		PullRequestID: 3,
+		GiteaUrl:      server.URL,
+	})
+	assert.NoError(t, err)
+	return client
+}
+
+func TestGiteaProvider_PostComment(t *testing.T) {
+	initLogger()
+	fake := &fakeGitea{}
+	fake.nextID++
+	fake.comments = append(fake.comments, &GiteaComment{ID: fake.nextID, Body: "review comment"})
+	client := defaultTestGiteaProvider(t, fake)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Policy Changes detected"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "there are Resources\nchanges"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Equal(t, client.CommentContent, comments[1].Body)
+	assert.Equal(t, fmt.Sprintf("%s/my-org/my-repo/pulls/3#issuecomment-2", fake.url), comments[1].Link)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	assert.Len(t, fake.comments, 1)
+}
+
+func TestGiteaProvider_Errors(t *testing.T) {
+	initLogger()
+	client := defaultTestGiteaProvider(t, &fakeGitea{})
+	_, err := client.UpdateComment(42)
+	assert.EqualError(t, err, "Gitea API Error: 404 Not Found ")
+
+	client.SetCommentContent("")
+	_, err = client.CreateComment()
+	assert.ErrorIs(t, err, errContentEmpty)
+
+	client.Service.(*GiteaClient).Transport = GiteaProxy{http.DefaultTransport, "wrong-token"}
+	_, err = client.ListComments()
+	assert.EqualError(t, err, "Gitea API Error: 401 Unauthorized ")
+}
+
+func TestGiteaProvider_OnlyOwnComments(t *testing.T) {
+	initLogger()
+	body := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	fake := &fakeGitea{nextID: 1, comments: []*GiteaComment{{ID: 1, Body: body, User: &GiteaUser{Login: "alice"}}}}
+	client := defaultTestGiteaProvider(t, fake)
+	client.Config.OnlyOwnComments = true
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nnew"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.Equal(t, body, fake.comments[0].Body)
+
+	client.SetCommentContent(fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nnewer"))
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, fake.comments, 2)
+	assert.Equal(t, "cdk-bot", fake.comments[1].User.Login)
+}
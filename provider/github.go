@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
 
 	"github.com/google/go-github/v88/github"
@@ -22,9 +23,22 @@ type GithubIssuesService interface {
 	CreateComment(ctx context.Context, owner string, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
 }
 
+// GithubLabelsService interface for required GitHub label actions with API
+type GithubLabelsService interface {
+	AddLabelsToIssue(ctx context.Context, owner string, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
+}
+
+// GithubUsersService interface for required GitHub user actions with API
+type GithubUsersService interface {
+	Get(ctx context.Context, user string) (*github.User, *github.Response, error)
+}
+
 // GithubClient GitHub client configuration
 type GithubClient struct {
 	Issues         GithubIssuesService
+	Labels         GithubLabelsService
+	Users          GithubUsersService
+	GraphQL        GithubGraphQLService
 	Context        context.Context
 	Client         *github.Client
 	Config         config.NotifierConfig
@@ -50,11 +64,15 @@ func NewGithubClient(ctx context.Context, cfg config.NotifierConfig) (*GithubCli
 		}
 		c.Client, err = github.NewClient(
 			github.WithAuthToken(cfg.Token),
+			github.WithTransport(NewRetryTransport(http.DefaultTransport)),
 			github.WithEnterpriseURLs(githubHost, githubHost),
 		)
 		logrus.Infof("Using GitHub Enterprise Client: %s", githubHost)
 	default:
-		c.Client, err = github.NewClient(github.WithAuthToken(cfg.Token))
+		c.Client, err = github.NewClient(
+			github.WithAuthToken(cfg.Token),
+			github.WithTransport(NewRetryTransport(http.DefaultTransport)),
+		)
 	}
 
 	if err != nil {
@@ -63,6 +81,15 @@ func NewGithubClient(ctx context.Context, cfg config.NotifierConfig) (*GithubCli
 	if c.Issues == nil {
 		c.Issues = c.Client.Issues
 	}
+	if c.Labels == nil {
+		c.Labels = c.Client.Issues
+	}
+	if c.Users == nil {
+		c.Users = c.Client.Users
+	}
+	if c.GraphQL == nil {
+		c.GraphQL = &githubGraphQL{client: c.Client}
+	}
 	return c, nil
 }
 
@@ -77,6 +104,10 @@ func transform(i *github.IssueComment) *Comment {
 	if i.HTMLURL != nil {
 		comment.Link = *i.HTMLURL
 	}
+	if i.NodeID != nil {
+		comment.Ref = *i.NodeID
+	}
+	comment.Author = i.GetUser().GetLogin()
 	return comment
 }
 
@@ -115,21 +146,69 @@ func (gc *GithubClient) PostComment() (CommentOperation, error) {
 	return postComment(gc, gc.Config)
 }
 
+func (gc *GithubClient) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(gc, gc.Config, groups)
+}
+
+func (gc *GithubClient) AddLabels(labels []string) error {
+	_, _, err := gc.Labels.AddLabelsToIssue(gc.Context, gc.Config.RepoOwner, gc.Config.RepoName, gc.Config.PullRequestID, labels)
+	return err
+}
+
+// AuthenticatedUser returns the login of the token. The GITHUB_TOKEN of GitHub Actions has no access to its user.
+func (gc *GithubClient) AuthenticatedUser() (string, error) {
+	user, _, err := gc.Users.Get(gc.Context, "")
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
 func (gc *GithubClient) SetCommentContent(content string) {
 	gc.CommentContent = content
 }
 
+// ListComments returns all comments of the pull request newest first
 func (gc *GithubClient) ListComments() ([]Comment, error) {
+	return listComments(gc.walkComments)
+}
+
+func (gc *GithubClient) SearchComment(match func(comment Comment) bool) (*Comment, error) {
+	return searchComment(gc.walkComments, match)
+}
+
+// walkComments visits the comments newest first. GitHub lists issue comments oldest first
+// so the pages are read from the last page backwards.
+// With HideOutdated the comments are listed with GraphQL to detect hidden comments.
+func (gc *GithubClient) walkComments(visit func(comment Comment) bool) error {
+	if gc.Config.HideOutdated {
+		return gc.walkCommentsGraphQL(visit)
+	}
 	opt := &github.IssueListCommentsOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
-	comments, _, err := gc.Issues.ListComments(gc.Context, gc.Config.RepoOwner, gc.Config.RepoName, gc.Config.PullRequestID, opt)
+	firstPage, resp, err := gc.Issues.ListComments(gc.Context, gc.Config.RepoOwner, gc.Config.RepoName, gc.Config.PullRequestID, opt)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var result []Comment
-	for _, comment := range comments {
-		result = append(result, *transform(comment))
+	lastPage := 1
+	if resp != nil && resp.LastPage > lastPage {
+		lastPage = resp.LastPage
+	}
+	for page := lastPage; page >= 1; page-- {
+		comments := firstPage
+		if page > 1 {
+			opt.Page = page
+			comments, _, err = gc.Issues.ListComments(gc.Context, gc.Config.RepoOwner, gc.Config.RepoName, gc.Config.PullRequestID, opt)
+			if err != nil {
+				return err
+			}
+		}
+		for i := len(comments) - 1; i >= 0; i-- {
+			if !visit(*transform(comments[i])) {
+				return nil
+			}
+		}
 	}
-	return result, nil
+	return nil
 }
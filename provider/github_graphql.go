@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v88/github"
+)
+
+// GithubGraphQLService interface for required GitHub GraphQL queries and mutations
+type GithubGraphQLService interface {
+	// Query sends the query with its variables and decodes the data of the response into v
+	Query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error
+}
+
+// githubGraphQL sends GraphQL requests with the authentication and transport of the REST client
+type githubGraphQL struct {
+	client *github.Client
+}
+
+type githubGraphQLResponse struct {
+	Data   interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
+// endpoint returns the GraphQL url. GitHub Enterprise serves GraphQL at /api/graphql next to the REST API at /api/v3/.
+func (g *githubGraphQL) endpoint() string {
+	baseURL := g.client.BaseURL()
+	if strings.HasSuffix(baseURL, "/api/v3/") {
+		return strings.TrimSuffix(baseURL, "v3/") + "graphql"
+	}
+	return baseURL + "graphql"
+}
+
+func (g *githubGraphQL) Query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	body := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+	req, err := g.client.NewRequest(ctx, http.MethodPost, g.endpoint(), body)
+	if err != nil {
+		return err
+	}
+	response := &githubGraphQLResponse{Data: v}
+	_, err = g.client.Do(req, response)
+	if err != nil {
+		return err
+	}
+	// GraphQL reports errors with status 200
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("GitHub GraphQL Error: %s", response.Errors[0].Message)
+	}
+	return nil
+}
+
+const githubPullRequestCommentsQuery = `query($owner: String!, $repo: String!, $number: Int!, $before: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      comments(last: 100, before: $before) {
+        nodes { id databaseId body url isMinimized author { login } }
+        pageInfo { hasPreviousPage startCursor }
+      }
+    }
+  }
+}`
+
+const githubMinimizeCommentMutation = `mutation($id: ID!) {
+  minimizeComment(input: {subjectId: $id, classifier: OUTDATED}) {
+    minimizedComment { isMinimized }
+  }
+}`
+
+type githubGraphQLComment struct {
+	ID          string `json:"id"`
+	DatabaseID  int64  `json:"databaseId"`
+	Body        string `json:"body"`
+	URL         string `json:"url"`
+	IsMinimized bool   `json:"isMinimized"`
+	Author      struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+type githubPullRequestComments struct {
+	Repository struct {
+		PullRequest struct {
+			Comments struct {
+				Nodes    []githubGraphQLComment `json:"nodes"`
+				PageInfo struct {
+					HasPreviousPage bool   `json:"hasPreviousPage"`
+					StartCursor     string `json:"startCursor"`
+				} `json:"pageInfo"`
+			} `json:"comments"`
+		} `json:"pullRequest"`
+	} `json:"repository"`
+}
+
+// walkCommentsGraphQL visits the comments newest first including their minimized state which is not part of the REST API
+func (gc *GithubClient) walkCommentsGraphQL(visit func(comment Comment) bool) error {
+	variables := map[string]interface{}{
+		"owner":  gc.Config.RepoOwner,
+		"repo":   gc.Config.RepoName,
+		"number": gc.Config.PullRequestID,
+		"before": nil,
+	}
+	for {
+		result := &githubPullRequestComments{}
+		err := gc.GraphQL.Query(gc.Context, githubPullRequestCommentsQuery, variables, result)
+		if err != nil {
+			return err
+		}
+		comments := result.Repository.PullRequest.Comments
+		// the comments of a page are ordered oldest first
+		for i := len(comments.Nodes) - 1; i >= 0; i-- {
+			node := comments.Nodes[i]
+			comment := Comment{Id: node.DatabaseID, Body: node.Body, Link: node.URL, Ref: node.ID, Hidden: node.IsMinimized, Author: node.Author.Login}
+			if !visit(comment) {
+				return nil
+			}
+		}
+		if !comments.PageInfo.HasPreviousPage {
+			return nil
+		}
+		variables["before"] = comments.PageInfo.StartCursor
+	}
+}
+
+// MinimizeComment hides the comment as outdated
+func (gc *GithubClient) MinimizeComment(comment Comment) error {
+	if comment.Ref == "" {
+		return fmt.Errorf("unable to hide comment %d without node id", comment.Id)
+	}
+	return gc.GraphQL.Query(gc.Context, githubMinimizeCommentMutation, map[string]interface{}{"id": comment.Ref}, nil)
+}
@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v88/github"
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockGraphQLService serves the pull request comments query and the minimize mutation
+type MockGraphQLService struct {
+	sync.RWMutex
+	comments  []githubGraphQLComment
+	pageSize  int
+	listCalls int
+}
+
+func (m *MockGraphQLService) Query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+	switch query {
+	case githubMinimizeCommentMutation:
+		for i := range m.comments {
+			if m.comments[i].ID == variables["id"] {
+				m.comments[i].IsMinimized = true
+				return nil
+			}
+		}
+		return fmt.Errorf("GitHub GraphQL Error: Could not resolve to a node with the global id of '%s'", variables["id"])
+	case githubPullRequestCommentsQuery:
+		m.listCalls++
+		// the cursor is the index of the first comment of the previous page
+		end := len(m.comments)
+		if before, ok := variables["before"].(string); ok {
+			end, _ = strconv.Atoi(before)
+		}
+		start := max(end-m.pageSize, 0)
+		result := v.(*githubPullRequestComments)
+		comments := &result.Repository.PullRequest.Comments
+		comments.Nodes = append([]githubGraphQLComment{}, m.comments[start:end]...)
+		for i := range comments.Nodes {
+			if comments.Nodes[i].Author.Login == "" {
+				comments.Nodes[i].Author.Login = fakeAuthor
+			}
+		}
+		comments.PageInfo.HasPreviousPage = start > 0
+		comments.PageInfo.StartCursor = strconv.Itoa(start)
+		return nil
+	}
+	return fmt.Errorf("unexpected query %s", query)
+}
+
+func defaultTestGithubGraphQLProvider(comments []githubGraphQLComment) (*GithubClient, *MockGraphQLService) {
+	mock := &MockGraphQLService{comments: comments, pageSize: 2}
+	client := defaultTestGithubProvider(nil)
+	client.GraphQL = mock
+	client.Config.HideOutdated = true
+	client.Config.CommentAuthor = fakeAuthor
+	return client, mock
+}
+
+func TestGithubClient_HideOutdated(t *testing.T) {
+	initLogger()
+	client, mock := defaultTestGithubGraphQLProvider([]githubGraphQLComment{
+		{ID: "IC_1", DatabaseID: 1, Body: fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")},
+		{ID: "IC_2", DatabaseID: 2, Body: "review comment"},
+		{ID: "IC_3", DatabaseID: 3, Body: "another review comment"},
+	})
+	comment, err := findComment(client, client.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, &Comment{Id: 1, Body: mock.comments[0].Body, Ref: "IC_1", Author: fakeAuthor}, comment)
+	assert.Equal(t, 2, mock.listCalls, "expect the oldest comment on the second page")
+
+	err = client.MinimizeComment(*comment)
+	assert.NoError(t, err)
+	assert.True(t, mock.comments[0].IsMinimized)
+
+	// hidden comments are skipped
+	comment, err = findComment(client, client.Config)
+	assert.NoError(t, err)
+	assert.Nil(t, comment)
+
+	err = client.MinimizeComment(Comment{Id: 4})
+	assert.EqualError(t, err, "unable to hide comment 4 without node id")
+}
+
+func TestGithubClient_ListCommentsGraphQL(t *testing.T) {
+	initLogger()
+	client, mock := defaultTestGithubGraphQLProvider([]githubGraphQLComment{
+		{ID: "IC_1", DatabaseID: 1, Body: "first", IsMinimized: true},
+		{ID: "IC_2", DatabaseID: 2, Body: "second", URL: "https://github.com/my-org/my-repo/pull/3#issuecomment-2"},
+		{ID: "IC_3", DatabaseID: 3, Body: "third"},
+	})
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Comment{
+		{Id: 3, Body: "third", Ref: "IC_3", Author: fakeAuthor},
+		{Id: 2, Body: "second", Ref: "IC_2", Link: "https://github.com/my-org/my-repo/pull/3#issuecomment-2", Author: fakeAuthor},
+		{Id: 1, Body: "first", Ref: "IC_1", Hidden: true, Author: fakeAuthor},
+	}, comments)
+	assert.Equal(t, 2, mock.listCalls)
+}
+
+func TestGithubGraphQL_Query(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		request := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request["variables"].(map[string]interface{})["id"] == "IC_1" {
+			_, _ = w.Write([]byte(`{"data": {"minimizeComment": {"minimizedComment": {"isMinimized": true}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "Could not resolve to a node"}]}`))
+	}))
+	defer server.Close()
+
+	// GitHub Enterprise serves GraphQL next to the REST API
+	enterprise, err := github.NewClient(github.WithEnterpriseURLs(server.URL, server.URL))
+	assert.NoError(t, err)
+	graphQL := &githubGraphQL{client: enterprise}
+	assert.Equal(t, server.URL+"/api/graphql", graphQL.endpoint())
+	err = graphQL.Query(context.Background(), githubMinimizeCommentMutation, map[string]interface{}{"id": "IC_1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/graphql", path)
+
+	err = graphQL.Query(context.Background(), githubMinimizeCommentMutation, map[string]interface{}{"id": "IC_2"}, nil)
+	assert.EqualError(t, err, "GitHub GraphQL Error: Could not resolve to a node")
+
+	client, err := NewGithubClient(context.Background(), config.NotifierConfig{Token: "some-token", Vcs: config.VcsGithub})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/graphql", client.GraphQL.(*githubGraphQL).endpoint())
+}
+
+func TestGithubClient_FindCommentWithMarker(t *testing.T) {
+	initLogger()
+	body := CommentMarker{TagID: defaultTag}.Embed("### Custom template\nResources\nold")
+	client, mock := defaultTestGithubGraphQLProvider([]githubGraphQLComment{
+		{ID: "IC_1", DatabaseID: 1, Body: body},
+		{ID: "IC_2", DatabaseID: 2, Body: fmt.Sprintf("> %s %s\nWhy?", HeaderPrefix, defaultTag)},
+		{ID: "IC_3", DatabaseID: 3, Body: "review comment"},
+	})
+	comment, err := findComment(client, client.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, &Comment{Id: 1, Body: body, Ref: "IC_1", Author: fakeAuthor}, comment, "expect the marker to win over the quoted header")
+
+	mock.comments = mock.comments[1:]
+	comment, err = findComment(client, client.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), comment.Id, "expect the header fallback without marker comments")
+}
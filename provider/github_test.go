@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -21,7 +22,8 @@ const defaultTag = "test-tag"
 
 type MockPullRequestService struct {
 	sync.RWMutex
-	comments []*github.IssueComment
+	comments  []*github.IssueComment
+	listCalls int
 }
 
 func (m *MockPullRequestService) DeleteComment(ctx context.Context, owner string, repo string, commentID int64) (*github.Response, error) {
@@ -65,14 +67,38 @@ func (m *MockPullRequestService) CreateComment(ctx context.Context, owner string
 	return comment, nil, nil
 }
 
+// ListComments returns the comments oldest first paginated like GitHub
 func (m *MockPullRequestService) ListComments(ctx context.Context, owner string, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
-	return m.comments, nil, nil
+	m.listCalls++
+	if opts == nil || opts.PerPage == 0 || len(m.comments) <= opts.PerPage {
+		return withFakeGithubUser(m.comments), nil, nil
+	}
+	page := max(opts.Page, 1)
+	lastPage := (len(m.comments) + opts.PerPage - 1) / opts.PerPage
+	start := min((page-1)*opts.PerPage, len(m.comments))
+	end := min(page*opts.PerPage, len(m.comments))
+	return withFakeGithubUser(m.comments[start:end]), &github.Response{LastPage: lastPage}, nil
+}
+
+// withFakeGithubUser returns the comments with the user of the token as author if they have none
+func withFakeGithubUser(comments []*github.IssueComment) []*github.IssueComment {
+	var result []*github.IssueComment
+	for _, comment := range comments {
+		if comment != nil && comment.User == nil {
+			withUser := *comment
+			withUser.User = &github.User{Login: github.Ptr(fakeAuthor)}
+			comment = &withUser
+		}
+		result = append(result, comment)
+	}
+	return result
 }
 
 func defaultTestGithubProvider(comments []*github.IssueComment) *GithubClient {
 	mock := &MockPullRequestService{comments: comments}
 	return &GithubClient{
 		Issues:         mock,
+		Users:          &MockUsersService{login: fakeAuthor},
 		Context:        context.Background(),
 		Config:         config.NotifierConfig{TagID: "test-tag", DeleteComment: true},
 		CommentContent: defaultTag,
@@ -163,6 +189,40 @@ func TestGithubClient_ListComments(t *testing.T) {
 
 }
 
+func TestGithubClient_ListCommentsPaginated(t *testing.T) {
+	initLogger()
+	var commentsMock []*github.IssueComment
+	for i := 1; i <= 250; i++ {
+		commentsMock = append(commentsMock, &github.IssueComment{
+			ID:   github.Ptr(int64(i)),
+			Body: github.Ptr(fmt.Sprintf("%s example-%d", HeaderPrefix, i)),
+		})
+	}
+	client := defaultTestGithubProvider(commentsMock)
+	mock := client.Issues.(*MockPullRequestService)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 250)
+	assert.Equal(t, int64(250), comments[0].Id, "expect newest comment first")
+	assert.Equal(t, int64(1), comments[249].Id)
+	assert.Equal(t, 3, mock.listCalls)
+
+	// the search stops at the newest page with a matching comment
+	mock.listCalls = 0
+	comment, err := client.SearchComment(func(comment Comment) bool { return comment.Body == fmt.Sprintf("%s example-%d", HeaderPrefix, 240) })
+	assert.NoError(t, err)
+	assert.Equal(t, int64(240), comment.Id)
+	assert.Equal(t, 2, mock.listCalls)
+
+	// a comment on the first page is found as well
+	client.Config.TagID = "example-1"
+	client.SetCommentContent(fmt.Sprintf("%s example-1\n%s", HeaderPrefix, "there are Policy Changes detected"))
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UPDATED, operation)
+	assert.Len(t, mock.comments, 250)
+}
+
 type HasChangesTest struct {
 	input            string
 	expectHasChanges bool
@@ -199,3 +259,61 @@ func readFile(path string) string {
 	}
 	return string(content)
 }
+
+type MockLabelsService struct {
+	labels []string
+}
+
+func (m *MockLabelsService) AddLabelsToIssue(ctx context.Context, owner string, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	m.labels = append(m.labels, labels...)
+	return nil, nil, nil
+}
+
+func TestGithubClient_AddLabels(t *testing.T) {
+	mock := &MockLabelsService{labels: []string{"existing"}}
+	client := defaultTestGithubProvider(nil)
+	client.Labels = mock
+	var labeler Labeler = client
+	err := labeler.AddLabels([]string{"database", "iam"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"existing", "database", "iam"}, mock.labels)
+}
+
+type MockUsersService struct {
+	login string
+	err   error
+}
+
+func (m *MockUsersService) Get(ctx context.Context, user string) (*github.User, *github.Response, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return &github.User{Login: &m.login}, nil, nil
+}
+
+func TestGithubClient_OnlyOwnComments(t *testing.T) {
+	initLogger()
+	body := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	client := defaultTestGithubProvider([]*github.IssueComment{
+		{ID: github.Ptr(int64(1)), Body: &body, User: &github.User{Login: github.Ptr("alice")}},
+		{ID: github.Ptr(int64(2)), Body: &body, User: &github.User{Login: github.Ptr("cdk-bot")}},
+	})
+	client.Users = &MockUsersService{login: "cdk-bot"}
+	client.Config.OnlyOwnComments = true
+
+	user, err := client.AuthenticatedUser()
+	assert.NoError(t, err)
+	assert.Equal(t, "cdk-bot", user)
+
+	client.CommentContent = fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes")
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Comment{{Id: 1, Body: body, Author: "alice"}}, comments)
+
+	client.Users = &MockUsersService{err: errors.New("403 Resource not accessible by integration")}
+	_, err = client.PostComment()
+	assert.ErrorContains(t, err, "Set comment-author")
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/karlderkaefer/cdk-notifier/config"
 	gitlab "gitlab.com/gitlab-org/api/client-go/v2"
@@ -25,15 +26,35 @@ type GitlabProjectsService interface {
 	GetProject(pid interface{}, opt *gitlab.GetProjectOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Project, *gitlab.Response, error)
 }
 
+// MergeRequestsService interface for required Gitlab merge request actions with API
+type GitlabMergeRequestsService interface {
+	UpdateMergeRequest(pid interface{}, mergeRequest int64, opt *gitlab.UpdateMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+}
+
+// GitlabDiscussionsService interface for required Gitlab discussion actions with API
+type GitlabDiscussionsService interface {
+	ListMergeRequestDiscussions(pid interface{}, mergeRequest int64, opt *gitlab.ListMergeRequestDiscussionsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Discussion, *gitlab.Response, error)
+	CreateMergeRequestDiscussion(pid interface{}, mergeRequest int64, opt *gitlab.CreateMergeRequestDiscussionOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Discussion, *gitlab.Response, error)
+	ResolveMergeRequestDiscussion(pid interface{}, mergeRequest int64, discussion string, opt *gitlab.ResolveMergeRequestDiscussionOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Discussion, *gitlab.Response, error)
+}
+
+// GitlabUsersService interface for required Gitlab user actions with API
+type GitlabUsersService interface {
+	CurrentUser(options ...gitlab.RequestOptionFunc) (*gitlab.User, *gitlab.Response, error)
+}
+
 // GitlabClient GitLab client configuration
 type GitlabClient struct {
-	Notes       GitlabNotesService
-	Projects    GitlabProjectsService
-	Context     context.Context
-	Client      *gitlab.Client
-	Config      config.NotifierConfig
-	ProjectId   string
-	NoteContent string
+	Notes         GitlabNotesService
+	Discussions   GitlabDiscussionsService
+	Projects      GitlabProjectsService
+	MergeRequests GitlabMergeRequestsService
+	Users         GitlabUsersService
+	Context       context.Context
+	Client        *gitlab.Client
+	Config        config.NotifierConfig
+	ProjectId     string
+	NoteContent   string
 }
 
 func NewGitlabClient(ctx context.Context, config config.NotifierConfig) *GitlabClient {
@@ -45,16 +66,34 @@ func NewGitlabClient(ctx context.Context, config config.NotifierConfig) *GitlabC
 		c.Context = context.Background()
 	}
 
-	c.Client, _ = gitlab.NewClient(config.Token, gitlab.WithBaseURL(config.Url))
+	// retries are handled by the RetryTransport shared with the other providers
+	c.Client, _ = gitlab.NewClient(
+		config.Token,
+		gitlab.WithBaseURL(config.Url),
+		gitlab.WithHTTPClient(&http.Client{Transport: NewRetryTransport(http.DefaultTransport)}),
+		gitlab.WithCustomRetryMax(0),
+	)
 
 	if c.Notes == nil {
 		c.Notes = c.Client.Notes
 	}
 
+	if c.Discussions == nil {
+		c.Discussions = c.Client.Discussions
+	}
+
 	if c.Projects == nil {
 		c.Projects = c.Client.Projects
 	}
 
+	if c.MergeRequests == nil {
+		c.MergeRequests = c.Client.MergeRequests
+	}
+
+	if c.Users == nil {
+		c.Users = c.Client.Users
+	}
+
 	return c
 }
 
@@ -66,6 +105,7 @@ func convert(n *gitlab.Note) *Comment {
 
 	comment.Id = int64(n.ID)
 	comment.Body = n.Body
+	comment.Author = n.Author.Username
 
 	return comment
 }
@@ -75,7 +115,7 @@ func (gc *GitlabClient) GetProjectId() (string, error) {
 		return gc.ProjectId, nil
 	}
 
-	project, _, err := gc.Projects.GetProject(gc.Config.RepoOwner+"/"+gc.Config.RepoName, nil)
+	project, _, err := gc.Projects.GetProject(gc.Config.RepoOwner+"/"+gc.Config.RepoName, nil, gitlab.WithContext(gc.Context))
 
 	if err != nil {
 		return "", err
@@ -90,7 +130,10 @@ func (gc *GitlabClient) CreateComment() (*Comment, error) {
 	if err != nil {
 		return nil, err
 	}
-	note, _, err := gc.Notes.CreateMergeRequestNote(projectId, int64(gc.Config.PullRequestID), &gitlab.CreateMergeRequestNoteOptions{Body: &gc.NoteContent})
+	if gc.Config.HideOutdated {
+		return gc.createDiscussion(projectId)
+	}
+	note, _, err := gc.Notes.CreateMergeRequestNote(projectId, int64(gc.Config.PullRequestID), &gitlab.CreateMergeRequestNoteOptions{Body: &gc.NoteContent}, gitlab.WithContext(gc.Context))
 	return convert(note), err
 }
 
@@ -99,7 +142,7 @@ func (gc *GitlabClient) UpdateComment(id int64) (*Comment, error) {
 	if err != nil {
 		return nil, err
 	}
-	editedNote, _, err := gc.Notes.UpdateMergeRequestNote(projectId, int64(gc.Config.PullRequestID), id, &gitlab.UpdateMergeRequestNoteOptions{Body: &gc.NoteContent})
+	editedNote, _, err := gc.Notes.UpdateMergeRequestNote(projectId, int64(gc.Config.PullRequestID), id, &gitlab.UpdateMergeRequestNoteOptions{Body: &gc.NoteContent}, gitlab.WithContext(gc.Context))
 	return convert(editedNote), err
 }
 
@@ -108,7 +151,26 @@ func (gc *GitlabClient) DeleteComment(id int64) error {
 	if err != nil {
 		return err
 	}
-	_, err = gc.Notes.DeleteMergeRequestNote(projectId, int64(gc.Config.PullRequestID), id)
+	_, err = gc.Notes.DeleteMergeRequestNote(projectId, int64(gc.Config.PullRequestID), id, gitlab.WithContext(gc.Context))
+	return err
+}
+
+// AuthenticatedUser returns the username of the token. Project and group access tokens have their own bot user.
+func (gc *GitlabClient) AuthenticatedUser() (string, error) {
+	user, _, err := gc.Users.CurrentUser(gitlab.WithContext(gc.Context))
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func (gc *GitlabClient) AddLabels(labels []string) error {
+	projectId, err := gc.GetProjectId()
+	if err != nil {
+		return err
+	}
+	addLabels := gitlab.LabelOptions(labels)
+	_, _, err = gc.MergeRequests.UpdateMergeRequest(projectId, int64(gc.Config.PullRequestID), &gitlab.UpdateMergeRequestOptions{AddLabels: &addLabels}, gitlab.WithContext(gc.Context))
 	return err
 }
 
@@ -120,25 +182,51 @@ func (gc *GitlabClient) PostComment() (CommentOperation, error) {
 	return postComment(gc, gc.Config)
 }
 
+func (gc *GitlabClient) PostComments(groups []CommentGroup) (map[string]CommentOperation, error) {
+	return postComments(gc, gc.Config, groups)
+}
+
 func (gc *GitlabClient) SetCommentContent(content string) {
 	gc.NoteContent = content
 }
 
+// ListComments returns all notes of the merge request newest first
 func (gc *GitlabClient) ListComments() ([]Comment, error) {
+	return listComments(gc.walkComments)
+}
+
+func (gc *GitlabClient) SearchComment(match func(comment Comment) bool) (*Comment, error) {
+	return searchComment(gc.walkComments, match)
+}
+
+// walkComments visits the notes newest first following the next page of the response.
+// With HideOutdated the discussions are listed to detect resolved comments.
+func (gc *GitlabClient) walkComments(visit func(comment Comment) bool) error {
 	projectId, err := gc.GetProjectId()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if gc.Config.HideOutdated {
+		return gc.walkDiscussions(projectId, visit)
 	}
 	opt := &gitlab.ListMergeRequestNotesOptions{
 		ListOptions: gitlab.ListOptions{PerPage: 100},
+		OrderBy:     gitlab.Ptr("created_at"),
+		Sort:        gitlab.Ptr("desc"),
 	}
-	notes, _, err := gc.Notes.ListMergeRequestNotes(projectId, int64(gc.Config.PullRequestID), opt)
-	if err != nil {
-		return nil, err
-	}
-	var result []Comment
-	for _, note := range notes {
-		result = append(result, *convert(note))
+	for {
+		notes, resp, err := gc.Notes.ListMergeRequestNotes(projectId, int64(gc.Config.PullRequestID), opt, gitlab.WithContext(gc.Context))
+		if err != nil {
+			return err
+		}
+		for _, note := range notes {
+			if !visit(*convert(note)) {
+				return nil
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
 	}
-	return result, nil
 }
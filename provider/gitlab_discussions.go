@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	gitlab "gitlab.com/gitlab-org/api/client-go/v2"
+)
+
+// convertDiscussion returns the first note of the discussion. Only notes of resolvable discussions get the discussion id as Ref.
+func convertDiscussion(d *gitlab.Discussion) *Comment {
+	if d == nil || len(d.Notes) == 0 {
+		return &Comment{}
+	}
+	comment := convert(d.Notes[0])
+	if !d.IndividualNote {
+		comment.Ref = d.ID
+		comment.Hidden = d.Notes[0].Resolved
+	}
+	return comment
+}
+
+// createDiscussion creates the comment as a resolvable discussion, so it can be resolved when it is outdated
+func (gc *GitlabClient) createDiscussion(projectId string) (*Comment, error) {
+	discussion, _, err := gc.Discussions.CreateMergeRequestDiscussion(projectId, int64(gc.Config.PullRequestID), &gitlab.CreateMergeRequestDiscussionOptions{Body: &gc.NoteContent}, gitlab.WithContext(gc.Context))
+	if err != nil {
+		return nil, err
+	}
+	return convertDiscussion(discussion), nil
+}
+
+// walkDiscussions visits the first note of all discussions newest first.
+// Discussions can not be sorted by the API so all pages are read before visiting.
+func (gc *GitlabClient) walkDiscussions(projectId string, visit func(comment Comment) bool) error {
+	opt := &gitlab.ListMergeRequestDiscussionsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+	var discussions []*gitlab.Discussion
+	for {
+		page, resp, err := gc.Discussions.ListMergeRequestDiscussions(projectId, int64(gc.Config.PullRequestID), opt, gitlab.WithContext(gc.Context))
+		if err != nil {
+			return err
+		}
+		discussions = append(discussions, page...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	for _, discussion := range slices.Backward(discussions) {
+		if len(discussion.Notes) == 0 || discussion.Notes[0].System {
+			continue
+		}
+		if !visit(*convertDiscussion(discussion)) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// MinimizeComment resolves the discussion of the comment, so GitLab collapses it.
+// Comments created without discussion can not be resolved and are deleted.
+func (gc *GitlabClient) MinimizeComment(comment Comment) error {
+	if comment.Ref == "" {
+		logrus.Debugf("Deleting comment with id %d because it is not resolvable", comment.Id)
+		return gc.DeleteComment(comment.Id)
+	}
+	projectId, err := gc.GetProjectId()
+	if err != nil {
+		return err
+	}
+	_, _, err = gc.Discussions.ResolveMergeRequestDiscussion(projectId, int64(gc.Config.PullRequestID), comment.Ref, &gitlab.ResolveMergeRequestDiscussionOptions{Resolved: gitlab.Ptr(true)}, gitlab.WithContext(gc.Context))
+	return err
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/gitlab-org/api/client-go/v2"
+)
+
+type MockDiscussionsService struct {
+	sync.RWMutex
+	discussions []*gitlab.Discussion
+	pageSize    int64
+	listCalls   int
+}
+
+func (m *MockDiscussionsService) ListMergeRequestDiscussions(pid interface{}, mergeRequest int64, opt *gitlab.ListMergeRequestDiscussionsOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Discussion, *gitlab.Response, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.listCalls++
+	page := max(opt.Page, 1)
+	start := min((page-1)*m.pageSize, int64(len(m.discussions)))
+	end := min(start+m.pageSize, int64(len(m.discussions)))
+	resp := &gitlab.Response{}
+	if end < int64(len(m.discussions)) {
+		resp.NextPage = page + 1
+	}
+	return m.discussions[start:end], resp, nil
+}
+
+func (m *MockDiscussionsService) CreateMergeRequestDiscussion(pid interface{}, mergeRequest int64, opt *gitlab.CreateMergeRequestDiscussionOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Discussion, *gitlab.Response, error) {
+	m.Lock()
+	defer m.Unlock()
+	id := int64(len(m.discussions) + 1)
+	discussion := &gitlab.Discussion{
+		ID:    fmt.Sprintf("discussion-%d", id),
+		Notes: []*gitlab.Note{{ID: id, Body: *opt.Body, Resolvable: true, Author: gitlab.NoteAuthor{Username: fakeAuthor}}},
+	}
+	m.discussions = append(m.discussions, discussion)
+	return discussion, nil, nil
+}
+
+func (m *MockDiscussionsService) ResolveMergeRequestDiscussion(pid interface{}, mergeRequest int64, discussion string, opt *gitlab.ResolveMergeRequestDiscussionOptions, options ...gitlab.RequestOptionFunc) (*gitlab.Discussion, *gitlab.Response, error) {
+	m.Lock()
+	defer m.Unlock()
+	for _, d := range m.discussions {
+		if d.ID == discussion {
+			for _, note := range d.Notes {
+				note.Resolved = *opt.Resolved
+			}
+			return d, nil, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("404 Discussion Not Found")
+}
+
+func defaultTestGitlabDiscussionsProvider(discussions []*gitlab.Discussion) (*GitlabClient, *MockDiscussionsService) {
+	mock := &MockDiscussionsService{discussions: discussions, pageSize: 2}
+	client := defaultTestGitlabProvider(nil)
+	client.Discussions = mock
+	client.Config.HideOutdated = true
+	return client, mock
+}
+
+func TestGitlabClient_HideOutdated(t *testing.T) {
+	initGitlabLogger()
+	client, mock := defaultTestGitlabDiscussionsProvider([]*gitlab.Discussion{
+		{ID: "discussion-1", IndividualNote: true, Notes: []*gitlab.Note{{ID: 1, Body: "review comment"}}},
+		{ID: "discussion-2", IndividualNote: true, Notes: []*gitlab.Note{{ID: 2, Body: "added 1 commit", System: true}}},
+	})
+
+	client.NoteContent = fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.Len(t, mock.discussions, 3)
+	assert.False(t, mock.discussions[2].IndividualNote, "expect a resolvable discussion")
+
+	client.NoteContent = fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nnew")
+	operation, err = client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_CREATED, operation)
+	assert.True(t, mock.discussions[2].Notes[0].Resolved, "expect the outdated discussion to be resolved")
+	assert.False(t, mock.discussions[3].Notes[0].Resolved)
+
+	mock.listCalls = 0
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Comment{
+		{Id: 4, Body: client.NoteContent, Ref: "discussion-4", Author: fakeAuthor},
+		{Id: 3, Body: fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold"), Ref: "discussion-3", Hidden: true, Author: fakeAuthor},
+		{Id: 1, Body: "review comment"},
+	}, comments, "expect system notes to be skipped")
+	assert.Equal(t, 2, mock.listCalls, "expect all pages to be read")
+}
+
+func TestGitlabClient_MinimizeIndividualNote(t *testing.T) {
+	initGitlabLogger()
+	client, _ := defaultTestGitlabDiscussionsProvider(nil)
+	notes := client.Notes.(*MockMergeRequestService)
+	notes.notes = []*gitlab.Note{{ID: 1, Body: "outdated diff"}}
+
+	// notes created before hiding was enabled can not be resolved
+	err := client.MinimizeComment(Comment{Id: 1})
+	assert.NoError(t, err)
+	assert.Empty(t, notes.notes)
+
+	err = client.MinimizeComment(Comment{Id: 2, Ref: "discussion-2"})
+	assert.EqualError(t, err, "404 Discussion Not Found")
+}
@@ -18,7 +18,8 @@ func initGitlabLogger() {
 
 type MockMergeRequestService struct {
 	sync.RWMutex
-	notes []*gitlab.Note
+	notes     []*gitlab.Note
+	listCalls int
 }
 
 type MockProjectService struct {
@@ -76,8 +77,42 @@ func (m *MockMergeRequestService) CreateMergeRequestNote(pid interface{}, mergeR
 	return note, nil, nil
 }
 
+// ListMergeRequestNotes returns the notes paginated and sorted like GitLab
 func (m *MockMergeRequestService) ListMergeRequestNotes(pid interface{}, mergeRequest int64, opt *gitlab.ListMergeRequestNotesOptions, options ...gitlab.RequestOptionFunc) ([]*gitlab.Note, *gitlab.Response, error) {
-	return m.notes, nil, nil
+	m.listCalls++
+	notes := withFakeGitlabAuthor(m.notes)
+	if opt == nil || opt.PerPage == 0 {
+		return notes, nil, nil
+	}
+	if opt.Sort != nil && *opt.Sort == "desc" {
+		reversed := make([]*gitlab.Note, 0, len(notes))
+		for i := len(notes) - 1; i >= 0; i-- {
+			reversed = append(reversed, notes[i])
+		}
+		notes = reversed
+	}
+	page := max(opt.Page, 1)
+	start := min((page-1)*opt.PerPage, int64(len(notes)))
+	end := min(page*opt.PerPage, int64(len(notes)))
+	resp := &gitlab.Response{}
+	if end < int64(len(notes)) {
+		resp.NextPage = page + 1
+	}
+	return notes[start:end], resp, nil
+}
+
+// withFakeGitlabAuthor returns the notes with the user of the token as author if they have none
+func withFakeGitlabAuthor(notes []*gitlab.Note) []*gitlab.Note {
+	var result []*gitlab.Note
+	for _, note := range notes {
+		if note != nil && note.Author.Username == "" {
+			withAuthor := *note
+			withAuthor.Author.Username = fakeAuthor
+			note = &withAuthor
+		}
+		result = append(result, note)
+	}
+	return result
 }
 
 func defaultTestGitlabProvider(notes []*gitlab.Note) *GitlabClient {
@@ -86,6 +121,7 @@ func defaultTestGitlabProvider(notes []*gitlab.Note) *GitlabClient {
 	return &GitlabClient{
 		Notes:       mock,
 		Projects:    mockProj,
+		Users:       &MockGitlabUsersService{username: fakeAuthor},
 		Context:     context.Background(),
 		Config:      config.NotifierConfig{TagID: "test-tag", DeleteComment: true},
 		NoteContent: defaultTag,
@@ -177,6 +213,36 @@ func TestGitlabClient_ListComments(t *testing.T) {
 	assert.Len(t, comments, maxLength, "Expected number of initial comment to be %d", maxLength)
 }
 
+func TestGitlabClient_ListCommentsPaginated(t *testing.T) {
+	initLogger()
+	var notesMock []*gitlab.Note
+	for i := 1; i <= 250; i++ {
+		notesMock = append(notesMock, &gitlab.Note{
+			ID:   int64(i),
+			Body: fmt.Sprintf("%s example-%d", HeaderPrefix, i),
+		})
+	}
+	client := defaultTestGitlabProvider(notesMock)
+	mock := client.Notes.(*MockMergeRequestService)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Len(t, comments, 250)
+	assert.Equal(t, int64(250), comments[0].Id, "expect newest note first")
+	assert.Equal(t, 3, mock.listCalls)
+
+	mock.listCalls = 0
+	comment, err := client.SearchComment(func(comment Comment) bool { return comment.Id == 240 })
+	assert.NoError(t, err)
+	assert.Equal(t, int64(240), comment.Id)
+	assert.Equal(t, 1, mock.listCalls, "expect search to stop at first page")
+
+	mock.listCalls = 0
+	comment, err = client.SearchComment(func(comment Comment) bool { return comment.Id == 1 })
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), comment.Id)
+	assert.Equal(t, 3, mock.listCalls)
+}
+
 func TestGitlabClient_hasChanges(t *testing.T) {
 	cases := []HasChangesTest{
 		{
@@ -200,3 +266,49 @@ func TestGitlabClient_hasChanges(t *testing.T) {
 		assert.Equal(t, c.expectHasChanges, actual)
 	}
 }
+
+type MockGitlabMergeRequestsService struct {
+	labels []string
+}
+
+func (m *MockGitlabMergeRequestsService) UpdateMergeRequest(pid interface{}, mergeRequest int64, opt *gitlab.UpdateMergeRequestOptions, options ...gitlab.RequestOptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	m.labels = append(m.labels, *opt.AddLabels...)
+	return &gitlab.MergeRequest{}, nil, nil
+}
+
+func TestGitlabClient_AddLabels(t *testing.T) {
+	mock := &MockGitlabMergeRequestsService{}
+	client := defaultTestGitlabProvider(nil)
+	client.MergeRequests = mock
+	var labeler Labeler = client
+	err := labeler.AddLabels([]string{"database"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"database"}, mock.labels)
+}
+
+type MockGitlabUsersService struct {
+	username string
+}
+
+func (m *MockGitlabUsersService) CurrentUser(options ...gitlab.RequestOptionFunc) (*gitlab.User, *gitlab.Response, error) {
+	return &gitlab.User{ID: 7, Username: m.username}, nil, nil
+}
+
+func TestGitlabClient_OnlyOwnComments(t *testing.T) {
+	initGitlabLogger()
+	body := fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "Resources\nold")
+	client := defaultTestGitlabProvider([]*gitlab.Note{
+		{ID: 1, Body: body, Author: gitlab.NoteAuthor{Username: "alice"}},
+		{ID: 2, Body: body, Author: gitlab.NoteAuthor{Username: "project_1_bot"}},
+	})
+	client.Users = &MockGitlabUsersService{username: "project_1_bot"}
+	client.Config.OnlyOwnComments = true
+
+	client.NoteContent = fmt.Sprintf("%s %s\n%s", HeaderPrefix, defaultTag, "no changes")
+	operation, err := client.PostComment()
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_DELETED, operation)
+	comments, err := client.ListComments()
+	assert.NoError(t, err)
+	assert.Equal(t, []Comment{{Id: 1, Body: body, Author: "alice"}}, comments)
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commentMarkerPrefix = "<!-- cdk-notifier "
+	commentMarkerSuffix = " -->"
+	// CommentMarkerMaxLength is the space reserved for the marker in every comment.
+	// The stack list is left out of markers that would be longer.
+	CommentMarkerMaxLength = 512
+	// TimestampMarker precedes the timestamp of the run rendered by templates.
+	// Only timestamps after the marker are ignored when comparing comments, timestamps of the diff itself are not.
+	TimestampMarker = "<!-- cdk-notifier-timestamp -->"
+	// HashChangesOnlyMarker is the first line of the diff rendered in no-post-mode if only suppressed hash changes were detected.
+	// post deletes the comment of such a diff like cdk-notifier does.
+	HashChangesOnlyMarker = "<!-- cdk-notifier-hash-changes-only -->"
+)
+
+var commentMarkerRegex = regexp.MustCompile(`(?m)\n*^` + regexp.QuoteMeta(commentMarkerPrefix) + `(\{.*?\})` + regexp.QuoteMeta(commentMarkerSuffix))
+
+// CommentMarker is embedded as invisible HTML comment into every managed comment.
+// It identifies the comment independent of the visible header of the template.
+type CommentMarker struct {
+	// Version of cdk-notifier which posted the comment
+	Version   string   `json:"version"`
+	TagID     string   `json:"tagId"`
+	Stacks    []string `json:"stacks,omitempty"`
+	CommitSha string   `json:"commitSha,omitempty"`
+	// Digest is the sha256 of the comment content without marker, job link and timestamp of the run
+	Digest string `json:"digest"`
+}
+
+// Embed returns the content with the marker appended. An existing marker of the content is replaced.
+func (m CommentMarker) Embed(content string) string {
+	content = stripCommentMarker(content)
+	m.Digest = commentDigest(content)
+	marker := m.String()
+	if len(marker) > CommentMarkerMaxLength && len(m.Stacks) > 0 {
+		logrus.Debugf("Leaving out the %d stacks from the marker of tag id %s", len(m.Stacks), m.TagID)
+		m.Stacks = nil
+		marker = m.String()
+	}
+	return content + "\n\n" + marker
+}
+
+func (m CommentMarker) String() string {
+	// json.Marshal escapes < and > so the marker can not end the HTML comment early
+	data, _ := json.Marshal(m)
+	return commentMarkerPrefix + string(data) + commentMarkerSuffix
+}
+
+// parseCommentMarker returns the marker of the comment or nil for comments without valid marker
+func parseCommentMarker(body string) *CommentMarker {
+	match := commentMarkerRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil
+	}
+	marker := &CommentMarker{}
+	if err := json.Unmarshal([]byte(match[1]), marker); err != nil || marker.TagID == "" {
+		logrus.Debugf("Ignoring invalid comment marker %s", match[0])
+		return nil
+	}
+	return marker
+}
+
+// stripCommentMarker removes the marker from the comment
+func stripCommentMarker(body string) string {
+	return commentMarkerRegex.ReplaceAllString(body, "")
+}
+
+// sameCommentCommit reports whether the markers of the existing comment and the new content show the same commit.
+// Comments without marker of older versions are not updated only to add the marker.
+func sameCommentCommit(existing, content string) bool {
+	existingMarker, contentMarker := parseCommentMarker(existing), parseCommentMarker(content)
+	if existingMarker == nil || contentMarker == nil {
+		return true
+	}
+	return existingMarker.CommitSha == contentMarker.CommitSha
+}
+
+// commentDigest returns the sha256 of the content ignoring the parts changing with every pipeline run
+func commentDigest(content string) string {
+	sum := sha256.Sum256([]byte(normalizeCommentBody(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// commentTagIDs returns the tag id of the marker. Comments without marker are identified by the tag ids of their headers.
+func commentTagIDs(body string) []string {
+	if marker := parseCommentMarker(body); marker != nil {
+		return []string{marker.TagID}
+	}
+	return parseHeaderTagIDs(body)
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentMarker_Embed(t *testing.T) {
+	marker := CommentMarker{Version: "1.2.3", TagID: "dev", Stacks: []string{"network", "app"}, CommitSha: "abc123"}
+	body := marker.Embed("## cdk diff for dev\nResources\n[+] bucket")
+	assert.True(t, strings.HasPrefix(body, "## cdk diff for dev\nResources\n[+] bucket\n\n<!-- cdk-notifier {"))
+	assert.True(t, strings.HasSuffix(body, "} -->"))
+
+	parsed := parseCommentMarker(body)
+	assert.NotNil(t, parsed)
+	assert.Equal(t, "1.2.3", parsed.Version)
+	assert.Equal(t, "dev", parsed.TagID)
+	assert.Equal(t, []string{"network", "app"}, parsed.Stacks)
+	assert.Equal(t, "abc123", parsed.CommitSha)
+	assert.Equal(t, commentDigest("## cdk diff for dev\nResources\n[+] bucket"), parsed.Digest)
+
+	// embedding again replaces the marker
+	marker.TagID = "prod"
+	body = marker.Embed(body)
+	assert.Equal(t, 1, strings.Count(body, commentMarkerPrefix))
+	assert.Equal(t, "prod", parseCommentMarker(body).TagID)
+	assert.Equal(t, "## cdk diff for dev\nResources\n[+] bucket", stripCommentMarker(body))
+}
+
+func TestCommentMarker_EmbedEscapes(t *testing.T) {
+	body := CommentMarker{TagID: "dev-->", Stacks: []string{"<b>app</b>"}}.Embed("content")
+	assert.Equal(t, 1, strings.Count(body, commentMarkerSuffix), "expect the marker not to close early")
+	parsed := parseCommentMarker(body)
+	assert.NotNil(t, parsed)
+	assert.Equal(t, "dev-->", parsed.TagID)
+	assert.Equal(t, []string{"<b>app</b>"}, parsed.Stacks)
+}
+
+func TestCommentMarker_EmbedManyStacks(t *testing.T) {
+	var stacks []string
+	for i := 0; i < 50; i++ {
+		stacks = append(stacks, fmt.Sprintf("VeryLongStackName%d", i))
+	}
+	body := CommentMarker{Version: "1.2.3", TagID: "dev", Stacks: stacks}.Embed("content")
+	marker := strings.TrimPrefix(body, "content\n\n")
+	assert.LessOrEqual(t, len(marker), CommentMarkerMaxLength)
+	parsed := parseCommentMarker(body)
+	assert.NotNil(t, parsed)
+	assert.Equal(t, "dev", parsed.TagID)
+	assert.Nil(t, parsed.Stacks)
+}
+
+func TestParseCommentMarker(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected *CommentMarker
+	}{
+		{"## cdk diff for dev\nResources", nil},
+		{"content\n\n<!-- cdk-notifier {\"tagId\":\"dev\",\"digest\":\"1234\"} -->", &CommentMarker{TagID: "dev", Digest: "1234"}},
+		{"content\n\n<!-- cdk-notifier {invalid} -->", nil},
+		{"content\n\n<!-- cdk-notifier {\"version\":\"1.2.3\"} -->", nil},
+		// quoted markers in replies are not taken over
+		{"> content\n> <!-- cdk-notifier {\"tagId\":\"dev\"} -->", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, parseCommentMarker(tt.body), tt.body)
+	}
+}
+
+func TestFindCommentByTagWithMarker(t *testing.T) {
+	withMarker := Comment{Id: 2, Body: CommentMarker{TagID: "dev"}.Embed("### Custom template without header\nResources")}
+	// the marker wins over a header quoting another tag id
+	quoting := Comment{Id: 3, Body: CommentMarker{TagID: "dev"}.Embed("## cdk diff for prod\nResources")}
+	// comments of older versions are matched by their header
+	legacy := Comment{Id: 4, Body: "## cdk diff for prod\nResources", Author: "cdk-bot"}
+	comments := []Comment{{Id: 1, Body: "> ## cdk diff for dev\nquoted", Author: "alice"}, withMarker, quoting, legacy}
+
+	assert.Equal(t, &withMarker, findCommentByTag(comments, "dev", "cdk-bot"))
+	assert.Equal(t, &legacy, findCommentByTag(comments, "prod", "cdk-bot"))
+	assert.Nil(t, findCommentByTag(comments, "test", "cdk-bot"))
+	assert.Equal(t, &comments[0], findCommentByTag(comments[:1], "dev", "alice"), "expect the header fallback without marker comments")
+	// only comments of the user of the token are matched by their header
+	assert.Nil(t, findCommentByTag(comments[:1], "dev", "cdk-bot"))
+	assert.Equal(t, &legacy, findCommentByTag(comments, "prod", ""), "expect the header fallback of all users without author")
+
+	assert.Equal(t, []string{"dev"}, commentTagIDs(quoting.Body))
+	assert.Equal(t, []string{"prod"}, commentTagIDs(legacy.Body))
+}
+
+func TestSameCommentBodyWithMarker(t *testing.T) {
+	content := "## cdk diff for dev\nResources\n[+] bucket"
+	first := CommentMarker{TagID: "dev", CommitSha: "abc123"}.Embed(content)
+	second := CommentMarker{TagID: "dev", CommitSha: "def456"}.Embed(content)
+	assert.True(t, sameCommentBody(first, second), "expect a new commit with the same diff to be unchanged")
+	assert.True(t, sameCommentBody(content, second), "expect a comment without marker to be unchanged")
+	assert.False(t, sameCommentBody(first, CommentMarker{TagID: "dev"}.Embed(content+"\n[+] queue")))
+
+	assert.False(t, sameCommentCommit(first, second), "expect the marker of a new commit to be updated")
+	assert.True(t, sameCommentCommit(first, CommentMarker{TagID: "dev", CommitSha: "abc123"}.Embed(content+"\n[+] queue")))
+	assert.True(t, sameCommentCommit(content, second), "expect a comment without marker not to be updated only for the marker")
+}
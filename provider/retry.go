@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// retryMaxAttempts is the maximum number of attempts of a single request
+	retryMaxAttempts = 5
+	// retryBaseDelay is the wait before the first retry. It is doubled for every further retry.
+	retryBaseDelay = time.Second
+	// retryMaxDelay is the longest wait for a rate limit reset when the request context has no deadline
+	retryMaxDelay = 5 * time.Minute
+	// retryAttemptTimeout limits a single attempt including reading the response body
+	retryAttemptTimeout = 15 * time.Second
+)
+
+// RetryTransport retries requests to the VCS API with exponential backoff.
+// Idempotent requests are retried on network errors and server errors.
+// Rate limited requests are retried for every method because they were not processed.
+// The wait honours the headers Retry-After and X-RateLimit-Reset.
+// No retry is attempted when the wait would pass the deadline of the request context.
+// Clients using the transport must not set http.Client.Timeout as it would limit all attempts together.
+type RetryTransport struct {
+	Proxied        http.RoundTripper
+	MaxAttempts    int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	AttemptTimeout time.Duration
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewRetryTransport wraps the transport with the default retry settings
+func NewRetryTransport(proxied http.RoundTripper) *RetryTransport {
+	if proxied == nil {
+		proxied = http.DefaultTransport
+	}
+	return &RetryTransport{
+		Proxied:        proxied,
+		MaxAttempts:    retryMaxAttempts,
+		BaseDelay:      retryBaseDelay,
+		MaxDelay:       retryMaxDelay,
+		AttemptTimeout: retryAttemptTimeout,
+		now:            time.Now,
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			var err error
+			attemptReq, err = rewindRequest(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.roundTripAttempt(attemptReq)
+		if attempt >= t.MaxAttempts || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		wait, retry := t.retryDelay(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && t.now().Add(wait).After(deadline) {
+			logrus.Debugf("Not retrying %s %s because the API timeout would be exceeded", req.Method, req.URL.Path)
+			return resp, err
+		}
+		if err != nil {
+			logrus.Warnf("Request %s %s failed: %v. Retrying in %s", req.Method, req.URL.Path, err, wait)
+		} else {
+			logrus.Warnf("Request %s %s failed with %s. Retrying in %s", req.Method, req.URL.Path, resp.Status, wait)
+			// the connection can only be reused if the body is read completely
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// roundTripAttempt sends the request within the attempt timeout.
+// The timeout is released when the response body is closed.
+func (t *RetryTransport) roundTripAttempt(req *http.Request) (*http.Response, error) {
+	if t.AttemptTimeout <= 0 {
+		return t.Proxied.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+	resp, err := t.Proxied.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// rewindRequest copies the request with a new body for another attempt
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.GetBody == nil {
+		return clone, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// retryDelay returns how long to wait before the next attempt and if the request should be retried at all
+func (t *RetryTransport) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	backoff := t.BaseDelay * time.Duration(math.Pow(2, float64(attempt-1)))
+	if err != nil {
+		if errors.Is(err, req.Context().Err()) {
+			return 0, false
+		}
+		return backoff, isIdempotent(req.Method)
+	}
+	if isRateLimited(resp) {
+		wait, ok := rateLimitReset(resp, t.now())
+		if !ok {
+			return backoff, true
+		}
+		if _, hasDeadline := req.Context().Deadline(); !hasDeadline && wait > t.MaxDelay {
+			return 0, false
+		}
+		return wait, true
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff, isIdempotent(req.Method)
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether a request with the method can be sent several times with the same effect
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRateLimited detects rate limits of GitHub, GitLab and Bitbucket.
+// GitHub answers exceeded primary and secondary rate limits with 403 instead of 429.
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+// rateLimitReset returns the wait until the rate limit is reset from the response headers
+func rateLimitReset(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		value, err := strconv.ParseInt(resp.Header.Get(header), 10, 64)
+		if err != nil {
+			continue
+		}
+		// GitHub and GitLab send the reset as unix time, others the seconds until the reset
+		if value > 1000000000 {
+			return max(time.Unix(value, 0).Sub(now), 0), true
+		}
+		return time.Duration(value) * time.Second, true
+	}
+	return 0, false
+}
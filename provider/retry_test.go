@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRetryClient() *http.Client {
+	transport := NewRetryTransport(http.DefaultTransport)
+	transport.BaseDelay = time.Millisecond
+	return &http.Client{Transport: transport}
+}
+
+// failingServer answers the first failures requests with the status and headers and succeeds afterwards
+func failingServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if count <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransport_RetriesIdempotentRequests(t *testing.T) {
+	initLogger()
+	client := testRetryClient()
+	tests := []struct {
+		method   string
+		status   int
+		expected int32
+	}{
+		{http.MethodGet, http.StatusBadGateway, 3},
+		{http.MethodPut, http.StatusServiceUnavailable, 3},
+		{http.MethodDelete, http.StatusGatewayTimeout, 3},
+		{http.MethodPost, http.StatusBadGateway, 1},
+		{http.MethodPatch, http.StatusInternalServerError, 1},
+		{http.MethodGet, http.StatusNotFound, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+strconv.Itoa(tt.status), func(t *testing.T) {
+			server, requests := failingServer(t, 2, tt.status, nil)
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("some-body"))
+			assert.NoError(t, err)
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expected, requests.Load())
+			if tt.expected > 1 {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.Equal(t, "some-body", string(body), "expect body to be sent again")
+			} else {
+				assert.Equal(t, tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RetriesRateLimitedRequests(t *testing.T) {
+	initLogger()
+	client := testRetryClient()
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+	}{
+		{"too many requests", http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}},
+		{"github secondary rate limit", http.StatusForbidden, http.Header{"Retry-After": {"0"}}},
+		{"github primary rate limit", http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Unix(), 10)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := failingServer(t, 1, tt.status, tt.header)
+			// requests rejected by a rate limit are not processed and can be retried for every method
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, int32(2), requests.Load())
+		})
+	}
+
+	// permission errors of GitHub are not retried
+	server, requests := failingServer(t, 1, http.StatusForbidden, nil)
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetryTransport_Limits(t *testing.T) {
+	initLogger()
+	client := testRetryClient()
+
+	// attempts are limited
+	server, requests := failingServer(t, 10, http.StatusBadGateway, nil)
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(retryMaxAttempts), requests.Load())
+
+	// no retry if waiting would exceed the deadline of the context
+	server, requests = failingServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRateLimitReset(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header   http.Header
+		expected time.Duration
+		found    bool
+	}{
+		{http.Header{"Retry-After": {"30"}}, 30 * time.Second, true},
+		{http.Header{"Retry-After": {"Wed, 01 May 2024 12:00:10 GMT"}}, 10 * time.Second, true},
+		{http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}}, time.Minute, true},
+		{http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}}, 0, true},
+		{http.Header{"Ratelimit-Reset": {strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}}, time.Minute, true},
+		{http.Header{"Ratelimit-Reset": {"5"}}, 5 * time.Second, true},
+		{http.Header{}, 0, false},
+	}
+	for _, tt := range tests {
+		wait, found := rateLimitReset(&http.Response{Header: tt.header}, now)
+		assert.Equal(t, tt.found, found, "%v", tt.header)
+		assert.Equal(t, tt.expected, wait, "%v", tt.header)
+	}
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"regexp"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/sirupsen/logrus"
+)
+
+const staleBannerMarker = "<!-- cdk-notifier-stale -->"
+
+var staleBannerRegex = regexp.MustCompile(`^` + regexp.QuoteMeta(staleBannerMarker) + `\r?\n[^\n]*\n\r?\n`)
+
+// staleBanner is prefixed to comments whose diff is being recomputed. It is removed when the new diff is posted.
+func staleBanner(commitSha string) string {
+	banner := "> ⚠️ **Outdated** — diff is being recomputed"
+	if len(commitSha) > 7 {
+		commitSha = commitSha[:7]
+	}
+	if commitSha != "" {
+		banner += " for commit " + commitSha
+	}
+	return staleBannerMarker + "\n" + banner + "\n\n"
+}
+
+// markStale prefixes the body with the banner. The banner of a previous run is replaced.
+func markStale(body string, commitSha string) string {
+	return staleBanner(commitSha) + staleBannerRegex.ReplaceAllString(body, "")
+}
+
+// MarkStale prefixes all comments of the tag id and its per stack and continuation tags with a banner
+// that the diff is being recomputed for config.CommitSha. With AggregateComment only the sections of the tag id are marked.
+// Hidden comments and comments without marker of other users than config.CommentAuthor are ignored.
+func MarkStale(ns NotifierService, config config.NotifierConfig, marker CommentMarker) (map[string]CommentOperation, error) {
+	operations := make(map[string]CommentOperation)
+	config, err := resolveCommentAuthor(ns, config)
+	if err != nil {
+		return operations, err
+	}
+	if config.AggregateComment != "" {
+		return markStaleSections(ns, config, marker)
+	}
+	comments, err := ns.ListComments()
+	if err != nil {
+		return operations, err
+	}
+	for _, comment := range ownComments(comments, config) {
+		if comment.Hidden || (parseCommentMarker(comment.Body) == nil && !isLegacyComment(comment, config.CommentAuthor)) {
+			continue
+		}
+		for _, tagID := range commentTagIDs(comment.Body) {
+			if !belongsToTag(tagID, config.TagID) {
+				continue
+			}
+			operations[tagID], err = markCommentStale(ns, config, comment, tagID)
+			if err != nil {
+				return operations, err
+			}
+			break
+		}
+	}
+	return operations, nil
+}
+
+func markCommentStale(ns NotifierService, config config.NotifierConfig, comment Comment, tagID string) (CommentOperation, error) {
+	body := markStale(comment.Body, config.CommitSha)
+	if body == comment.Body {
+		logrus.Infof("Comment with id %d and tag id %s is already marked as outdated", comment.Id, tagID)
+		return API_COMMENT_UNCHANGED, nil
+	}
+	ns.SetCommentContent(body)
+	updated, err := ns.UpdateComment(comment.Id)
+	if err != nil {
+		logrus.Error(err)
+		return API_COMMENT_NOTHING, err
+	}
+	logrus.Infof("Marked comment with id %d and tag id %s as outdated %v", updated.Id, tagID, updated.Link)
+	return API_COMMENT_UPDATED, nil
+}
+
+// markStaleSections prefixes the sections of the tag id in the aggregate comment with the banner
+func markStaleSections(ns NotifierService, config config.NotifierConfig, marker CommentMarker) (map[string]CommentOperation, error) {
+	operations := make(map[string]CommentOperation)
+	comment, _, err := findAggregateComments(ns, config)
+	if err != nil || comment == nil {
+		return operations, err
+	}
+	var groups []CommentGroup
+	for _, section := range parseCommentSections(comment.Body) {
+		if belongsToTag(section.TagID, config.TagID) {
+			content := markStale(section.Content, config.CommitSha)
+			groups = append(groups, CommentGroup{TagID: section.TagID, Comments: []ManagedComment{{TagID: section.TagID, Content: content}}})
+		}
+	}
+	if len(groups) == 0 {
+		return operations, nil
+	}
+	// sections without changes are marked as well instead of being removed
+	config.DeleteComment = false
+	operation, err := PostAggregateComment(ns, config, groups, marker)
+	for _, group := range groups {
+		operations[group.TagID] = operation
+	}
+	return operations, err
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkStale(t *testing.T) {
+	dev := CommentMarker{TagID: "dev"}.Embed("## cdk diff for dev\nResources\n[+] bucket")
+	stack := "## cdk diff for dev::app\nResources\n[+] queue"
+	store := &fakeCommentStore{
+		comments: []Comment{
+			{Id: 1, Body: dev},
+			{Id: 2, Body: stack},
+			{Id: 3, Body: "## cdk diff for prod\nResources"},
+			{Id: 4, Body: "## cdk diff for dev\nold diff", Hidden: true},
+		},
+	}
+	cfg := config.NotifierConfig{TagID: "dev", CommitSha: "0123456789abcdef"}
+	operations, err := MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{"dev": API_COMMENT_UPDATED, "dev::app": API_COMMENT_UPDATED}, operations)
+	banner := "<!-- cdk-notifier-stale -->\n> ⚠️ **Outdated** — diff is being recomputed for commit 0123456\n\n"
+	assert.Equal(t, []string{banner + dev, banner + stack, "## cdk diff for prod\nResources", "## cdk diff for dev\nold diff"}, store.bodies())
+
+	// the marked comment is still found and replaced by the new diff
+	assert.Equal(t, int64(1), findCommentByTag(store.comments, "dev", "").Id)
+	assert.False(t, sameCommentBody(store.comments[0].Body, dev), "expect the banner to be removed by the next diff")
+
+	operations, err = MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, API_COMMENT_UNCHANGED, operations["dev"])
+
+	// the banner of a previous commit is replaced
+	cfg.CommitSha = "fedcba9876543210"
+	_, err = MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, "<!-- cdk-notifier-stale -->\n> ⚠️ **Outdated** — diff is being recomputed for commit fedcba9\n\n"+dev, store.comments[0].Body)
+}
+
+func TestMarkStaleCommentsOfOtherUsers(t *testing.T) {
+	dev := CommentMarker{TagID: "dev"}.Embed("## cdk diff for dev\nResources\n[+] bucket")
+	pasted := "## cdk diff for dev::app\nResources\npasted by alice"
+	store := &fakeCommentStore{
+		comments: []Comment{
+			{Id: 1, Body: dev, Author: "alice"},
+			{Id: 2, Body: pasted, Author: "alice"},
+			{Id: 3, Body: "## cdk diff for dev::db\nResources"},
+		},
+	}
+	cfg := config.NotifierConfig{TagID: "dev", CommentAuthor: fakeAuthor}
+	operations, err := MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{"dev": API_COMMENT_UPDATED, "dev::db": API_COMMENT_UPDATED}, operations)
+	assert.Equal(t, pasted, store.comments[1].Body, "expect the comment without marker of alice to be kept")
+}
+
+func TestMarkStaleAggregate(t *testing.T) {
+	store := &fakeCommentStore{}
+	cfg := config.NotifierConfig{AggregateComment: "cdk", DeleteComment: true}
+	for _, tagID := range []string{"dev", "prod"} {
+		_, err := PostAggregateComment(store, cfg, aggregateGroup(tagID, "Resources\n[+] bucket"), CommentMarker{})
+		assert.NoError(t, err)
+	}
+
+	cfg.TagID = "dev"
+	operations, err := MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]CommentOperation{"dev": API_COMMENT_UPDATED}, operations)
+	assert.Equal(t, []commentSection{
+		{TagID: "dev", Content: staleBanner("") + "## cdk diff for dev\nResources\n[+] bucket"},
+		{TagID: "prod", Content: "## cdk diff for prod\nResources\n[+] bucket"},
+	}, parseCommentSections(store.comments[0].Body))
+
+	cfg.TagID = "test"
+	operations, err = MarkStale(store, cfg, CommentMarker{})
+	assert.NoError(t, err)
+	assert.Empty(t, operations)
+}
@@ -0,0 +1,43 @@
+package transform
+
+const (
+	// ExitCodeNoChanges is returned with detailed exit codes when the diff contains no changes
+	ExitCodeNoChanges = 0
+	// ExitCodeChanges is returned with detailed exit codes when the diff contains changes
+	ExitCodeChanges = 2
+	// ExitCodeDestructiveChanges is returned with detailed exit codes when resources are replaced or destroyed
+	ExitCodeDestructiveChanges = 3
+)
+
+// DetailedExitCode returns the exit code describing the changes of the diff similar to terraform plan -detailed-exitcode.
+// Process has to be called before.
+func (t *LogTransformer) DetailedExitCode() int {
+	if t.hasDestructiveChanges() {
+		return ExitCodeDestructiveChanges
+	}
+	if t.TotalChanges > 0 || (t.Diff != nil && len(t.Diff.StacksWithDifferences()) > 0) {
+		return ExitCodeChanges
+	}
+	return ExitCodeNoChanges
+}
+
+// hasDestructiveChanges returns true if any resource is replaced or removed
+func (t *LogTransformer) hasDestructiveChanges() bool {
+	if t.NumberReplaces > 0 {
+		return true
+	}
+	for _, resource := range t.ChangedBaseResource {
+		if resource.Replaced {
+			return true
+		}
+	}
+	if t.Diff == nil {
+		return false
+	}
+	for _, stack := range t.Diff.Stacks {
+		if stack.Removals() > 0 || stack.Replacements() > 0 {
+			return true
+		}
+	}
+	return false
+}
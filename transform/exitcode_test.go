@@ -0,0 +1,51 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTransformer_DetailedExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		logfile  string
+		expected int
+	}{
+		{name: "NoChanges", logfile: "../data/cdk-nochanges.log", expected: ExitCodeNoChanges},
+		{name: "Replacements", logfile: "../data/cdk-diff-number-diff-replace.log", expected: ExitCodeDestructiveChanges},
+		{name: "MultipleStacks", logfile: "../data/cdk-multistack.log", expected: ExitCodeDestructiveChanges},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := &LogTransformer{
+				Logfile:                  tt.logfile,
+				TagID:                    tt.name,
+				NoTruncate:               true,
+				SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+			}
+			transformer.initProcessorsChain()
+			transformer.Process()
+			assert.Equal(t, tt.expected, transformer.DetailedExitCode())
+		})
+	}
+}
+
+func TestLogTransformer_DetailedExitCodeFromDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		expected int
+	}{
+		{name: "Addition", log: "Stack small\nResources\n[+] AWS::S3::Bucket bucket Bucket83908E77\n", expected: ExitCodeChanges},
+		{name: "Removal", log: "Stack small\nResources\n[-] AWS::S3::Bucket bucket Bucket83908E77 destroy\n", expected: ExitCodeDestructiveChanges},
+		{name: "NoDifferences", log: "Stack small\nThere were no differences\n", expected: ExitCodeNoChanges},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := &LogTransformer{Diff: ParseDiff(tt.log)}
+			assert.Equal(t, tt.expected, transformer.DetailedExitCode())
+		})
+	}
+}
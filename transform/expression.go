@@ -0,0 +1,380 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule expressions compare the fields of a single change of the diff, e.g.
+//
+//	stack =~ "^prod-" && type == "AWS::IAM::Role" && (kind == "removal" || replacement)
+//
+// Supported operators are == != =~ !~ && || ! and parentheses.
+// Strings are double-quoted with Go escapes or single-quoted without escapes.
+
+type fieldType int
+
+const (
+	fieldTypeString fieldType = iota
+	fieldTypeBool
+)
+
+// ruleFields are the fields of a change that can be used in rule expressions
+var ruleFields = map[string]fieldType{
+	"stack":       fieldTypeString,
+	"section":     fieldTypeString,
+	"type":        fieldTypeString,
+	"logicalId":   fieldTypeString,
+	"path":        fieldTypeString,
+	"kind":        fieldTypeString,
+	"property":    fieldTypeString,
+	"action":      fieldTypeString,
+	"replacement": fieldTypeBool,
+}
+
+// ruleContext is a single change of the diff a rule expression is evaluated against
+type ruleContext struct {
+	stack       string
+	section     string
+	resource    string // resource type
+	logicalID   string
+	path        string
+	kind        ChangeKind
+	property    string
+	action      string
+	replacement bool
+	// subject identifies the change in rule results
+	subject string
+}
+
+func (c *ruleContext) get(field string) string {
+	switch field {
+	case "stack":
+		return c.stack
+	case "section":
+		return c.section
+	case "type":
+		return c.resource
+	case "logicalId":
+		return c.logicalID
+	case "path":
+		return c.path
+	case "kind":
+		return string(c.kind)
+	case "property":
+		return c.property
+	case "action":
+		return c.action
+	case "replacement":
+		return strconv.FormatBool(c.replacement)
+	}
+	return ""
+}
+
+// ruleExpression is a compiled rule expression
+type ruleExpression interface {
+	match(c *ruleContext) bool
+}
+
+type orExpression struct {
+	left, right ruleExpression
+}
+
+func (e orExpression) match(c *ruleContext) bool {
+	return e.left.match(c) || e.right.match(c)
+}
+
+type andExpression struct {
+	left, right ruleExpression
+}
+
+func (e andExpression) match(c *ruleContext) bool {
+	return e.left.match(c) && e.right.match(c)
+}
+
+type notExpression struct {
+	expression ruleExpression
+}
+
+func (e notExpression) match(c *ruleContext) bool {
+	return !e.expression.match(c)
+}
+
+// operand is either a field or a literal
+type operand struct {
+	field     string
+	literal   string
+	valueType fieldType
+}
+
+func (o operand) value(c *ruleContext) string {
+	if o.field != "" {
+		return c.get(o.field)
+	}
+	return o.literal
+}
+
+// boolExpression is a bool field or literal used without comparison
+type boolExpression struct {
+	operand operand
+}
+
+func (e boolExpression) match(c *ruleContext) bool {
+	return e.operand.value(c) == "true"
+}
+
+type equalExpression struct {
+	left, right operand
+	negate      bool
+}
+
+func (e equalExpression) match(c *ruleContext) bool {
+	return (e.left.value(c) == e.right.value(c)) != e.negate
+}
+
+type regexExpression struct {
+	operand operand
+	regex   *regexp.Regexp
+	negate  bool
+}
+
+func (e regexExpression) match(c *ruleContext) bool {
+	return e.regex.MatchString(e.operand.value(c)) != e.negate
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// operators sorted by length to match the longest operator first
+var ruleOperators = []string{"==", "!=", "=~", "!~", "&&", "||", "!", "(", ")"}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: string(runes[start:i])})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if r == '"' && runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			literal := string(runes[start+1 : i-1])
+			if r == '"' {
+				var err error
+				literal, err = strconv.Unquote(string(runes[start:i]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at position %d: %w", start, err)
+				}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: literal})
+		default:
+			operator := ""
+			for _, candidate := range ruleOperators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator})
+			i += len([]rune(operator))
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// ruleParser is a recursive descent parser for rule expressions
+type ruleParser struct {
+	tokens []token
+	pos    int
+}
+
+// compileRule parses the rule expression and checks that all fields exist
+func compileRule(expression string) (ruleExpression, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens}
+	compiled, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected '%s'", next.text)
+	}
+	return compiled, nil
+}
+
+func (p *ruleParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *ruleParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *ruleParser) acceptOperator(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ruleParser) parseOr() (ruleExpression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (ruleExpression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseUnary() (ruleExpression, error) {
+	if p.acceptOperator("!") {
+		expression, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpression{expression: expression}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *ruleParser) parsePrimary() (ruleExpression, error) {
+	if p.acceptOperator("(") {
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptOperator(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return expression, nil
+	}
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokenOperator || (t.text != "==" && t.text != "!=" && t.text != "=~" && t.text != "!~") {
+		if left.valueType != fieldTypeBool {
+			return nil, fmt.Errorf("expected comparison after '%s'", left.field+left.literal)
+		}
+		return boolExpression{operand: left}, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch t.text {
+	case "=~", "!~":
+		if right.field != "" || right.valueType != fieldTypeString {
+			return nil, fmt.Errorf("right side of '%s' must be a string", t.text)
+		}
+		if left.valueType != fieldTypeString {
+			return nil, fmt.Errorf("left side of '%s' must be a string field", t.text)
+		}
+		regex, err := regexp.Compile(right.literal)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", right.literal, err)
+		}
+		return regexExpression{operand: left, regex: regex, negate: t.text == "!~"}, nil
+	default:
+		if left.valueType != right.valueType {
+			return nil, fmt.Errorf("can not compare bool and string with '%s'", t.text)
+		}
+		return equalExpression{left: left, right: right, negate: t.text == "!="}, nil
+	}
+}
+
+func (p *ruleParser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return operand{literal: t.text, valueType: fieldTypeString}, nil
+	case tokenIdentifier:
+		if t.text == "true" || t.text == "false" {
+			return operand{literal: t.text, valueType: fieldTypeBool}, nil
+		}
+		valueType, ok := ruleFields[t.text]
+		if !ok {
+			return operand{}, fmt.Errorf("unknown field '%s'. Use one of [%s]", t.text, strings.Join(ruleFieldNames(), "|"))
+		}
+		return operand{field: t.text, valueType: valueType}, nil
+	case tokenEOF:
+		return operand{}, fmt.Errorf("unexpected end of expression")
+	default:
+		return operand{}, fmt.Errorf("unexpected '%s'", t.text)
+	}
+}
+
+func ruleFieldNames() []string {
+	var names []string
+	for name := range ruleFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
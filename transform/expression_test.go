@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileRule(t *testing.T) {
+	c := &ruleContext{
+		stack:       "prod-db",
+		section:     SectionResources,
+		resource:    "AWS::DynamoDB::Table",
+		logicalID:   "OrdersTable315BB997",
+		path:        "Orders",
+		kind:        ChangeKindUpdate,
+		property:    "KeySchema",
+		replacement: true,
+	}
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: `type == "AWS::DynamoDB::Table"`, expected: true},
+		{expression: `type != "AWS::DynamoDB::Table"`, expected: false},
+		{expression: `stack =~ "^prod-"`, expected: true},
+		{expression: `stack !~ '^prod-'`, expected: false},
+		{expression: `replacement`, expected: true},
+		{expression: `!replacement`, expected: false},
+		{expression: `replacement == false`, expected: false},
+		{expression: `kind == "removal" || replacement && property == "KeySchema"`, expected: true},
+		{expression: `(kind == "removal" || replacement) && property == "TableName"`, expected: false},
+		{expression: `!(kind == "addition") && logicalId =~ 'Orders\w+'`, expected: true},
+		{expression: `action == "" && section == "Resources" && path == "Orders"`, expected: true},
+		{expression: `"AWS::DynamoDB::Table" == type`, expected: true},
+		{expression: `type =~ "AWS::(RDS|DynamoDB)::.*"`, expected: true},
+		{expression: `true`, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := compileRule(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, expression.match(c))
+		})
+	}
+}
+
+func TestCompileRuleErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{expression: `resource == "x"`, expected: "unknown field 'resource'. Use one of [action|kind|logicalId|path|property|replacement|section|stack|type]"},
+		{expression: `type`, expected: "expected comparison after 'type'"},
+		{expression: `type == "x`, expected: "unterminated string at position 8"},
+		{expression: `type == `, expected: "unexpected end of expression"},
+		{expression: `(type == "x"`, expected: "missing ')'"},
+		{expression: `type == "x")`, expected: "unexpected ')'"},
+		{expression: `type =~ "("`, expected: "invalid regex '(': error parsing regexp: missing closing ): `(`"},
+		{expression: `type =~ stack`, expected: "right side of '=~' must be a string"},
+		{expression: `replacement == "true"`, expected: "can not compare bool and string with '=='"},
+		{expression: `type = "x"`, expected: "unexpected character '=' at position 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := compileRule(tt.expression)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
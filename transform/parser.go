@@ -0,0 +1,517 @@
+package transform
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ChangeKind describes how a resource, property or security rule is changed
+type ChangeKind string
+
+const (
+	ChangeKindAddition ChangeKind = "addition"
+	ChangeKindRemoval  ChangeKind = "removal"
+	ChangeKindUpdate   ChangeKind = "update"
+
+	SectionIAMStatementChanges  = "IAM Statement Changes"
+	SectionIAMPolicyChanges     = "IAM Policy Changes"
+	SectionSecurityGroupChanges = "Security Group Changes"
+	SectionParameters           = "Parameters"
+	SectionResources            = "Resources"
+	SectionOutputs              = "Outputs"
+)
+
+// knownSections are the section headers cdk diff prints for each stack
+var knownSections = map[string]bool{
+	"AWSTemplateFormatVersion":  true,
+	"Transform":                 true,
+	"Description":               true,
+	SectionIAMStatementChanges:  true,
+	SectionIAMPolicyChanges:     true,
+	SectionSecurityGroupChanges: true,
+	SectionParameters:           true,
+	"Metadata":                  true,
+	"Mappings":                  true,
+	"Conditions":                true,
+	SectionResources:            true,
+	SectionOutputs:              true,
+	"Other Changes":             true,
+}
+
+// resource impacts printed by cdk diff at the end of a resource line, longest first
+var resourceImpacts = []string{"may be replaced", "replace", "destroy", "orphan"}
+
+var (
+	// https://regex101.com/r/9ORjxP/1
+	regexParserNumberOfDifferences = regexp.MustCompile(`Number of stacks with differences:\s*(\d+)`)
+	regexParserStack               = regexp.MustCompile(`^Stack (.+?)\s*$`)
+	regexParserResource            = regexp.MustCompile(`^[+-]?\[([+~-])\] (\S+)(?: (.*))?$`)
+	regexParserTree                = regexp.MustCompile(`^([ +-][ │]*)[├└]─ ?(.*)$`)
+	regexParserHunkLine            = regexp.MustCompile(`^[ +-][ │]*\[([ +-])\] ?(.*)$`)
+	regexParserMarker              = regexp.MustCompile(`^\[([+~-])\] ?(.*)$`)
+	regexParserReplacement         = regexp.MustCompile(`^(.*?) \((requires replacement|may cause replacement)\)$`)
+	regexParserNestedProperty      = regexp.MustCompile(`^\.(.+):$`)
+	regexParserAddedRemoved        = regexp.MustCompile(`^(Added|Removed): \.?(.+)$`)
+)
+
+// Diff is the structured representation of a cdk diff log
+type Diff struct {
+	Stacks []*Stack `json:"stacks"`
+	// NumberOfStacksWithDifferences as reported by cdk diff, 0 if the summary line is missing
+	NumberOfStacksWithDifferences int `json:"numberOfStacksWithDifferences"`
+}
+
+// Stack contains all changes of a single cdk stack
+type Stack struct {
+	Name     string     `json:"name"`
+	Sections []*Section `json:"sections"`
+	// Log contains the raw lines of the cdk diff log belonging to this stack
+	Log string `json:"-"`
+}
+
+// Section is a block within a stack like "IAM Statement Changes", "Resources" or "Outputs"
+type Section struct {
+	Name            string            `json:"name"`
+	Resources       []*Resource       `json:"resources,omitempty"`
+	SecurityChanges []*SecurityChange `json:"securityChanges,omitempty"`
+}
+
+// Resource is a changed resource, parameter, output or condition within a section
+type Resource struct {
+	Kind      ChangeKind `json:"kind"`
+	Type      string     `json:"type"`
+	Path      string     `json:"path,omitempty"`
+	LogicalID string     `json:"logicalId"`
+	// Impact is the deployment impact printed by cdk diff: replace, may be replaced, destroy or orphan
+	Impact string `json:"impact,omitempty"`
+	// Value holds the definition of parameters, outputs and conditions
+	Value      string            `json:"value,omitempty"`
+	Properties []*PropertyChange `json:"properties,omitempty"`
+}
+
+// PropertyChange is a changed property of a resource. Nested properties are listed in Properties.
+type PropertyChange struct {
+	Kind ChangeKind `json:"kind"`
+	// Path is the dot separated property path, e.g. Parameters.sql_mode
+	Path string `json:"path"`
+	// Replacement is either "requires replacement" or "may cause replacement"
+	Replacement string            `json:"replacement,omitempty"`
+	OldValue    string            `json:"oldValue,omitempty"`
+	NewValue    string            `json:"newValue,omitempty"`
+	Properties  []*PropertyChange `json:"properties,omitempty"`
+}
+
+// SecurityChange is a single row of the IAM or security group tables
+type SecurityChange struct {
+	Kind ChangeKind `json:"kind"`
+	// Values maps the column header to the cell content. Multi line cells are joined by newlines.
+	Values map[string]string `json:"values"`
+}
+
+// Get returns all lines of the cell for the given column header
+func (s *SecurityChange) Get(column string) []string {
+	value, ok := s.Values[column]
+	if !ok || value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+// StacksWithDifferences returns all stacks which contain at least one change
+func (d *Diff) StacksWithDifferences() []*Stack {
+	var stacks []*Stack
+	for _, stack := range d.Stacks {
+		if stack.HasDifferences() {
+			stacks = append(stacks, stack)
+		}
+	}
+	return stacks
+}
+
+// HasDifferences returns true if cdk diff printed any section for the stack
+func (s *Stack) HasDifferences() bool {
+	return len(s.Sections) > 0
+}
+
+// Section returns the section with the given name or nil if the stack has no such section
+func (s *Stack) Section(name string) *Section {
+	for _, section := range s.Sections {
+		if section.Name == name {
+			return section
+		}
+	}
+	return nil
+}
+
+// Resources returns the changes listed in the Resources section
+func (s *Stack) Resources() []*Resource {
+	section := s.Section(SectionResources)
+	if section == nil {
+		return nil
+	}
+	return section.Resources
+}
+
+// CountResources returns the number of resources changed with the given kind
+func (s *Stack) CountResources(kind ChangeKind) int {
+	count := 0
+	for _, resource := range s.Resources() {
+		if resource.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Additions returns the number of added resources
+func (s *Stack) Additions() int {
+	return s.CountResources(ChangeKindAddition)
+}
+
+// Removals returns the number of removed resources
+func (s *Stack) Removals() int {
+	return s.CountResources(ChangeKindRemoval)
+}
+
+// Updates returns the number of updated resources
+func (s *Stack) Updates() int {
+	return s.CountResources(ChangeKindUpdate)
+}
+
+// Replacements returns the number of resources that will or may be replaced
+func (s *Stack) Replacements() int {
+	count := 0
+	for _, resource := range s.Resources() {
+		if resource.RequiresReplacement() {
+			count++
+		}
+	}
+	return count
+}
+
+// IAMStatementChanges returns the rows of the IAM Statement Changes table
+func (s *Stack) IAMStatementChanges() []*SecurityChange {
+	return s.securityChanges(SectionIAMStatementChanges)
+}
+
+// IAMPolicyChanges returns the rows of the IAM Policy Changes table
+func (s *Stack) IAMPolicyChanges() []*SecurityChange {
+	return s.securityChanges(SectionIAMPolicyChanges)
+}
+
+// SecurityGroupChanges returns the rows of the Security Group Changes table
+func (s *Stack) SecurityGroupChanges() []*SecurityChange {
+	return s.securityChanges(SectionSecurityGroupChanges)
+}
+
+func (s *Stack) securityChanges(name string) []*SecurityChange {
+	section := s.Section(name)
+	if section == nil {
+		return nil
+	}
+	return section.SecurityChanges
+}
+
+// RequiresReplacement returns true if the resource or any of its properties will or may cause a replacement
+func (r *Resource) RequiresReplacement() bool {
+	if r.Impact == "replace" || r.Impact == "may be replaced" {
+		return true
+	}
+	return anyReplacement(r.Properties)
+}
+
+func anyReplacement(properties []*PropertyChange) bool {
+	for _, property := range properties {
+		if property.Replacement != "" || anyReplacement(property.Properties) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffParser keeps the state while walking line by line through the cdk diff log
+type diffParser struct {
+	diff     *Diff
+	stack    *Stack
+	section  *Section
+	resource *Resource
+	// nodes holds the last property seen for each tree depth
+	nodes []*PropertyChange
+	// hunk is the property receiving the lines of a unified diff block (@@ -1,4 +1,5 @@)
+	hunk      *PropertyChange
+	table     []string
+	inTable   bool
+	stackLogs [][]string
+}
+
+// ParseDiff parses a cdk diff log without ANSI codes into a Diff
+func ParseDiff(log string) *Diff {
+	p := &diffParser{diff: &Diff{}}
+	for _, line := range strings.Split(log, "\n") {
+		p.parseLine(strings.TrimRight(line, " \r"))
+	}
+	for i, stack := range p.diff.Stacks {
+		stack.Log = strings.Join(p.stackLogs[i], "\n")
+	}
+	return p.diff
+}
+
+func (p *diffParser) parseLine(line string) {
+	if matches := regexParserNumberOfDifferences.FindStringSubmatch(line); matches != nil {
+		p.diff.NumberOfStacksWithDifferences, _ = strconv.Atoi(matches[1])
+		p.stack = nil
+		return
+	}
+	if matches := regexParserStack.FindStringSubmatch(line); matches != nil {
+		p.startStack(matches[1])
+		p.appendLog(line)
+		return
+	}
+	if knownSections[line] {
+		p.ensureStack()
+		p.section = &Section{Name: line}
+		p.stack.Sections = append(p.stack.Sections, p.section)
+		p.resource = nil
+		p.appendLog(line)
+		return
+	}
+	if p.stack == nil {
+		// lines before the first stack like synth warnings
+		return
+	}
+	p.appendLog(line)
+	switch {
+	case strings.HasPrefix(line, "┌"):
+		p.inTable = true
+		p.table = nil
+	case p.inTable && strings.HasPrefix(line, "└"):
+		p.inTable = false
+		p.parseTable()
+	case p.inTable:
+		p.table = append(p.table, line)
+	default:
+		p.parseResourceLine(line)
+	}
+}
+
+func (p *diffParser) startStack(name string) {
+	p.stack = &Stack{Name: name}
+	p.diff.Stacks = append(p.diff.Stacks, p.stack)
+	p.stackLogs = append(p.stackLogs, nil)
+	p.section = nil
+	p.resource = nil
+}
+
+// ensureStack creates an unnamed stack for logs which do not start with a "Stack" line
+func (p *diffParser) ensureStack() {
+	if p.stack == nil {
+		p.startStack("")
+	}
+}
+
+func (p *diffParser) appendLog(line string) {
+	i := len(p.stackLogs) - 1
+	p.stackLogs[i] = append(p.stackLogs[i], line)
+}
+
+func (p *diffParser) parseResourceLine(line string) {
+	if p.section == nil {
+		return
+	}
+	if matches := regexParserResource.FindStringSubmatch(line); matches != nil {
+		p.resource = newResource(matches[1], matches[2], matches[3])
+		p.section.Resources = append(p.section.Resources, p.resource)
+		p.nodes = nil
+		p.hunk = nil
+		return
+	}
+	if p.resource == nil {
+		return
+	}
+	if matches := regexParserTree.FindStringSubmatch(line); matches != nil {
+		depth := (utf8.RuneCountInString(matches[1])-1)/4 + 1
+		p.parseProperty(depth, matches[2])
+		return
+	}
+	if p.hunk != nil {
+		if matches := regexParserHunkLine.FindStringSubmatch(line); matches != nil {
+			switch matches[1] {
+			case " ":
+				p.hunk.OldValue = appendValue(p.hunk.OldValue, matches[2])
+				p.hunk.NewValue = appendValue(p.hunk.NewValue, matches[2])
+			case "-":
+				p.hunk.OldValue = appendValue(p.hunk.OldValue, matches[2])
+			case "+":
+				p.hunk.NewValue = appendValue(p.hunk.NewValue, matches[2])
+			}
+		}
+	}
+}
+
+func newResource(symbol string, resourceType string, rest string) *Resource {
+	r := &Resource{
+		Kind: changeKindFromSymbol(symbol),
+		Type: resourceType,
+	}
+	rest = strings.TrimSpace(rest)
+	// parameters, outputs and conditions print their definition after the logical id
+	if i := strings.Index(rest, ": "); i >= 0 && len(strings.Fields(rest[:i])) <= 2 {
+		r.Value = strings.TrimSpace(rest[i+2:])
+		rest = rest[:i]
+	}
+	for _, impact := range resourceImpacts {
+		if rest == impact || strings.HasSuffix(rest, " "+impact) {
+			r.Impact = impact
+			rest = strings.TrimSpace(strings.TrimSuffix(rest, impact))
+			break
+		}
+	}
+	fields := strings.Fields(rest)
+	switch len(fields) {
+	case 0:
+	case 1:
+		r.LogicalID = fields[0]
+	default:
+		r.Path = strings.Join(fields[:len(fields)-1], " ")
+		r.LogicalID = fields[len(fields)-1]
+	}
+	return r
+}
+
+// parseProperty handles a single line of the property tree of a resource
+func (p *diffParser) parseProperty(depth int, text string) {
+	p.hunk = nil
+	if depth == 1 || len(p.nodes) == 0 {
+		property := newProperty(text, "")
+		p.resource.Properties = append(p.resource.Properties, property)
+		p.nodes = []*PropertyChange{property}
+		return
+	}
+	if depth-1 < len(p.nodes) {
+		p.nodes = p.nodes[:depth-1]
+	}
+	parent := p.nodes[len(p.nodes)-1]
+	if strings.HasPrefix(text, "@@") {
+		p.hunk = parent
+		return
+	}
+	matches := regexParserMarker.FindStringSubmatch(text)
+	if matches == nil {
+		// plain values belong to added or removed properties
+		if parent.Kind == ChangeKindRemoval {
+			parent.OldValue = appendValue(parent.OldValue, text)
+		} else {
+			parent.NewValue = appendValue(parent.NewValue, text)
+		}
+		return
+	}
+	symbol, value := matches[1], matches[2]
+	if m := regexParserAddedRemoved.FindStringSubmatch(value); m != nil {
+		kind := ChangeKindAddition
+		if m[1] == "Removed" {
+			kind = ChangeKindRemoval
+		}
+		p.addNestedProperty(parent, &PropertyChange{Kind: kind, Path: parent.Path + "." + m[2]})
+		return
+	}
+	if m := regexParserNestedProperty.FindStringSubmatch(value); m != nil || symbol == "~" {
+		name := value
+		if m != nil {
+			name = m[1]
+		}
+		p.addNestedProperty(parent, newProperty(name, parent.Path))
+		p.nodes[len(p.nodes)-1].Kind = changeKindFromSymbol(symbol)
+		return
+	}
+	switch symbol {
+	case "-":
+		parent.OldValue = appendValue(parent.OldValue, value)
+	case "+":
+		parent.NewValue = appendValue(parent.NewValue, value)
+	}
+}
+
+func (p *diffParser) addNestedProperty(parent *PropertyChange, property *PropertyChange) {
+	parent.Properties = append(parent.Properties, property)
+	p.nodes = append(p.nodes, property)
+}
+
+// newProperty parses a property line like "[~] TableName (requires replacement)"
+func newProperty(text string, parentPath string) *PropertyChange {
+	property := &PropertyChange{}
+	if matches := regexParserMarker.FindStringSubmatch(text); matches != nil {
+		property.Kind = changeKindFromSymbol(matches[1])
+		text = matches[2]
+	}
+	if matches := regexParserReplacement.FindStringSubmatch(text); matches != nil {
+		text = matches[1]
+		property.Replacement = matches[2]
+	}
+	property.Path = text
+	if parentPath != "" {
+		property.Path = parentPath + "." + text
+	}
+	return property
+}
+
+// parseTable converts the collected lines of an IAM or security group table into SecurityChanges
+func (p *diffParser) parseTable() {
+	if p.section == nil {
+		return
+	}
+	var header []string
+	var current *SecurityChange
+	for _, line := range p.table {
+		if !strings.HasPrefix(line, "│") {
+			// row separators
+			continue
+		}
+		cells := strings.Split(line, "│")
+		if len(cells) < 3 {
+			continue
+		}
+		cells = cells[1 : len(cells)-1]
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		if header == nil {
+			header = cells
+			continue
+		}
+		symbol := cells[0]
+		if symbol != "" || current == nil {
+			current = &SecurityChange{
+				Kind:   changeKindFromSymbol(symbol),
+				Values: make(map[string]string),
+			}
+			p.section.SecurityChanges = append(p.section.SecurityChanges, current)
+		}
+		for i := 1; i < len(cells) && i < len(header); i++ {
+			if cells[i] != "" {
+				current.Values[header[i]] = appendValue(current.Values[header[i]], cells[i])
+			}
+		}
+	}
+	p.table = nil
+}
+
+func changeKindFromSymbol(symbol string) ChangeKind {
+	switch symbol {
+	case "+":
+		return ChangeKindAddition
+	case "-":
+		return ChangeKindRemoval
+	case "~":
+		return ChangeKindUpdate
+	}
+	return ""
+}
+
+func appendValue(existing string, value string) string {
+	if existing == "" {
+		return value
+	}
+	return existing + "\n" + value
+}
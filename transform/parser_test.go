@@ -0,0 +1,144 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/acarl005/stripansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestFile(t *testing.T, path string) *Diff {
+	transformer := &LogTransformer{
+		Logfile: path,
+	}
+	err := transformer.readFile()
+	assert.NoError(t, err)
+	return ParseDiff(stripansi.Strip(transformer.LogContent))
+}
+
+func TestParseDiff_MultiStack(t *testing.T) {
+	diff := parseTestFile(t, "../data/cdk-multistack.log")
+	assert.Len(t, diff.Stacks, 2)
+	assert.Equal(t, "CoreIamStack", diff.Stacks[0].Name)
+	assert.False(t, diff.Stacks[0].HasDifferences())
+
+	stack := diff.Stacks[1]
+	assert.Equal(t, "CoreIamStackmain12345678eucentral1E9950359", stack.Name)
+	assert.True(t, stack.HasDifferences())
+	assert.Len(t, diff.StacksWithDifferences(), 1)
+	assert.Contains(t, stack.Log, "Stack CoreIamStackmain12345678eucentral1E9950359\nIAM Statement Changes")
+
+	statements := stack.IAMStatementChanges()
+	assert.Len(t, statements, 2)
+	assert.Equal(t, ChangeKindRemoval, statements[0].Kind)
+	assert.Equal(t, []string{"eks:Describe*", "eks:List*"}, statements[0].Get("Action"))
+	assert.Equal(t, "Allow", statements[0].Values["Effect"])
+	assert.Nil(t, statements[0].Get("Condition"))
+
+	policies := stack.IAMPolicyChanges()
+	assert.Len(t, policies, 1)
+	assert.Equal(t, ChangeKindAddition, policies[0].Kind)
+	assert.Equal(t, "arn:${AWS::Partition}:iam::12345678:policy/reset_pw", policies[0].Values["Managed Policy ARN"])
+
+	assert.Equal(t, 1, stack.Additions())
+	assert.Equal(t, 1, stack.Removals())
+	assert.Equal(t, 1, stack.Updates())
+	assert.Equal(t, 0, stack.Replacements())
+
+	removed := stack.Resources()[0]
+	assert.Equal(t, "AWS::IAM::Policy", removed.Type)
+	assert.Equal(t, "", removed.Path)
+	assert.Equal(t, "CircleCiAccessRoleDefaultPolicy8190211F", removed.LogicalID)
+	assert.Equal(t, "destroy", removed.Impact)
+
+	role := stack.Resources()[2]
+	assert.Equal(t, "main-12345678************/CircleCiAccessRole", role.Path)
+	assert.Len(t, role.Properties, 3)
+	assert.Equal(t, ChangeKindAddition, role.Properties[2].Kind)
+	assert.Equal(t, "DependsOn", role.Properties[2].Path)
+	assert.Equal(t, `["EksKubeConfigUpdate43433205","PermissionBoundaryPolicy910600B0"]`, role.Properties[2].NewValue)
+}
+
+func TestParseDiff_Replacements(t *testing.T) {
+	diff := parseTestFile(t, "../data/cdk-diff-number-diff-replace.log")
+	assert.Equal(t, 1, diff.NumberOfStacksWithDifferences)
+	assert.Len(t, diff.Stacks, 1)
+	stack := diff.Stacks[0]
+	assert.Equal(t, "db-stack", stack.Name)
+	assert.Equal(t, 4, stack.Replacements())
+
+	table := stack.Resources()[0]
+	assert.Equal(t, "AWS::DynamoDB::Table", table.Type)
+	assert.Equal(t, "ddb-table", table.Path)
+	assert.Equal(t, "ddbtable7G3B6F3F", table.LogicalID)
+	assert.Equal(t, "replace", table.Impact)
+	assert.Equal(t, &PropertyChange{
+		Kind:        ChangeKindUpdate,
+		Path:        "TableName",
+		Replacement: "requires replacement",
+		OldValue:    "ddb-table",
+		NewValue:    "ddb-table2",
+	}, table.Properties[0])
+
+	parameterGroup := stack.Resources()[2]
+	parameters := parameterGroup.Properties[2]
+	assert.Equal(t, "Parameters", parameters.Path)
+	assert.Len(t, parameters.Properties, 3)
+	assert.Equal(t, ChangeKindRemoval, parameters.Properties[0].Kind)
+	assert.Equal(t, "Parameters.query_cache_size", parameters.Properties[0].Path)
+	assert.Equal(t, "Parameters.sql_mode", parameters.Properties[2].Path)
+	assert.Equal(t, "only_full_group_by,strict_trans_tables,error_for_division_by_zero,no_engine_substitution", parameters.Properties[2].NewValue)
+
+	instance := stack.Resources()[3]
+	assert.Equal(t, "may be replaced", instance.Impact)
+	assert.True(t, instance.RequiresReplacement())
+	assert.Equal(t, "may cause replacement", instance.Properties[0].Replacement)
+	assert.Equal(t, "DBParameterGroupName.Ref", instance.Properties[0].Properties[0].Path)
+}
+
+func TestParseDiff_SectionsAndHunks(t *testing.T) {
+	diff := parseTestFile(t, "../data/cdk-diff1.log")
+	assert.Len(t, diff.Stacks, 1)
+	stack := diff.Stacks[0]
+	var names []string
+	for _, section := range stack.Sections {
+		names = append(names, section.Name)
+	}
+	assert.Equal(t, []string{SectionIAMStatementChanges, SectionIAMPolicyChanges, SectionParameters, SectionResources, SectionOutputs}, names)
+	assert.Len(t, stack.IAMStatementChanges(), 8)
+	assert.Equal(t, []string{"secretsmanager:DescribeSecret", "secretsmanager:GetSecretValue"}, stack.IAMStatementChanges()[2].Get("Action"))
+
+	parameter := stack.Section(SectionParameters).Resources[0]
+	assert.Equal(t, "Parameter", parameter.Type)
+	assert.Equal(t, "AssetParameters/123456/S3Bucket", parameter.Path)
+	assert.Equal(t, "AssetParameters123456S3BucketBEE108A9", parameter.LogicalID)
+	assert.Equal(t, `{"Type":"String","Description":"S3 bucket for asset \"123456\""}`, parameter.Value)
+
+	output := stack.Section(SectionOutputs).Resources[0]
+	assert.Equal(t, "Output", output.Type)
+	assert.Equal(t, "ExampleInitScriptOutput", output.LogicalID)
+
+	subnetGroup := stack.Resources()[13]
+	assert.Equal(t, "AWS::RDS::DBSubnetGroup", subnetGroup.Type)
+	subnetIds := subnetGroup.Properties[0]
+	assert.Equal(t, "SubnetIds", subnetIds.Path)
+	assert.Equal(t, "[\n  \"subnet-02aa6d56dd115b47f\",\n  \"subnet-0b934832beb3a0a6e\"\n]", subnetIds.OldValue)
+	assert.Equal(t, "[\n  \"subnet-0726e6f9683ceea51\",\n  \"subnet-0b934832beb3a0a6e\",\n  \"subnet-02aa6d56dd115b47f\"\n]", subnetIds.NewValue)
+}
+
+func TestParseDiff_WithoutStackHeader(t *testing.T) {
+	diff := parseTestFile(t, "../data/cdk-small.log")
+	assert.Len(t, diff.Stacks, 1)
+	assert.Equal(t, "", diff.Stacks[0].Name)
+	assert.Equal(t, 1, diff.Stacks[0].Additions())
+	assert.Equal(t, 1, diff.Stacks[0].Updates())
+	assert.Equal(t, 1, diff.Stacks[0].Removals())
+}
+
+func TestParseDiff_NoChanges(t *testing.T) {
+	diff := ParseDiff("Stack SuiteRedisStack\nThere were no differences\n\n✨  Number of stacks with differences: 0\n")
+	assert.Len(t, diff.Stacks, 1)
+	assert.False(t, diff.Stacks[0].HasDifferences())
+	assert.Empty(t, diff.StacksWithDifferences())
+	assert.Equal(t, "Stack SuiteRedisStack\nThere were no differences\n", diff.Stacks[0].Log)
+}
@@ -0,0 +1,77 @@
+package transform
+
+import (
+	"fmt"
+	"path"
+)
+
+const (
+	// ProtectionReasonRemoval is used when a protected resource is removed
+	ProtectionReasonRemoval = "removal"
+	// ProtectionReasonReplacement is used when a protected resource will or may be replaced
+	ProtectionReasonReplacement = "replacement"
+)
+
+// ProtectedResourceViolation is a protected resource which is removed or replaced by the diff
+type ProtectedResourceViolation struct {
+	Stack     string `json:"stack"`
+	Type      string `json:"type"`
+	LogicalID string `json:"logicalId"`
+	Path      string `json:"path,omitempty"`
+	// Reason is either removal or replacement
+	Reason string `json:"reason"`
+}
+
+func (v ProtectedResourceViolation) String() string {
+	return fmt.Sprintf("%s %s %s in stack %s", v.Reason, v.Type, v.LogicalID, v.Stack)
+}
+
+// checkProtectedResources collects all resources matching the protected resource types or logical ids which are removed or replaced
+func (t *LogTransformer) checkProtectedResources() {
+	t.ProtectedResourceViolations = nil
+	if t.Diff == nil || (len(t.ProtectedResourceTypes) == 0 && len(t.ProtectedLogicalIDs) == 0) {
+		return
+	}
+	for _, stack := range t.Diff.Stacks {
+		section := stack.Section(SectionResources)
+		if section == nil {
+			continue
+		}
+		for _, resource := range section.Resources {
+			if !t.isProtected(resource) {
+				continue
+			}
+			reason := ""
+			if resource.Kind == ChangeKindRemoval {
+				reason = ProtectionReasonRemoval
+			} else if resource.RequiresReplacement() {
+				reason = ProtectionReasonReplacement
+			}
+			if reason == "" {
+				continue
+			}
+			t.ProtectedResourceViolations = append(t.ProtectedResourceViolations, ProtectedResourceViolation{
+				Stack:     stack.Name,
+				Type:      resource.Type,
+				LogicalID: resource.LogicalID,
+				Path:      resource.Path,
+				Reason:    reason,
+			})
+		}
+	}
+}
+
+// isProtected returns true if the resource type or logical id matches one of the protected glob patterns
+func (t *LogTransformer) isProtected(resource *Resource) bool {
+	return matchesAny(t.ProtectedResourceTypes, resource.Type) || matchesAny(t.ProtectedLogicalIDs, resource.LogicalID)
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		// patterns are validated by the config, invalid patterns never match
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
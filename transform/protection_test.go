@@ -0,0 +1,72 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTransformer_CheckProtectedResources(t *testing.T) {
+	log := `Stack db-stack
+Resources
+[-] AWS::S3::Bucket Assets AssetsBucket5CB76180 destroy
+[~] AWS::DynamoDB::Table Orders OrdersTable315BB997 replace
+ └─ [~] TableName (requires replacement)
+[~] AWS::Lambda::Function Handler HandlerFunction5A4E4B1D
+ └─ [~] Timeout
+[+] AWS::RDS::DBInstance Database DatabaseB269D8BB
+`
+	tests := []struct {
+		name          string
+		resourceTypes []string
+		logicalIDs    []string
+		expected      []ProtectedResourceViolation
+	}{
+		{
+			name: "NoPatterns",
+		},
+		{
+			name:          "ResourceTypes",
+			resourceTypes: []string{"AWS::DynamoDB::Table", "AWS::RDS::*", "AWS::Lambda::Function"},
+			expected: []ProtectedResourceViolation{
+				{Stack: "db-stack", Type: "AWS::DynamoDB::Table", LogicalID: "OrdersTable315BB997", Path: "Orders", Reason: ProtectionReasonReplacement},
+			},
+		},
+		{
+			name:       "LogicalIDs",
+			logicalIDs: []string{"Assets*"},
+			expected: []ProtectedResourceViolation{
+				{Stack: "db-stack", Type: "AWS::S3::Bucket", LogicalID: "AssetsBucket5CB76180", Path: "Assets", Reason: ProtectionReasonRemoval},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := &LogTransformer{
+				Diff:                   ParseDiff(log),
+				ProtectedResourceTypes: tt.resourceTypes,
+				ProtectedLogicalIDs:    tt.logicalIDs,
+			}
+			transformer.checkProtectedResources()
+			assert.Equal(t, tt.expected, transformer.ProtectedResourceViolations)
+		})
+	}
+}
+
+func TestLogTransformer_ProtectedResourcesInComment(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-diff-number-diff-replace.log",
+		TagID:                    "replace",
+		NoTruncate:               true,
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+		ProtectedResourceTypes:   []string{"AWS::RDS::DBInstance"},
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	assert.Len(t, transformer.ProtectedResourceViolations, 1)
+	assert.Equal(t, "replacement AWS::RDS::DBInstance SpmArchivePrimary8D438481 in stack db-stack", transformer.ProtectedResourceViolations[0].String())
+	assert.Contains(t, transformer.LogContent, "🚫 Protected resources are removed or replaced:\n- replacement of AWS::RDS::DBInstance SpmArchivePrimary8D438481 in stack db-stack")
+	assert.Equal(t, transformer.ProtectedResourceViolations, transformer.Report().ProtectedResourceViolations)
+}
@@ -0,0 +1,116 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+)
+
+// ReportSchemaVersion is increased whenever a field of the JSON report is renamed or removed.
+// Adding new fields is not considered a breaking change.
+const ReportSchemaVersion = 1
+
+// Report is the machine-readable representation of a processed cdk diff log
+type Report struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	TagID         string         `json:"tagId"`
+	Summary       ReportSummary  `json:"summary"`
+	Stacks        []*StackReport `json:"stacks"`
+	// ProtectedResourceViolations is only set when protected resources are removed or replaced
+	ProtectedResourceViolations []ProtectedResourceViolation `json:"protectedResourceViolations,omitempty"`
+	// RuleResults is only set when rules match changes of the diff
+	RuleResults []RuleResult `json:"ruleResults,omitempty"`
+}
+
+// ReportSummary contains the totals over all stacks
+type ReportSummary struct {
+	StacksWithDifferences int                       `json:"stacksWithDifferences"`
+	TotalChanges          int                       `json:"totalChanges"`
+	HashChanges           int                       `json:"hashChanges"`
+	NumberReplaces        int                       `json:"numberReplaces"`
+	ChangedResources      map[string]ResourceMetric `json:"changedResources"`
+}
+
+// StackReport contains the parsed sections of a single stack together with its counts
+type StackReport struct {
+	Name           string     `json:"name"`
+	HasDifferences bool       `json:"hasDifferences"`
+	Additions      int        `json:"additions"`
+	Updates        int        `json:"updates"`
+	Removals       int        `json:"removals"`
+	Replacements   int        `json:"replacements"`
+	Sections       []*Section `json:"sections"`
+}
+
+// Report creates the machine-readable report. Process has to be called before.
+func (t *LogTransformer) Report() *Report {
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		TagID:         t.TagID,
+		Summary: ReportSummary{
+			TotalChanges:     t.TotalChanges,
+			HashChanges:      t.HashChanges,
+			NumberReplaces:   t.NumberReplaces,
+			ChangedResources: t.ChangedBaseResource,
+		},
+		Stacks:                      []*StackReport{},
+		ProtectedResourceViolations: t.ProtectedResourceViolations,
+		RuleResults:                 t.RuleResults,
+	}
+	if report.Summary.ChangedResources == nil {
+		report.Summary.ChangedResources = map[string]ResourceMetric{}
+	}
+	if t.Diff == nil {
+		return report
+	}
+	for _, stack := range t.Diff.Stacks {
+		stackReport := &StackReport{
+			Name:           stack.Name,
+			HasDifferences: stack.HasDifferences(),
+			Additions:      stack.Additions(),
+			Updates:        stack.Updates(),
+			Removals:       stack.Removals(),
+			Replacements:   stack.Replacements(),
+			Sections:       stack.Sections,
+		}
+		if stackReport.Sections == nil {
+			stackReport.Sections = []*Section{}
+		}
+		if stackReport.HasDifferences {
+			report.Summary.StacksWithDifferences++
+		}
+		report.Stacks = append(report.Stacks, stackReport)
+	}
+	return report
+}
+
+// writeReportFile is writing the JSON report to file and appends .json to filename.
+// In no-post-mode the report is additionally streamed to stdout instead of the markdown diff.
+// When the log was read from stdin the report is only streamed to stdout.
+func (t *LogTransformer) writeReportFile() error {
+	if t.Output != config.OutputJSON {
+		return nil
+	}
+	content, err := json.MarshalIndent(t.Report(), "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	if t.Logfile == config.LogFileStdin {
+		// there is no log file to write next to, so the report is always streamed to stdout
+		fmt.Print(string(content))
+		return nil
+	}
+	filePath := t.Logfile + ".json"
+	// read/write for the owner, and read-only for the group and others
+	err = os.WriteFile(filePath, content, 0644)
+	if err != nil {
+		return err
+	}
+	if t.NoPostMode {
+		fmt.Print(string(content))
+	}
+	return nil
+}
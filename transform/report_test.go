@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTransformer_Report(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-diff-number-diff-replace.log",
+		TagID:                    "replace",
+		NoTruncate:               true,
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	report := transformer.Report()
+	assert.Equal(t, ReportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, "replace", report.TagID)
+	assert.Equal(t, 1, report.Summary.StacksWithDifferences)
+	assert.Equal(t, transformer.TotalChanges, report.Summary.TotalChanges)
+	assert.Equal(t, 5, report.Summary.NumberReplaces)
+	assert.Equal(t, ResourceMetric{Count: 2, Replaced: true}, report.Summary.ChangedResources["AWS::DynamoDB::Table"])
+	assert.Len(t, report.Stacks, 1)
+	assert.Equal(t, "db-stack", report.Stacks[0].Name)
+	assert.Equal(t, 4, report.Stacks[0].Updates)
+	assert.Equal(t, 4, report.Stacks[0].Replacements)
+}
+
+func TestLogTransformer_ReportWithoutDiff(t *testing.T) {
+	transformer := &LogTransformer{TagID: "empty"}
+	content, err := json.Marshal(transformer.Report())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"tagId": "empty",
+		"summary": {"stacksWithDifferences": 0, "totalChanges": 0, "hashChanges": 0, "numberReplaces": 0, "changedResources": {}},
+		"stacks": []
+	}`, string(content))
+}
+
+func TestLogTransformer_ReportJSON(t *testing.T) {
+	transformer := &LogTransformer{
+		TagID: "small",
+		Diff:  ParseDiff("Stack small\nResources\n[-] AWS::S3::Bucket bucket Bucket83908E77 destroy\n"),
+	}
+	content, err := json.Marshal(transformer.Report().Stacks)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"name": "small",
+		"hasDifferences": true,
+		"additions": 0,
+		"updates": 0,
+		"removals": 1,
+		"replacements": 0,
+		"sections": [{
+			"name": "Resources",
+			"resources": [{"kind": "removal", "type": "AWS::S3::Bucket", "path": "bucket", "logicalId": "Bucket83908E77", "impact": "destroy"}]
+		}]
+	}]`, string(content))
+}
+
+func TestLogTransformer_WriteReportFile(t *testing.T) {
+	file := "../data/cdk-small.log"
+	fileReport := "../data/cdk-small.log.json"
+	fileDiff := "../data/cdk-small.log.diff"
+	transformer := &LogTransformer{
+		Logfile:    file,
+		TagID:      "small",
+		NoPostMode: true,
+		Output:     config.OutputMarkdown,
+	}
+
+	defer func() {
+		if err := os.Remove(fileReport); err != nil {
+			t.Logf("failed to remove report file: %v", err)
+		}
+	}()
+
+	err := transformer.writeReportFile()
+	assert.NoError(t, err)
+	assert.NoFileExistsf(t, fileReport, "Expect report file not be found when output is markdown")
+
+	transformer.Output = config.OutputJSON
+	err = transformer.writeReportFile()
+	assert.NoError(t, err)
+	assert.FileExistsf(t, fileReport, "Expect report file to be found")
+
+	err = transformer.writeDiffFile()
+	assert.NoError(t, err)
+	assert.NoFileExistsf(t, fileDiff, "Expect diff file not be found when output is json")
+
+	transformer.Logfile = "/tmp/nonexisting-dir/nofile"
+	err = transformer.writeReportFile()
+	assert.Error(t, err)
+}
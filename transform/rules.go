@@ -0,0 +1,155 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+)
+
+// RuleResult is a rule matching a change of the diff
+type RuleResult struct {
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Label   string `json:"label,omitempty"`
+	Message string `json:"message,omitempty"`
+	Stack   string `json:"stack"`
+	// Subject is the logical id of the resource or the IAM action of the change
+	Subject string `json:"subject"`
+}
+
+func (r RuleResult) String() string {
+	return fmt.Sprintf("%s: %s (%s in stack %s)", r.Rule, r.Message, r.Subject, r.Stack)
+}
+
+// ValidateRules compiles the when expressions of all rules and returns the error of the first invalid expression
+func ValidateRules(rules []config.Rule) error {
+	_, err := compileRules(rules)
+	return err
+}
+
+// compileRules compiles the when expression of every rule in the order of the rules
+func compileRules(rules []config.Rule) ([]ruleExpression, error) {
+	var expressions []ruleExpression
+	for _, rule := range rules {
+		expression, err := compileRule(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("invalid when '%s' for rule '%s': %w", rule.When, rule.Name, err)
+		}
+		expressions = append(expressions, expression)
+	}
+	return expressions, nil
+}
+
+// evaluateRules matches all rules against every change of the parsed diff. The rules must be compiled before.
+// A rule matches a change at most once, even if several properties or actions of the change match.
+func (t *LogTransformer) evaluateRules() {
+	t.RuleResults = nil
+	if t.Diff == nil || len(t.Rules) == 0 {
+		return
+	}
+	contexts := ruleContexts(t.Diff)
+	for i, rule := range t.Rules {
+		expression := t.ruleExpressions[i]
+		matched := make(map[string]bool)
+		for _, c := range contexts {
+			key := c.stack + "/" + c.subject
+			if matched[key] || !expression.match(c) {
+				continue
+			}
+			matched[key] = true
+			t.RuleResults = append(t.RuleResults, RuleResult{
+				Rule:    rule.Name,
+				Action:  rule.Action,
+				Label:   rule.Label,
+				Message: rule.Message,
+				Stack:   c.stack,
+				Subject: c.subject,
+			})
+		}
+	}
+}
+
+// RuleResultsByAction returns all rule results with the given action
+func (t *LogTransformer) RuleResultsByAction(action string) []RuleResult {
+	var results []RuleResult
+	for _, result := range t.RuleResults {
+		if result.Action == action {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// RuleLabels returns the distinct labels of all matching rules with action label
+func (t *LogTransformer) RuleLabels() []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, result := range t.RuleResultsByAction(config.RuleActionLabel) {
+		if !seen[result.Label] {
+			seen[result.Label] = true
+			labels = append(labels, result.Label)
+		}
+	}
+	return labels
+}
+
+// ruleContexts creates a context for every resource property and every action of the security changes.
+// Resources without properties and security changes without actions get a single context.
+func ruleContexts(diff *Diff) []*ruleContext {
+	var contexts []*ruleContext
+	for _, stack := range diff.Stacks {
+		for _, section := range stack.Sections {
+			for _, resource := range section.Resources {
+				base := ruleContext{
+					stack:       stack.Name,
+					section:     section.Name,
+					resource:    resource.Type,
+					logicalID:   resource.LogicalID,
+					path:        resource.Path,
+					kind:        resource.Kind,
+					replacement: resource.RequiresReplacement(),
+					subject:     resource.LogicalID,
+				}
+				properties := propertyPaths(resource.Properties)
+				if len(properties) == 0 {
+					properties = []string{""}
+				}
+				for _, property := range properties {
+					c := base
+					c.property = property
+					contexts = append(contexts, &c)
+				}
+			}
+			for _, change := range section.SecurityChanges {
+				actions := change.Get("Action")
+				if len(actions) == 0 {
+					actions = []string{""}
+				}
+				for _, action := range actions {
+					c := &ruleContext{
+						stack:   stack.Name,
+						section: section.Name,
+						kind:    change.Kind,
+						action:  action,
+						subject: action,
+					}
+					if action == "" {
+						c.subject = section.Name
+					}
+					contexts = append(contexts, c)
+				}
+			}
+		}
+	}
+	return contexts
+}
+
+// propertyPaths returns the paths of all properties including nested properties
+func propertyPaths(properties []*PropertyChange) []string {
+	var paths []string
+	for _, property := range properties {
+		paths = append(paths, property.Path)
+		paths = append(paths, propertyPaths(property.Properties)...)
+	}
+	return paths
+}
@@ -0,0 +1,94 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTransformer_EvaluateRules(t *testing.T) {
+	log := `Stack prod-db
+Resources
+[~] AWS::DynamoDB::Table Orders OrdersTable315BB997 replace
+ ├─ [~] KeySchema (requires replacement)
+ └─ [~] TableName (requires replacement)
+[+] AWS::Lambda::Function Handler HandlerFunction5A4E4B1D
+Stack dev-db
+Resources
+[~] AWS::DynamoDB::Table Orders OrdersTable315BB997 replace
+ └─ [~] TableName (requires replacement)
+`
+	transformer := &LogTransformer{
+		Diff: ParseDiff(log),
+		Rules: []config.Rule{
+			{Name: "prod-replacement", When: `stack =~ "^prod-" && replacement`, Action: config.RuleActionFail, Message: "Replacement in production"},
+			{Name: "new-functions", When: `type == "AWS::Lambda::Function" && kind == "addition"`, Action: config.RuleActionWarn, Message: "New function"},
+			{Name: "table-name", When: `property == "TableName"`, Action: config.RuleActionLabel, Label: "database", Message: "Table renamed"},
+		},
+	}
+	var err error
+	transformer.ruleExpressions, err = compileRules(transformer.Rules)
+	assert.NoError(t, err)
+	transformer.evaluateRules()
+
+	assert.Equal(t, []RuleResult{
+		{Rule: "prod-replacement", Action: config.RuleActionFail, Message: "Replacement in production", Stack: "prod-db", Subject: "OrdersTable315BB997"},
+		{Rule: "new-functions", Action: config.RuleActionWarn, Message: "New function", Stack: "prod-db", Subject: "HandlerFunction5A4E4B1D"},
+		{Rule: "table-name", Action: config.RuleActionLabel, Label: "database", Message: "Table renamed", Stack: "prod-db", Subject: "OrdersTable315BB997"},
+		{Rule: "table-name", Action: config.RuleActionLabel, Label: "database", Message: "Table renamed", Stack: "dev-db", Subject: "OrdersTable315BB997"},
+	}, transformer.RuleResults)
+	assert.Len(t, transformer.RuleResultsByAction(config.RuleActionFail), 1)
+	assert.Equal(t, []string{"database"}, transformer.RuleLabels())
+	assert.Equal(t, "prod-replacement: Replacement in production (OrdersTable315BB997 in stack prod-db)", transformer.RuleResults[0].String())
+}
+
+func TestLogTransformer_EvaluateRulesOnIAMActions(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-multistack.log",
+		TagID:                    "multistack",
+		NoTruncate:               true,
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+		Rules: []config.Rule{
+			{Name: "iam-wildcard", When: `section == "IAM Statement Changes" && action =~ '\*$'`, Action: config.RuleActionWarn, Message: "Wildcard action"},
+		},
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	assert.Len(t, transformer.RuleResults, 2)
+	assert.Equal(t, "eks:Describe*", transformer.RuleResults[0].Subject)
+	assert.Equal(t, "eks:List*", transformer.RuleResults[1].Subject)
+	assert.Contains(t, transformer.LogContent, "⚠️ iam-wildcard: Wildcard action (eks:Describe* in stack ")
+	assert.Equal(t, transformer.RuleResults, transformer.Report().RuleResults)
+}
+
+func TestLogTransformer_EvaluateRulesWithStackTransformers(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-multistack.log",
+		TagID:                    "multistack",
+		Vcs:                      "github",
+		Template:                 "default",
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+		Rules: []config.Rule{
+			{Name: "iam-wildcard", When: `section == "IAM Statement Changes" && action =~ '\*$'`, Action: config.RuleActionWarn, Message: "Wildcard action"},
+		},
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	stackTransformers := transformer.StackTransformers()
+	assert.Len(t, stackTransformers, 1)
+	assert.Equal(t, transformer.RuleResults, stackTransformers[0].RuleResults, "expect the rules to be evaluated for every stack")
+	assert.Contains(t, stackTransformers[0].LogContent, "⚠️ iam-wildcard: Wildcard action (eks:Describe* in stack ")
+}
+
+func TestValidateRules(t *testing.T) {
+	assert.NoError(t, ValidateRules(nil))
+	assert.NoError(t, ValidateRules([]config.Rule{{Name: "replacement", When: "replacement", Action: config.RuleActionWarn}}))
+	err := ValidateRules([]config.Rule{
+		{Name: "replacement", When: "replacement", Action: config.RuleActionWarn},
+		{Name: "broken", When: `type == `, Action: config.RuleActionFail},
+	})
+	assert.ErrorContains(t, err, "invalid when 'type == ' for rule 'broken'")
+}
@@ -0,0 +1,115 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/karlderkaefer/cdk-notifier/provider"
+	"github.com/sirupsen/logrus"
+)
+
+// maxParts is used to reserve space for the longest possible part header
+const maxParts = 999
+
+var regexSplitResource = regexp.MustCompile(`^[+-]?\[[+~-]\] `)
+
+// splitComments splits the transformed diff into several comments when it is longer than the max comment length.
+// The diff is split at stack, section and resource boundaries. Only resources longer than a whole comment are split by lines
+// and only lines longer than a whole comment are split within the line.
+func (t *LogTransformer) splitComments() {
+	t.Parts = nil
+	maxCommentLength := t.maxCommentLength()
+	if !t.SplitComments || t.NoTruncate || maxCommentLength == 0 {
+		return
+	}
+	// render the longest possible header without content to know the space left for the diff
+	limit := maxCommentLength - utf8.RuneCountInString(t.renderPart("", maxParts, maxParts))
+	if limit <= 0 {
+		logrus.Warnf("Template is longer than max comment length %d, falling back to truncation", maxCommentLength)
+		return
+	}
+	if utf8.RuneCountInString(t.LogContent) <= limit {
+		return
+	}
+	chunks := packBlocks(splitBlocks(t.LogContent), limit)
+	for i, chunk := range chunks {
+		t.Parts = append(t.Parts, t.renderPart(chunk, i+1, len(chunks)))
+	}
+	logrus.Infof("Split diff for tag id %s into %d comments", t.TagID, len(t.Parts))
+	t.LogContent = strings.Join(t.Parts, "\n")
+}
+
+// renderPart renders a single comment of a split diff
+func (t *LogTransformer) renderPart(content string, part int, totalParts int) string {
+	template := t.newCommentTemplate(content)
+	template.TagID = provider.PartTagID(t.TagID, part)
+	template.Part = part
+	template.TotalParts = totalParts
+	rendered, err := template.render()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return rendered
+}
+
+// isBlockBoundary returns true if the line starts a new stack, section or resource
+func isBlockBoundary(line string) bool {
+	return regexParserStack.MatchString(line) || knownSections[strings.TrimSpace(line)] || regexSplitResource.MatchString(line)
+}
+
+// splitBlocks splits the diff into blocks that each start with a stack, section or resource line
+func splitBlocks(content string) []string {
+	var blocks []string
+	var current []string
+	for _, line := range strings.Split(content, "\n") {
+		if isBlockBoundary(line) && len(current) > 0 {
+			blocks = append(blocks, strings.Join(current, "\n"))
+			current = nil
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// packBlocks joins as many consecutive blocks as possible into chunks not longer than limit runes
+func packBlocks(blocks []string, limit int) []string {
+	var chunks []string
+	var current []string
+	currentLength := 0
+	add := func(piece string) {
+		length := utf8.RuneCountInString(piece)
+		if len(current) > 0 && currentLength+1+length > limit {
+			chunks = append(chunks, strings.Join(current, "\n"))
+			current = nil
+		}
+		if len(current) == 0 {
+			currentLength = length
+		} else {
+			currentLength += 1 + length
+		}
+		current = append(current, piece)
+	}
+	for _, block := range blocks {
+		if utf8.RuneCountInString(block) <= limit {
+			add(block)
+			continue
+		}
+		// a single resource longer than a whole comment is split by lines
+		for _, line := range strings.Split(block, "\n") {
+			// lines longer than a whole comment are continued in the next comment
+			for runes := []rune(line); len(runes) > limit; runes = runes[limit:] {
+				add(string(runes[:limit]))
+				line = string(runes[limit:])
+			}
+			add(line)
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, "\n"))
+	}
+	return chunks
+}
@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBlocks(t *testing.T) {
+	content := "Stack a\nResources\n+[+] AWS::S3::Bucket b B1\n ├─ [+] Foo\n[~] AWS::S3::Bucket c C1\nOutputs\n+[+] Output o O1\nStack b\nThere were no differences"
+	assert.Equal(t, []string{
+		"Stack a",
+		"Resources",
+		"+[+] AWS::S3::Bucket b B1\n ├─ [+] Foo",
+		"[~] AWS::S3::Bucket c C1",
+		"Outputs",
+		"+[+] Output o O1",
+		"Stack b\nThere were no differences",
+	}, splitBlocks(content))
+}
+
+func TestPackBlocks(t *testing.T) {
+	assert.Equal(t, []string{"aaa\nbbb", "cccc\ndd", "e"}, packBlocks([]string{"aaa", "bbb", "cccc", "dd", "e"}, 7))
+	// blocks longer than the limit are split by lines and long lines are continued in the next chunk
+	assert.Equal(t, []string{"a\nbbbb", "cc", "xyzxyzx", "yz\nz"}, packBlocks([]string{"a", "bbbb\ncc\nxyzxyzxyz\nz"}, 7))
+	// a single line longer than several chunks
+	assert.Equal(t, []string{"abcdefg", "hijklmn", "op"}, packBlocks([]string{"abcdefghijklmnop"}, 7))
+	assert.Equal(t, []string{"abcdefg"}, packBlocks([]string{"abcdefg"}, 7))
+	assert.Nil(t, packBlocks(nil, 7))
+}
+
+func TestLogTransformer_SplitComments(t *testing.T) {
+	maxLength := 2000
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-diff1.log",
+		TagID:                    "split",
+		Vcs:                      config.VcsGithubEnterprise,
+		GithubMaxCommentLength:   maxLength,
+		Template:                 "default",
+		SplitComments:            true,
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	assert.Greater(t, len(transformer.Parts), 2)
+	total := len(transformer.Parts)
+	var contents []string
+	for i, part := range transformer.Parts {
+		assert.LessOrEqual(t, utf8.RuneCountInString(part), maxLength)
+		assert.NotContains(t, part, "Truncated output")
+		tagID := "split"
+		if i > 0 {
+			tagID = fmt.Sprintf("split#%d", i+1)
+		}
+		assert.Contains(t, part, fmt.Sprintf("## cdk diff for %s (part %d/%d)", tagID, i+1, total))
+		contents = append(contents, part)
+	}
+	joined := strings.Join(contents, "\n")
+	assert.Contains(t, joined, "Stack SuiteRdsStack")
+	assert.Contains(t, joined, "[+] Output ExampleInitScriptOutput ExampleInitScriptOutput")
+
+	comment := transformer.ManagedComment()
+	assert.Equal(t, "split", comment.TagID)
+	assert.Equal(t, transformer.Parts[0], comment.Content)
+	assert.Equal(t, transformer.Parts[1:], comment.Continuations)
+}
+
+func TestLogTransformer_SplitCommentsShortDiff(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-small.log",
+		TagID:                    "small",
+		Vcs:                      config.VcsBitbucket,
+		Template:                 "default",
+		SplitComments:            true,
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	assert.Nil(t, transformer.Parts)
+	assert.NotContains(t, transformer.LogContent, "(part ")
+	assert.Equal(t, transformer.LogContent, transformer.ManagedComment().Content)
+	assert.Nil(t, transformer.ManagedComment().Continuations)
+}
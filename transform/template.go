@@ -2,15 +2,28 @@ package transform
 
 import (
 	"bytes"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/karlderkaefer/cdk-notifier/provider"
 	"github.com/sirupsen/logrus"
 )
 
 var defaultTemplate = `
-{{ .HeaderPrefix }} {{ .TagID }} {{ .JobLink }}
+{{ .HeaderPrefix }} {{ .TagID }}{{ if .TotalParts }} (part {{ .Part }}/{{ .TotalParts }}){{ end }} {{ .JobLink }}
+{{- if .ProtectedResources }}
+🚫 Protected resources are removed or replaced:
+{{- range .ProtectedResources }}
+- {{ .Reason }} of {{ .Type }} {{ .LogicalID }} in stack {{ .Stack }}
+{{- end }}
+{{- end }}
+{{- range .RuleResults }}
+{{ if eq .Action "fail" }}❌{{ else if eq .Action "warn" }}⚠️{{ else }}🏷️{{ end }} {{ .Rule }}: {{ .Message }} ({{ .Subject }} in stack {{ .Stack }})
+{{- end }}
 {{- if .Collapsible }}
 <details>
 <summary>Click to expand</summary>
@@ -25,7 +38,16 @@ var defaultTemplate = `
 `
 
 var extendedTemplate = `
-{{ .HeaderPrefix }} {{ .TagID }} {{ .JobLink }}
+{{ .HeaderPrefix }} {{ .TagID }}{{ if .TotalParts }} (part {{ .Part }}/{{ .TotalParts }}){{ end }} {{ .JobLink }}
+{{- if .ProtectedResources }}
+🚫 Protected resources are removed or replaced:
+{{- range .ProtectedResources }}
+- {{ .Reason }} of {{ .Type }} {{ .LogicalID }} in stack {{ .Stack }}
+{{- end }}
+{{- end }}
+{{- range .RuleResults }}
+{{ if eq .Action "fail" }}❌{{ else if eq .Action "warn" }}⚠️{{ else }}🏷️{{ end }} {{ .Rule }}: {{ .Message }} ({{ .Subject }} in stack {{ .Stack }})
+{{- end }}
 {{ .NumberOfDifferencesString }}
 {{- if .NumberReplaces }}
 ⚠️ Number of resources that require replacement: {{ .NumberReplaces }}
@@ -44,7 +66,16 @@ var extendedTemplate = `
 `
 
 var extendedWithResourcesTemplate = `
-{{ .HeaderPrefix }} {{ .TagID }} {{ .JobLink }}
+{{ .HeaderPrefix }} {{ .TagID }}{{ if .TotalParts }} (part {{ .Part }}/{{ .TotalParts }}){{ end }} {{ .JobLink }}
+{{- if .ProtectedResources }}
+🚫 Protected resources are removed or replaced:
+{{- range .ProtectedResources }}
+- {{ .Reason }} of {{ .Type }} {{ .LogicalID }} in stack {{ .Stack }}
+{{- end }}
+{{- end }}
+{{- range .RuleResults }}
+{{ if eq .Action "fail" }}❌{{ else if eq .Action "warn" }}⚠️{{ else }}🏷️{{ end }} {{ .Rule }}: {{ .Message }} ({{ .Subject }} in stack {{ .Stack }})
+{{- end }}
 {{ .NumberOfDifferencesString }}
 {{- if .NumberReplaces }}
 ⚠️ Number of resources that require replacement: {{ .NumberReplaces }}
@@ -71,8 +102,15 @@ var extendedWithResourcesTemplate = `
 // commentTemplate wrapper object to use go templating
 type commentTemplate struct {
 	TagID                     string
+	StackName                 string // only set when comments are split per stack
 	Content                   string
+	Part                      int // number of the comment starting at 1 when the diff is split
+	TotalParts                int // number of comments the diff is split into, 0 if not split
 	JobLink                   string
+	CommitSha                 string
+	ShortSha                  string // first 7 chars of the commit sha
+	Branch                    string
+	Timestamp                 string // time of the run in RFC3339
 	Backticks                 string
 	HeaderPrefix              string
 	Collapsible               bool
@@ -80,6 +118,9 @@ type commentTemplate struct {
 	NumberOfDifferencesString string
 	NumberReplaces            int
 	ChangedBaseResource       map[string]ResourceMetric
+	Diff                      *Diff
+	ProtectedResources        []ProtectedResourceViolation
+	RuleResults               []RuleResult
 	Template                  string // template type
 	customTemplate            string // template file or string
 }
@@ -171,5 +212,39 @@ func (t *commentTemplate) render() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return stringWriter.String(), nil
+	return markTimestamp(stringWriter.String(), t.Timestamp), nil
+}
+
+// markTimestamp precedes the timestamp of the run with the invisible provider.TimestampMarker, so it is ignored when comparing comments.
+// Code blocks are left as they are because the marker would be visible there.
+func markTimestamp(rendered string, timestamp string) string {
+	if timestamp == "" || !strings.Contains(rendered, timestamp) {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	inCodeBlock := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock {
+			lines[i] = strings.ReplaceAll(line, timestamp, provider.TimestampMarker+timestamp)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateTemplate renders the template of the transformer for an empty diff to detect syntax and field errors
+func (t *LogTransformer) ValidateTemplate() error {
+	templates := []string{"default", "extended", "extendedWithResources"}
+	if t.CustomTemplate == "" && !slices.Contains(templates, t.Template) {
+		return fmt.Errorf("unknown template '%s'. Use one of [%s] or custom-template", t.Template, strings.Join(templates, "|"))
+	}
+	commentTemplate := t.newCommentTemplate("")
+	if commentTemplate.Diff == nil {
+		commentTemplate.Diff = ParseDiff("")
+	}
+	_, err := commentTemplate.render()
+	return err
 }
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/karlderkaefer/cdk-notifier/provider"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -154,6 +155,28 @@ func TestRender(t *testing.T) {
 			expected:    "cdk diff BADGERS",
 			expectError: false,
 		},
+		{
+			name: "WithParsedDiff",
+			template: commentTemplate{
+				customTemplate: "{{ range .Diff.StacksWithDifferences }}{{ .Name }}: {{ .Replacements }} replaced{{ end }}",
+				Diff:           ParseDiff("Stack db-stack\nResources\n[~] AWS::DynamoDB::Table ddb-table ddbtable7G3B6F3F replace\n └─ [~] TableName (requires replacement)\n"),
+			},
+			expected:    "db-stack: 1 replaced",
+			expectError: false,
+		},
+		{
+			name: "WithCommit",
+			template: commentTemplate{
+				customTemplate: "{{ .TagID }} at {{ .ShortSha }} on {{ .Branch }} ({{ .Timestamp }})",
+				TagID:          "small",
+				CommitSha:      "0123456789abcdef",
+				ShortSha:       "0123456",
+				Branch:         "feature",
+				Timestamp:      "2024-05-01T12:00:00Z",
+			},
+			expected:    "small at 0123456 on feature (" + provider.TimestampMarker + "2024-05-01T12:00:00Z)",
+			expectError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,3 +191,35 @@ func TestRender(t *testing.T) {
 		})
 	}
 }
+
+func TestMarkTimestamp(t *testing.T) {
+	rendered := "updated 2024-05-01T12:00:00Z\n```diff\n[~] DEPLOYED_AT: 2024-05-01T12:00:00Z\n```\nat 2024-05-01T12:00:00Z"
+	expected := "updated " + provider.TimestampMarker + "2024-05-01T12:00:00Z\n```diff\n[~] DEPLOYED_AT: 2024-05-01T12:00:00Z\n```\nat " + provider.TimestampMarker + "2024-05-01T12:00:00Z"
+	assert.Equal(t, expected, markTimestamp(rendered, "2024-05-01T12:00:00Z"))
+	assert.Equal(t, rendered, markTimestamp(rendered, ""))
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		transformer LogTransformer
+		expectError string
+	}{
+		{name: "Default", transformer: LogTransformer{Template: "default"}},
+		{name: "ExtendedWithResources", transformer: LogTransformer{Template: "extendedWithResources"}},
+		{name: "Unknown", transformer: LogTransformer{Template: "compact"}, expectError: "unknown template 'compact'. Use one of [default|extended|extendedWithResources] or custom-template"},
+		{name: "Custom", transformer: LogTransformer{CustomTemplate: "{{ .TagID }} {{ range .Diff.StacksWithDifferences }}{{ .Name }}{{ end }}"}},
+		{name: "CustomSyntaxError", transformer: LogTransformer{CustomTemplate: "{{ .TagID }"}, expectError: "template: commentTemplate:1: unexpected \"}\" in operand"},
+		{name: "CustomUnknownField", transformer: LogTransformer{CustomTemplate: "{{ .Commit }}"}, expectError: "template: commentTemplate:1:3: executing \"commentTemplate\" at <.Commit>: can't evaluate field Commit in type *transform.commentTemplate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.transformer.ValidateTemplate()
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectError)
+			}
+		})
+	}
+}
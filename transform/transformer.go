@@ -3,9 +3,11 @@ package transform
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/acarl005/stripansi"
@@ -22,9 +24,14 @@ import (
 type LogTransformer struct {
 	LogContent                string
 	Logfile                   string
+	Input                     io.Reader // used instead of Logfile when Logfile is config.LogFileStdin
 	TagID                     string
+	StackName                 string
 	NoPostMode                bool
+	Output                    string
 	NoTruncate                bool
+	SplitComments             bool
+	Parts                     []string
 	Vcs                       string
 	DisableCollapse           bool
 	ShowOverview              bool
@@ -37,12 +44,28 @@ type LogTransformer struct {
 	ProcessorsChain           LineProcessor
 	TotalChanges              int
 	HashChanges               int
+	SuppressHashChanges       bool
 	SuppressHashChangesRegex  string
+	Diff                      *Diff
+	// ProtectedResourceTypes and ProtectedLogicalIDs are glob patterns of resources which must not be removed or replaced
+	ProtectedResourceTypes      []string
+	ProtectedLogicalIDs         []string
+	ProtectedResourceViolations []ProtectedResourceViolation
+	Rules                       []config.Rule
+	RuleResults                 []RuleResult
+	// ruleExpressions are the compiled when expressions of Rules
+	ruleExpressions []ruleExpression
+	// JobLink is the link to the CI job. Detected from the CI environment if not set
+	JobLink   string
+	CommitSha string
+	Branch    string
+	// Timestamp is the time of the run shown by templates
+	Timestamp time.Time
 }
 
 type ResourceMetric struct {
-	Count    int
-	Replaced bool
+	Count    int  `json:"count"`
+	Replaced bool `json:"replaced"`
 }
 
 // A LineProcessor is responsible to process a single line.
@@ -73,16 +96,27 @@ func NewLogTransformer(config *config.NotifierConfig) *LogTransformer {
 	lt := &LogTransformer{
 		LogContent:               "",
 		Logfile:                  config.LogFile,
+		Input:                    os.Stdin,
 		TagID:                    config.TagID,
 		NoPostMode:               config.NoPostMode,
+		Output:                   config.Output,
 		NoTruncate:               config.NoTruncate,
+		SplitComments:            config.SplitComments,
 		Vcs:                      config.Vcs,
 		DisableCollapse:          config.DisableCollapse,
 		ShowOverview:             config.ShowOverview,
 		Template:                 config.Template,
 		CustomTemplate:           config.CustomTemplate,
 		GithubMaxCommentLength:   config.GithubMaxCommentLength,
+		SuppressHashChanges:      config.SuppressHashChanges,
 		SuppressHashChangesRegex: config.SuppressHashChangesRegex,
+		ProtectedResourceTypes:   config.ProtectedResourceTypes,
+		ProtectedLogicalIDs:      config.ProtectedLogicalIDs,
+		Rules:                    config.Rules,
+		JobLink:                  config.JobLink,
+		CommitSha:                config.CommitSha,
+		Branch:                   config.Branch,
+		Timestamp:                time.Now().UTC(),
 	}
 	lt.initProcessorsChain()
 	return lt
@@ -103,6 +137,17 @@ func (t *LogTransformer) initProcessorsChain() {
 }
 
 func (t *LogTransformer) readFile() error {
+	if t.Logfile == config.LogFileStdin {
+		if t.Input == nil {
+			return fmt.Errorf("no input to read log from")
+		}
+		content, err := io.ReadAll(t.Input)
+		if err != nil {
+			return err
+		}
+		t.LogContent = string(content)
+		return nil
+	}
 	content, err := os.ReadFile(t.Logfile)
 	if err != nil {
 		return err
@@ -115,6 +160,11 @@ func (t *LogTransformer) removeAnsiCode() {
 	t.LogContent = stripansi.Strip(t.LogContent)
 }
 
+// parseDiff builds the structured Diff from the log before any line is transformed
+func (t *LogTransformer) parseDiff() {
+	t.Diff = ParseDiff(t.LogContent)
+}
+
 func trimFirstRune(s string) string {
 	_, i := utf8.DecodeRuneInString(s)
 	return s[i:]
@@ -239,21 +289,22 @@ func (t *LogTransformer) transformDiff() {
 	t.LogContent = strings.Join(transformedLines, "\n")
 }
 
+// maxCommentLength returns the max comment length of the VCS without the space reserved for the comment marker
+func (t *LogTransformer) maxCommentLength() int {
+	maxCommentLength := provider.MaxCommentLength(t.Vcs, t.GithubMaxCommentLength)
+	if maxCommentLength == 0 {
+		return 0
+	}
+	// leave space for the marker embedded before posting
+	return maxCommentLength - provider.CommentMarkerMaxLength
+}
+
 // truncate to avoid Message:Body is too long (maximum is set per VCS)
 func (t *LogTransformer) truncate() {
-	if t.NoTruncate {
+	if t.NoTruncate || len(t.Parts) > 1 {
 		return
 	}
-	var maxCommentLength int
-	if t.Vcs == config.VcsGithubEnterprise && t.GithubMaxCommentLength != 0 {
-		maxCommentLength = t.GithubMaxCommentLength
-	} else if t.Vcs == config.VcsGithub || t.Vcs == config.VcsGithubEnterprise {
-		maxCommentLength = provider.GithubMaxCommentLength
-	} else if t.Vcs == config.VcsBitbucket {
-		maxCommentLength = provider.BitbucketMaxCommentLength
-	} else if t.Vcs == config.VcsGitlab {
-		maxCommentLength = provider.GitlabMaxCommentLength
-	}
+	maxCommentLength := t.maxCommentLength()
 	truncatedCommentSuffix := "\n```\n</details>\n<br>\n\n**Warning**: Truncated output as length greater than max comment size."
 	maxCommentLength = maxCommentLength - len([]rune(truncatedCommentSuffix))
 	runes := bytes.Runes([]byte(t.LogContent))
@@ -265,10 +316,19 @@ func (t *LogTransformer) truncate() {
 }
 
 func (t *LogTransformer) addHeader() {
+	content, err := t.newCommentTemplate(t.LogContent).render()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	t.LogContent = content
+}
+
+// newCommentTemplate creates the template to render the comment for the given diff content
+func (t *LogTransformer) newCommentTemplate(content string) *commentTemplate {
 	collapsible := false
 	showOverview := false
-	// only github and gitlab support collapsable sections
-	if t.Vcs == "github" || t.Vcs == "github-enterprise" || t.Vcs == "gitlab" {
+	// only github, gitlab and gitea support collapsable sections
+	if t.Vcs == "github" || t.Vcs == "github-enterprise" || t.Vcs == "gitlab" || t.Vcs == "gitea" {
 		collapsible = true
 	}
 	// can be disable by command line
@@ -280,96 +340,214 @@ func (t *LogTransformer) addHeader() {
 		showOverview = true
 	}
 	var jobLink string
-	jobUrl := getJobLink()
+	jobUrl := t.JobLink
+	if jobUrl == "" || os.Getenv("CDK_NOTIFIER_DEACTIVATE_JOB_LINK") == "true" {
+		jobUrl = getJobLink()
+	}
 	if jobUrl != "" {
-		jobLink = fmt.Sprintf("[Job Link](%s)", getJobLink())
+		jobLink = fmt.Sprintf("[Job Link](%s)", jobUrl)
+	}
+	var timestamp string
+	if !t.Timestamp.IsZero() {
+		// the rendered timestamp is marked and ignored when comparing comments, so a new run with the same diff does not edit the comment
+		timestamp = t.Timestamp.Format(time.RFC3339)
 	}
 	template := &commentTemplate{
 		TagID:                     t.TagID,
+		StackName:                 t.StackName,
 		NumberOfDifferencesString: t.NumberOfDifferencesString,
 		NumberReplaces:            t.NumberReplaces,
 		ChangedBaseResource:       t.ChangedBaseResource,
-		Content:                   t.LogContent,
+		Diff:                      t.Diff,
+		ProtectedResources:        t.ProtectedResourceViolations,
+		RuleResults:               t.RuleResults,
+		Content:                   content,
 		Backticks:                 "```",
 		JobLink:                   jobLink,
+		CommitSha:                 t.CommitSha,
+		ShortSha:                  shortSha(t.CommitSha),
+		Branch:                    t.Branch,
+		Timestamp:                 timestamp,
 		HeaderPrefix:              provider.HeaderPrefix,
 		Collapsible:               collapsible,
 		ShowOverview:              showOverview,
 		Template:                  t.Template,
 		customTemplate:            t.CustomTemplate,
 	}
-	content, err := template.render()
-	if err != nil {
-		logrus.Fatal(err)
+	return template
+}
+
+// shortSha returns the abbreviated commit sha like git log --oneline
+func shortSha(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
 	}
-	t.LogContent = content
+	return sha
 }
 
+// getJobLink returns the job link of the detected CI system
 func getJobLink() string {
-	var jobLink string
 	// deactivate job link for some tests
 	if os.Getenv("CDK_NOTIFIER_DEACTIVATE_JOB_LINK") == "true" {
 		return ""
 	}
-	if os.Getenv("GITLAB_CI") == "true" {
-		jobLink = os.Getenv("CI_JOB_URL")
-	}
-	if os.Getenv("CIRCLECI") == "true" {
-		jobLink = os.Getenv("CIRCLE_BUILD_URL")
-	}
-	if os.Getenv("BITBUCKET_BUILD_NUMBER") != "" {
-		workspace := os.Getenv("BITBUCKET_WORKSPACE")
-		repoSlug := os.Getenv("BITBUCKET_REPO_SLUG")
-		buildNumber := os.Getenv("BITBUCKET_BUILD_NUMBER")
-		jobLink = fmt.Sprintf("https://bitbucket.org/%s/%s/pipelines/results/%s", workspace, repoSlug, buildNumber)
+	detector := config.DetectCi()
+	if detector == nil || detector.Info == nil {
+		return ""
 	}
-	if os.Getenv("GITHUB_ACTIONS") != "" {
-		server := os.Getenv("GITHUB_SERVER_URL")
-		repo := os.Getenv("GITHUB_REPOSITORY")
-		runID := os.Getenv("GITHUB_RUN_ID")
-		jobLink = fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, runID)
+	info, err := detector.Info()
+	if err != nil {
+		logrus.Debugf("Unable to read job link from %s: %s", detector.Name, err)
 	}
-	logrus.Debugf("Found Job link: %s", jobLink)
-	return jobLink
+	logrus.Debugf("Found Job link: %s", info.JobLink)
+	return info.JobLink
 }
 
 func (t *LogTransformer) printFile() {
 	logrus.Infof("File contents: %s", t.LogContent)
 }
 
+// OnlyHashChanges reports whether suppress-hash-changes is set and all changes are hash changes
+func (t *LogTransformer) OnlyHashChanges() bool {
+	return t.SuppressHashChanges && t.TotalChanges == t.HashChanges
+}
+
 // writeDiffFile is writing the transformed diff to file and appends .diff to filename.
-// Additionally, the diff is streamed to stdout
+// Additionally, the diff is streamed to stdout. Skipped when JSON output is selected.
+// When the log was read from stdin the diff is only streamed to stdout.
+// A diff with only hash changes starts with provider.HashChangesOnlyMarker, so post deletes the comment.
 func (t *LogTransformer) writeDiffFile() error {
-	if !t.NoPostMode {
+	if !t.NoPostMode || t.Output == config.OutputJSON {
 		return nil
 	}
-	filePath := t.Logfile + ".diff"
-	// read/write for the owner, and read-only for the group and others
-	err := os.WriteFile(filePath, []byte(t.LogContent), 0644)
-	if err != nil {
-		return err
+	content := t.LogContent
+	if t.OnlyHashChanges() {
+		content = provider.HashChangesOnlyMarker + "\n" + content
+	}
+	if t.Logfile != config.LogFileStdin {
+		filePath := t.Logfile + ".diff"
+		// read/write for the owner, and read-only for the group and others
+		err := os.WriteFile(filePath, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
 	}
-	fmt.Print(t.LogContent)
+	fmt.Print(content)
 	return nil
 }
 
 // Process log file
 // 1. Clean any ANSI chars and XTERM color created from cdk diff command
-// 2. Transform additions and removals to markdown diff syntax
-// 3. Create unique message header
-// 4. truncate content if message is longer than GitHub API can handle
-// 5. write diff as file and to stdout when no-post-mode is activated
+// 2. Parse the log into a structured Diff, check protected resources and evaluate rules
+// 3. Transform additions and removals to markdown diff syntax
+// 4. Create unique message header or split the diff into several comments
+// 5. truncate content if message is longer than GitHub API can handle
+// 6. write diff as file and to stdout when no-post-mode is activated
+// 7. write JSON report as file when JSON output is selected
 func (t *LogTransformer) Process() {
-	err := t.readFile()
+	var err error
+	t.ruleExpressions, err = compileRules(t.Rules)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	err = t.readFile()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	t.processContent()
+	err = t.writeDiffFile()
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	err = t.writeReportFile()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func (t *LogTransformer) processContent() {
 	t.removeAnsiCode()
+	t.parseDiff()
+	t.checkProtectedResources()
+	t.evaluateRules()
 	t.transformDiff()
+	t.splitComments()
+	if len(t.Parts) > 1 {
+		return
+	}
 	t.addHeader()
 	t.truncate()
-	err = t.writeDiffFile()
-	if err != nil {
-		logrus.Fatal(err)
+}
+
+// ManagedComment returns the comment to post. Continuation parts are added when the diff was split.
+func (t *LogTransformer) ManagedComment() provider.ManagedComment {
+	if len(t.Parts) > 1 {
+		return provider.ManagedComment{
+			TagID:         t.TagID,
+			Content:       t.Parts[0],
+			Continuations: t.Parts[1:],
+			Stacks:        t.stackNames(),
+		}
+	}
+	return provider.ManagedComment{
+		TagID:   t.TagID,
+		Content: t.LogContent,
+		Stacks:  t.stackNames(),
+	}
+}
+
+// stackNames returns the stack of a split transformer or all stacks with differences
+func (t *LogTransformer) stackNames() []string {
+	if t.StackName != "" {
+		return []string{t.StackName}
+	}
+	if t.Diff == nil {
+		return nil
+	}
+	var names []string
+	for _, stack := range t.Diff.StacksWithDifferences() {
+		names = append(names, stack.Name)
+	}
+	return names
+}
+
+// StackTransformers creates a processed LogTransformer for each stack with differences.
+// The tag id of each transformer is extended by the stack name. Process has to be called before.
+func (t *LogTransformer) StackTransformers() []*LogTransformer {
+	var transformers []*LogTransformer
+	if t.Diff == nil {
+		return transformers
+	}
+	for _, stack := range t.Diff.StacksWithDifferences() {
+		st := &LogTransformer{
+			LogContent:               stack.Log,
+			Logfile:                  t.Logfile,
+			TagID:                    provider.StackTagID(t.TagID, stack.Name),
+			StackName:                stack.Name,
+			NoPostMode:               t.NoPostMode,
+			Output:                   t.Output,
+			NoTruncate:               t.NoTruncate,
+			SplitComments:            t.SplitComments,
+			Vcs:                      t.Vcs,
+			DisableCollapse:          t.DisableCollapse,
+			ShowOverview:             t.ShowOverview,
+			Template:                 t.Template,
+			CustomTemplate:           t.CustomTemplate,
+			GithubMaxCommentLength:   t.GithubMaxCommentLength,
+			SuppressHashChanges:      t.SuppressHashChanges,
+			SuppressHashChangesRegex: t.SuppressHashChangesRegex,
+			ProtectedResourceTypes:   t.ProtectedResourceTypes,
+			ProtectedLogicalIDs:      t.ProtectedLogicalIDs,
+			Rules:                    t.Rules,
+			ruleExpressions:          t.ruleExpressions,
+			JobLink:                  t.JobLink,
+			CommitSha:                t.CommitSha,
+			Branch:                   t.Branch,
+			Timestamp:                t.Timestamp,
+		}
+		st.initProcessorsChain()
+		st.processContent()
+		transformers = append(transformers, st)
 	}
+	return transformers
 }
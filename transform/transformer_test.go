@@ -4,10 +4,12 @@ import (
 	"math/rand"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/karlderkaefer/cdk-notifier/config"
+	"github.com/karlderkaefer/cdk-notifier/provider"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,6 +28,20 @@ type TestObject struct {
 	expected string
 }
 
+func TestLogTransformer_ReadStdin(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile: config.LogFileStdin,
+		Input:   strings.NewReader("Stack small\nResources\n"),
+	}
+	err := transformer.readFile()
+	assert.NoError(t, err)
+	assert.Equal(t, "Stack small\nResources\n", transformer.LogContent)
+
+	transformer.Input = nil
+	err = transformer.readFile()
+	assert.Error(t, err)
+}
+
 func TestLogTransformer_RemoveAnsiCode(t *testing.T) {
 	cases := []TestObject{
 		{
@@ -222,20 +238,21 @@ func TestLogTransform_Truncate(t *testing.T) {
 			exceeds:        false,
 		},
 		{
+			// the space reserved for the comment marker is not available
 			runeCount:      65000,
-			expectedLength: 65000,
+			expectedLength: 65536 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsGithub,
-			exceeds:        false,
+			exceeds:        true,
 		},
 		{
 			runeCount:      78999,
-			expectedLength: 65536,
+			expectedLength: 65536 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsGithub,
 			exceeds:        true,
 		},
 		{
 			runeCount:      878999,
-			expectedLength: 65536,
+			expectedLength: 65536 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsGithub,
 			exceeds:        true,
 		},
@@ -271,13 +288,13 @@ func TestLogTransform_Truncate(t *testing.T) {
 		},
 		{
 			runeCount:      1000001,
-			expectedLength: 1000000,
+			expectedLength: 1000000 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsGitlab,
 			exceeds:        true,
 		},
 		{
 			runeCount:      423428,
-			expectedLength: 32768,
+			expectedLength: 32768 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsBitbucket,
 			exceeds:        true,
 		},
@@ -289,13 +306,13 @@ func TestLogTransform_Truncate(t *testing.T) {
 		},
 		{
 			runeCount:      32769,
-			expectedLength: 32768,
+			expectedLength: 32768 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsBitbucket,
 			exceeds:        true,
 		},
 		{
 			runeCount:      80001,
-			expectedLength: 80000,
+			expectedLength: 80000 - provider.CommentMarkerMaxLength,
 			provider:       config.VcsGithubEnterprise,
 			exceeds:        true,
 		},
@@ -355,6 +372,21 @@ func TestNewLogTransformer(t *testing.T) {
 	assert.Contains(t, transformer.LogContent, "Stack SuiteRedisStack\nThere were no differences")
 
 }
+func TestNewCommentTemplateCommit(t *testing.T) {
+	transformer := NewLogTransformer(&config.NotifierConfig{TagID: "small", CommitSha: "0123456789abcdef", Branch: "feature"})
+	assert.False(t, transformer.Timestamp.IsZero())
+	transformer.Timestamp = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	template := transformer.newCommentTemplate("")
+	assert.Equal(t, "0123456789abcdef", template.CommitSha)
+	assert.Equal(t, "0123456", template.ShortSha)
+	assert.Equal(t, "feature", template.Branch)
+	assert.Equal(t, "2024-05-01T12:00:00Z", template.Timestamp)
+
+	template = (&LogTransformer{CommitSha: "abc"}).newCommentTemplate("")
+	assert.Equal(t, "abc", template.ShortSha)
+	assert.Empty(t, template.Timestamp)
+}
+
 func TestOverviewSection(t *testing.T) {
 	c := &config.NotifierConfig{
 		LogFile:      "../data/cdk-diff-number-diff-replace.log",
@@ -603,3 +635,34 @@ func TestIgnoreHashesProcessor_ProcessLine(t *testing.T) {
 		assert.Equal(t, c.expectedHashes, lt.HashChanges)
 	}
 }
+
+func TestLogTransformer_StackTransformers(t *testing.T) {
+	transformer := &LogTransformer{
+		Logfile:                  "../data/cdk-multistack.log",
+		TagID:                    "multi",
+		Vcs:                      "github",
+		Template:                 "default",
+		SuppressHashChangesRegex: config.DefaultSuppressHashChangesRegex,
+	}
+	transformer.initProcessorsChain()
+	transformer.Process()
+
+	stackTransformers := transformer.StackTransformers()
+	assert.Len(t, stackTransformers, 1)
+	st := stackTransformers[0]
+	assert.Equal(t, "CoreIamStackmain12345678eucentral1E9950359", st.StackName)
+	assert.Equal(t, "multi::CoreIamStackmain12345678eucentral1E9950359", st.TagID)
+	assert.Equal(t, []string{"CoreIamStackmain12345678eucentral1E9950359"}, st.ManagedComment().Stacks)
+	assert.Equal(t, []string{"CoreIamStackmain12345678eucentral1E9950359"}, transformer.ManagedComment().Stacks)
+	assert.Contains(t, st.LogContent, "## cdk diff for multi::CoreIamStackmain12345678eucentral1E9950359")
+	assert.Contains(t, st.LogContent, "-[-] AWS::IAM::Policy CircleCiAccessRoleDefaultPolicy8190211F destroy")
+	assert.NotContains(t, st.LogContent, "Stack CoreIamStack\n")
+	assert.Equal(t, 1, st.ChangedBaseResource["AWS::IAM::Policy"].Count)
+	assert.Greater(t, st.TotalChanges, 0)
+	assert.LessOrEqual(t, st.TotalChanges, transformer.TotalChanges)
+}
+
+func TestLogTransformer_StackTransformersWithoutDiff(t *testing.T) {
+	transformer := &LogTransformer{}
+	assert.Empty(t, transformer.StackTransformers())
+}